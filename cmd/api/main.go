@@ -0,0 +1,60 @@
+// Command api serves the REST API and websocket feed, relaying live klines
+// published by a separately running collector over the PostgreSQL bus.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"crypto-monitor/internal/app"
+	"crypto-monitor/pkg/database"
+)
+
+func main() {
+	cfg, flags, err := app.LoadConfig("api", os.Args[1:])
+	if err != nil {
+		app.Fatal("Failed to load configuration", err)
+	}
+	if err := app.RequireSharedBus(cfg, "the API server"); err != nil {
+		app.Fatal("Invalid configuration", err)
+	}
+
+	stopTracing, err := app.SetupTracing(cfg, "api")
+	if err != nil {
+		app.Fatal("Failed to set up tracing", err)
+	}
+	defer stopTracing()
+
+	db, err := app.OpenDatabase(cfg.Database)
+	if err != nil {
+		app.Fatal("Database unavailable", err)
+	}
+
+	b, err := app.OpenBus(cfg, db)
+	if err != nil {
+		app.Fatal("Failed to open message bus", err)
+	}
+
+	// Tracking is managed by the collector, so no admin endpoints are served
+	server, err := app.NewAPIServer(cfg, db, b, nil, flags)
+	if err != nil {
+		app.Fatal("Failed to start API server", err)
+	}
+	server.Start()
+
+	app.WaitForSignals(nil)
+	slog.Info("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+	defer cancel()
+	if err := app.Shutdown(ctx,
+		app.ShutdownStep{Name: "api server", Stop: server.Shutdown},
+		app.ShutdownStep{Name: "imports", Stop: server.StopImports},
+		app.CloseStep("bus", b.Close),
+		app.CloseStep("database", database.CloseDB),
+	); err != nil {
+		slog.Error("Server did not shut down cleanly", "error", err)
+	}
+	slog.Info("Server exited")
+}
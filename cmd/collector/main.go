@@ -0,0 +1,65 @@
+// Command collector streams the tracked series from the exchange, stores their
+// klines and publishes them to API servers over the PostgreSQL bus.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"crypto-monitor/internal/app"
+	"crypto-monitor/pkg/database"
+)
+
+func main() {
+	cfg, flags, err := app.LoadConfig("collector", os.Args[1:])
+	if err != nil {
+		app.Fatal("Failed to load configuration", err)
+	}
+	if err := app.RequireSharedBus(cfg, "the collector"); err != nil {
+		app.Fatal("Invalid configuration", err)
+	}
+
+	stopTracing, err := app.SetupTracing(cfg, "collector")
+	if err != nil {
+		app.Fatal("Failed to set up tracing", err)
+	}
+	defer stopTracing()
+
+	db, err := app.OpenDatabase(cfg.Database)
+	if err != nil {
+		app.Fatal("Database unavailable", err)
+	}
+
+	b, err := app.OpenBus(cfg, db)
+	if err != nil {
+		app.Fatal("Failed to open message bus", err)
+	}
+
+	collector, err := app.StartCollector(cfg, db, b)
+	if err != nil {
+		app.Fatal("Failed to start collector", err)
+	}
+	slog.Info("Collector started")
+
+	// The collector has no API, so it serves only /metrics, /healthz and
+	// /readyz on the server port
+	opsServer := app.StartOpsServer(cfg, db, collector)
+
+	// SIGHUP reloads the tracked symbols and intervals without a restart
+	app.WaitForSignals(func() { collector.Reload(flags) })
+	slog.Info("Shutting down collector")
+
+	// Pending klines are flushed before the bus and database are closed
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+	defer cancel()
+	if err := app.Shutdown(ctx,
+		app.ShutdownStep{Name: "ops server", Stop: opsServer.Shutdown},
+		app.ShutdownStep{Name: "collector", Stop: collector.Stop},
+		app.CloseStep("bus", b.Close),
+		app.CloseStep("database", database.CloseDB),
+	); err != nil {
+		slog.Error("Collector did not shut down cleanly", "error", err)
+	}
+	slog.Info("Collector exited")
+}
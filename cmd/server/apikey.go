@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/repository"
+	"crypto-monitor/pkg/database"
+)
+
+// runAPIKey implements the "apikey" subcommand, which creates, lists and
+// revokes API keys, for instance to issue the first admin key
+func runAPIKey(args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	name := fs.String("name", "", "name of the key to create")
+	scopes := fs.String("scopes", string(auth.ScopeMarketRead), "comma separated scopes of the key to create: market:read, alerts:manage or admin")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: server apikey [flags] create|list|revoke <id>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("an action is required")
+	}
+
+	cfg, err := flags.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer database.CloseDB()
+
+	repo := repository.NewAPIKeyRepository(db)
+	ctx := context.Background()
+
+	switch action := fs.Arg(0); action {
+	case "create":
+		if *name == "" {
+			return fmt.Errorf("-name is required")
+		}
+		parsed, err := auth.ParseScopes(strings.Split(*scopes, ","))
+		if err != nil {
+			return err
+		}
+		key, record, err := auth.NewKey(*name, parsed)
+		if err != nil {
+			return err
+		}
+		if err := repo.CreateAPIKey(ctx, record); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created API key %d (%s) with scopes %s; it is not shown again\n", record.ID, record.Name, strings.Join(record.Scopes, ","))
+		fmt.Println(key)
+
+	case "list":
+		keys, err := repo.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tREVOKED")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix, strings.Join(key.Scopes, ","),
+				key.CreatedAt.Format(time.RFC3339), formatOptionalTime(key.LastUsedAt), formatOptionalTime(key.RevokedAt))
+		}
+		return w.Flush()
+
+	case "revoke":
+		id, err := strconv.ParseUint(fs.Arg(1), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid key id %q", fs.Arg(1))
+		}
+		revoked, err := repo.RevokeAPIKey(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return fmt.Errorf("no active API key with id %d", id)
+		}
+		fmt.Fprintf(os.Stderr, "Revoked API key %d; servers stop accepting it within auth.cache_ttl (%s)\n", id, cfg.Auth.CacheTTL)
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+	return nil
+}
+
+// formatOptionalTime formats t, or "-" when it is not set
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
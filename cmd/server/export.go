@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/export"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/repository"
+	"crypto-monitor/pkg/database"
+)
+
+// runExport implements the "export" subcommand, which writes stored klines to a file
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	symbol := fs.String("symbol", "", "trading pair symbol, e.g. BTCUSDT (required)")
+	interval := fs.String("interval", "", "kline interval, e.g. 1m (required)")
+	format := fs.String("format", export.FormatCSV, "output format: csv, ndjson or parquet")
+	gzipped := fs.Bool("gzip", false, "gzip-compress the output")
+	startTime := fs.Int64("start", 0, "start open_time in milliseconds (0 for no bound)")
+	endTime := fs.Int64("end", 0, "end open_time in milliseconds (0 for no bound)")
+	output := fs.String("o", "", "output file (default <symbol>_<interval>.<format>[.gz], - for stdout)")
+	fs.Parse(args)
+
+	if *symbol == "" || *interval == "" {
+		fs.Usage()
+		return fmt.Errorf("symbol and interval are required")
+	}
+	if !export.IsValidFormat(*format) {
+		return fmt.Errorf("unsupported export format: %s", *format)
+	}
+
+	cfg, err := flags.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := logging.Setup(cfg.Log, os.Stderr); err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer database.CloseDB()
+	klineRepo := repository.NewKlineRepository(db)
+
+	path := *output
+	if path == "" {
+		path = export.FileName(strings.ToUpper(*symbol), *interval, *format, *gzipped)
+	}
+
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w, err := export.NewWriter(out, *format, *gzipped)
+	if err != nil {
+		return err
+	}
+
+	var start, end *int64
+	if *startTime > 0 {
+		start = startTime
+	}
+	if *endTime > 0 {
+		end = endTime
+	}
+
+	count := 0
+	err = klineRepo.StreamKlines(context.Background(), strings.ToUpper(*symbol), *interval, start, end, func(kline models.Kline) error {
+		count++
+		return w.Write(kline)
+	})
+	if err != nil {
+		return fmt.Errorf("export failed after %d klines: %w", count, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish export: %w", err)
+	}
+
+	if path != "-" {
+		slog.Info("Exported", "path", path, "klines", count)
+	}
+	return nil
+}
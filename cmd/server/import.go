@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/importer"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/repository"
+	"crypto-monitor/pkg/database"
+)
+
+// runImport implements the "import" subcommand, which loads kline archives from local disk
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	symbol := fs.String("symbol", "", "trading pair symbol (inferred from Binance file names when empty)")
+	interval := fs.String("interval", "", "kline interval (inferred from Binance file names when empty)")
+	format := fs.String("format", importer.FormatBinance, "input format: binance or generic")
+	mapping := fs.String("map", "", "generic CSV column mapping, e.g. open_time=timestamp,volume=vol")
+	timeUnit := fs.String("time-unit", importer.TimeUnitAuto, "timestamp unit for generic CSVs: auto, s, ms or us")
+	force := fs.Bool("force", false, "re-import files that were already imported")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: server import [flags] <file or directory>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("at least one file or directory is required")
+	}
+
+	columnMapping, err := importer.ParseColumnMapping(*mapping)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := flags.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := logging.Setup(cfg.Log, os.Stderr); err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer database.CloseDB()
+
+	klineImporter := importer.NewImporter(repository.NewKlineRepository(db), repository.NewKlineImportRepository(db))
+
+	opts := importer.Options{
+		Symbol:   strings.ToUpper(*symbol),
+		Interval: *interval,
+		Format:   *format,
+		Mapping:  columnMapping,
+		TimeUnit: *timeUnit,
+		Force:    *force,
+		OnProgress: func(p importer.Progress) {
+			percent := 100.0
+			if p.TotalBytes > 0 {
+				percent = float64(p.BytesRead) * 100 / float64(p.TotalBytes)
+			}
+			slog.Info("Importing", "file", p.File, "file_index", p.FileIndex, "file_count", p.FileCount, "percent", math.Round(percent*10)/10, "klines", p.Rows)
+		},
+	}
+
+	for _, path := range fs.Args() {
+		result, err := klineImporter.Import(context.Background(), path, opts)
+		if err != nil {
+			return err
+		}
+		slog.Info("Imported", "path", path, "files", result.Files, "skipped", result.Skipped, "klines", result.Rows)
+	}
+
+	return nil
+}
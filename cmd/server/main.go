@@ -2,106 +2,89 @@ package main
 
 import (
 	"context"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"crypto-monitor/internal/api"
-	"crypto-monitor/internal/repository"
-	"crypto-monitor/internal/service"
+	"crypto-monitor/internal/app"
 	"crypto-monitor/pkg/database"
-
-	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 )
 
 func main() {
-	// Initialize database connection
-	db, err := database.InitDB()
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Dispatch subcommands; with no subcommand the HTTP server is started
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			if err := runExport(os.Args[2:]); err != nil {
+				app.Fatal("Export failed", err)
+			}
+			return
+		case "import":
+			if err := runImport(os.Args[2:]); err != nil {
+				app.Fatal("Import failed", err)
+			}
+			return
+		case "apikey":
+			if err := runAPIKey(os.Args[2:]); err != nil {
+				app.Fatal("API key command failed", err)
+			}
+			return
+		}
 	}
-	defer database.CloseDB()
 
-	// Test database connection
-	sqlDB, err := db.DB()
+	cfg, flags, err := app.LoadConfig("server", os.Args[1:])
 	if err != nil {
-		log.Fatalf("Failed to get database instance: %v", err)
-	}
-	if err := sqlDB.Ping(); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+		app.Fatal("Failed to load configuration", err)
 	}
-	log.Println("Database connection test successful")
-
-	// Initialize services
-	klineRepo := repository.NewKlineRepository(db)
-	binanceSvc := service.NewBinanceService()
-	wsSvc := service.NewWebSocketService(binanceSvc, klineRepo)
-
-	// Start WebSocket service
-	go wsSvc.Run()
-	log.Println("WebSocket service started")
-
-	// Initialize Gin router
-	r := gin.Default()
-
-	// Setup API routes
-	api.SetupRoutes(r, klineRepo)
 
-	// Setup WebSocket route
-	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			// Allow all origins for development
-			// In production, you should validate the origin
-			return true
-		},
+	stopTracing, err := app.SetupTracing(cfg, "server")
+	if err != nil {
+		app.Fatal("Failed to set up tracing", err)
 	}
+	defer stopTracing()
 
-	r.GET("/ws", func(c *gin.Context) {
-		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-		if err != nil {
-			log.Printf("WebSocket upgrade failed: %v", err)
-			return
-		}
-		wsSvc.HandleConnection(conn)
-	})
+	// Initialize database connection
+	db, err := app.OpenDatabase(cfg.Database)
+	if err != nil {
+		app.Fatal("Database unavailable", err)
+	}
 
-	// Get server port from environment
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// The collector and API run in this process; with the postgres bus, API
+	// servers started with cmd/api receive the same live klines
+	b, err := app.OpenBus(cfg, db)
+	if err != nil {
+		app.Fatal("Failed to open message bus", err)
 	}
 
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
+	// Start always-on streams for the tracked series
+	collector, err := app.StartCollector(cfg, db, b)
+	if err != nil {
+		app.Fatal("Failed to start collector", err)
 	}
 
-	// Start HTTP server in a goroutine
-	go func() {
-		log.Printf("Server starting on port %s", port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+	server, err := app.NewAPIServer(cfg, db, b, collector, flags)
+	if err != nil {
+		app.Fatal("Failed to start API server", err)
+	}
+	server.Start()
 
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+	// SIGHUP reloads the tracked symbols and intervals without a restart;
+	// wait for interrupt signal to gracefully shutdown the server
+	app.WaitForSignals(func() { collector.Reload(flags) })
+	slog.Info("Shutting down server")
 
-	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Stop accepting clients, cancel imports, close the upstream streams and
+	// flush pending writes before the bus and database they rely on are closed
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
 	defer cancel()
-
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	if err := app.Shutdown(ctx,
+		app.ShutdownStep{Name: "api server", Stop: server.Shutdown},
+		app.ShutdownStep{Name: "imports", Stop: server.StopImports},
+		app.ShutdownStep{Name: "collector", Stop: collector.Stop},
+		app.CloseStep("bus", b.Close),
+		app.CloseStep("database", database.CloseDB),
+	); err != nil {
+		slog.Error("Server did not shut down cleanly", "error", err)
 	}
 
-	log.Println("Server exited")
+	slog.Info("Server exited")
 }
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"crypto-monitor/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// APIKeyHeader carries an API key as an alternative to an Authorization bearer token
+const APIKeyHeader = "X-API-Key"
+
+// tokenQueryParam carries the API key of websocket handshakes, since browsers
+// cannot set headers on them
+const tokenQueryParam = "token"
+
+// AuthMiddleware rejects requests without a valid API key, read from an
+// Authorization bearer token or X-API-Key, and stores the principal in the
+// request context
+func AuthMiddleware(authn *auth.Authenticator) gin.HandlerFunc {
+	return authenticate(authn, false)
+}
+
+// WebSocketAuthMiddleware is AuthMiddleware for websocket handshakes, which
+// also accept the key in the token query parameter
+func WebSocketAuthMiddleware(authn *auth.Authenticator) gin.HandlerFunc {
+	return authenticate(authn, true)
+}
+
+func authenticate(authn *auth.Authenticator, queryToken bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := requestKey(c.Request, queryToken)
+		if key == "" {
+			abortUnauthorized(c, "API key required")
+			return
+		}
+
+		ctx := c.Request.Context()
+		principal, err := authn.Authenticate(ctx, key)
+		if errors.Is(err, auth.ErrInvalidKey) {
+			abortUnauthorized(c, "invalid API key")
+			return
+		}
+		if err != nil {
+			httpLog.ErrorContext(ctx, "Failed to verify API key", "error", err)
+			abortJSON(c, http.StatusServiceUnavailable, "authentication unavailable")
+			return
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("auth.key_id", int64(principal.KeyID)))
+		c.Request = c.Request.WithContext(auth.WithPrincipal(ctx, principal))
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose API key does not grant scope; it must
+// follow AuthMiddleware
+func RequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.PrincipalFrom(c.Request.Context())
+		if principal == nil {
+			abortUnauthorized(c, "API key required")
+			return
+		}
+		if !principal.Allows(scope) {
+			abortJSON(c, http.StatusForbidden, fmt.Sprintf("API key lacks the %s scope", scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestKey returns the API key sent with r, if any
+func requestKey(r *http.Request, queryToken bool) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if queryToken {
+		return r.URL.Query().Get(tokenQueryParam)
+	}
+	return ""
+}
+
+// abortUnauthorized rejects a request that needs an API key
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="`+serverName+`"`)
+	abortJSON(c, http.StatusUnauthorized, message)
+}
+
+// abortJSON stops the handler chain with an error in the API response format
+func abortJSON(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"code":    status,
+		"message": message,
+		"data":    nil,
+	})
+}
+
+// OriginChecker returns a websocket CheckOrigin function allowing the listed
+// origins, every origin for "*", or only the server's own origin when allowed
+// is empty
+// Requests without an Origin header do not come from browsers and are allowed.
+func OriginChecker(allowed []string) func(r *http.Request) bool {
+	anyOrigin := slices.Contains(allowed, "*")
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || anyOrigin {
+			return true
+		}
+		if slices.Contains(allowed, strings.ToLower(origin)) {
+			return true
+		}
+		if len(allowed) == 0 {
+			u, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(u.Host, r.Host)
+		}
+		return false
+	}
+}
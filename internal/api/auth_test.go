@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/auth/authtest"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/repository"
+	"crypto-monitor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupAuthRouter serves the API routes with authentication backed by store
+func setupAuthRouter(store *authtest.KeyStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authn := auth.NewAuthenticator(store, time.Minute)
+	SetupRoutes(r, Dependencies{
+		KlineRepo: repository.NewKlineRepository(nil),
+		Auth:      authn,
+		APIKeys:   store,
+	})
+	return r
+}
+
+// serve sends a request with an optional bearer key and returns the recorder
+func serve(r *gin.Engine, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestAuthMiddleware_Scopes tests that API routes need a key granting their scope
+func TestAuthMiddleware_Scopes(t *testing.T) {
+	store := &authtest.KeyStore{}
+	r := setupAuthRouter(store)
+	reader := store.Issue(t, auth.ScopeMarketRead)
+	admin := store.Issue(t, auth.ScopeAdmin)
+
+	w := serve(r, http.MethodGet, "/api/v1/symbols", "", "")
+	if w.Code != http.StatusUnauthorized || !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("Expected 401 with a bearer challenge without a key, got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if w := serve(r, http.MethodGet, "/api/v1/symbols", "cm_forged", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown key, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/healthz", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected health endpoints to stay open, got %d", w.Code)
+	}
+
+	// Missing parameters are rejected by the handler, past authentication
+	if w := serve(r, http.MethodGet, "/api/v1/klines", reader, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a market:read key to reach the handler, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/klines", nil)
+	req.Header.Set(APIKeyHeader, reader)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected the key to be accepted in %s, got %d", APIKeyHeader, w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/api/v1/klines?token="+reader, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected query tokens to be refused outside websocket handshakes, got %d", w.Code)
+	}
+
+	if w := serve(r, http.MethodGet, "/api/v1/admin/keys", reader, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a market:read key on admin routes, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/api/v1/admin/keys", admin, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected admin keys to manage keys, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/api/v1/klines", admin, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected admin to imply market:read, got %d", w.Code)
+	}
+}
+
+// TestAPIKeyHandler_CreateAndRevoke tests issuing a key over the API and revoking it
+func TestAPIKeyHandler_CreateAndRevoke(t *testing.T) {
+	store := &authtest.KeyStore{}
+	r := setupAuthRouter(store)
+	admin := store.Issue(t, auth.ScopeAdmin)
+
+	if w := serve(r, http.MethodPost, "/api/v1/admin/keys", admin, `{"name":"bot","scopes":["market:write"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown scope, got %d", w.Code)
+	}
+
+	w := serve(r, http.MethodPost, "/api/v1/admin/keys", admin, `{"name":"bot","scopes":["market:read"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data struct {
+			Key    string        `json:"key"`
+			APIKey models.APIKey `json:"api_key"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if strings.Contains(w.Body.String(), auth.HashKey(created.Data.Key)) {
+		t.Error("Expected the key hash not to be exposed")
+	}
+
+	key := created.Data.Key
+	if w := serve(r, http.MethodGet, "/api/v1/klines", key, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the new key to be accepted, got %d", w.Code)
+	}
+
+	path := "/api/v1/admin/keys/" + strconv.FormatUint(created.Data.APIKey.ID, 10)
+	if w := serve(r, http.MethodDelete, path, admin, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the key to be revoked, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/api/v1/klines", key, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked key to be rejected at once, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodDelete, path, admin, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when revoking twice, got %d", w.Code)
+	}
+}
+
+// TestWebSocketAuthMiddleware tests that websocket handshakes accept a query token
+func TestWebSocketAuthMiddleware(t *testing.T) {
+	store := &authtest.KeyStore{}
+	reader := store.Issue(t, auth.ScopeMarketRead)
+	authn := auth.NewAuthenticator(store, time.Minute)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/ws", WebSocketAuthMiddleware(authn), RequireScope(auth.ScopeMarketRead), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, tc := range []struct {
+		path, key string
+		want      int
+	}{
+		{"/ws", "", http.StatusUnauthorized},
+		{"/ws?token=" + reader, "", http.StatusNoContent},
+		{"/ws", reader, http.StatusNoContent},
+		{"/ws?token=cm_forged", "", http.StatusUnauthorized},
+	} {
+		if w := serve(r, http.MethodGet, tc.path, tc.key, ""); w.Code != tc.want {
+			t.Errorf("GET %s with key %t: expected %d, got %d", tc.path, tc.key != "", tc.want, w.Code)
+		}
+	}
+}
+
+// untrackedStreams refuses every event stream as untracked
+type untrackedStreams struct{}
+
+func (untrackedStreams) OpenEventStream(req service.StreamRequest) (*service.EventStream, error) {
+	return nil, service.ErrNotTracked
+}
+
+// TestStreamAuth tests that event streams, like websocket handshakes, accept
+// a query token
+func TestStreamAuth(t *testing.T) {
+	store := &authtest.KeyStore{}
+	reader := store.Issue(t, auth.ScopeMarketRead)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, Dependencies{
+		KlineRepo: repository.NewKlineRepository(nil),
+		Auth:      auth.NewAuthenticator(store, time.Minute),
+		APIKeys:   store,
+		Streams:   untrackedStreams{},
+	})
+
+	// Authenticated requests reach the handler, which refuses the series
+	const path = "/api/v1/stream/klines?symbol=BTCUSDT&interval=1m"
+	for _, tc := range []struct {
+		path, key string
+		want      int
+	}{
+		{path, "", http.StatusUnauthorized},
+		{path + "&token=" + reader, "", http.StatusNotFound},
+		{path, reader, http.StatusNotFound},
+		{path + "&token=cm_forged", "", http.StatusUnauthorized},
+	} {
+		if w := serve(r, http.MethodGet, tc.path, tc.key, ""); w.Code != tc.want {
+			t.Errorf("GET %s with key %t: expected %d, got %d", tc.path, tc.key != "", tc.want, w.Code)
+		}
+	}
+}
+
+// TestOriginChecker tests the websocket origin allow list
+func TestOriginChecker(t *testing.T) {
+	request := func(host, origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://"+host+"/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+
+	listed := OriginChecker([]string{"https://app.example.com"})
+	if !listed(request("api.example.com", "https://App.Example.com")) {
+		t.Error("Expected a listed origin to be allowed")
+	}
+	if listed(request("api.example.com", "https://evil.example.com")) {
+		t.Error("Expected an unlisted origin to be refused")
+	}
+	if !listed(request("api.example.com", "")) {
+		t.Error("Expected clients without an Origin to be allowed")
+	}
+
+	sameOrigin := OriginChecker(nil)
+	if !sameOrigin(request("localhost:8080", "http://localhost:8080")) || sameOrigin(request("localhost:8080", "http://localhost:5173")) {
+		t.Error("Expected only the server's own origin to be allowed by default")
+	}
+
+	if !OriginChecker([]string{"*"})(request("localhost:8080", "https://anywhere.example")) {
+		t.Error("Expected * to allow any origin")
+	}
+}
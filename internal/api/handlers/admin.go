@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrackingManager changes the tracked series at runtime
+type TrackingManager interface {
+	Tracking() config.TrackingConfig
+	Series() []service.Series
+	Apply(tracking config.TrackingConfig) (service.TrackingChange, error)
+}
+
+// AdminHandler handles runtime administration requests
+type AdminHandler struct {
+	collector TrackingManager
+	reload    func() (config.TrackingConfig, error)
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+// reload re-reads the tracking configuration from the configured sources
+func NewAdminHandler(collector TrackingManager, reload func() (config.TrackingConfig, error)) *AdminHandler {
+	return &AdminHandler{
+		collector: collector,
+		reload:    reload,
+	}
+}
+
+// TrackingResponse describes the tracked series
+type TrackingResponse struct {
+	Symbols   []string         `json:"symbols"`
+	Intervals []string         `json:"intervals"`
+	Series    []service.Series `json:"series"`
+}
+
+// UpdateTrackingRequest is the body of PUT /api/v1/admin/tracking
+type UpdateTrackingRequest struct {
+	Symbols   []string `json:"symbols" binding:"required"`
+	Intervals []string `json:"intervals" binding:"required"`
+}
+
+// GetTracking handles GET /api/v1/admin/tracking request
+func (h *AdminHandler) GetTracking(c *gin.Context) {
+	tracking := h.collector.Tracking()
+	respondSuccess(c, TrackingResponse{
+		Symbols:   tracking.Symbols,
+		Intervals: tracking.Intervals,
+		Series:    h.collector.Series(),
+	})
+}
+
+// UpdateTracking handles PUT /api/v1/admin/tracking request
+// Replaces the tracked symbols and intervals until the next reload
+func (h *AdminHandler) UpdateTracking(c *gin.Context) {
+	var req UpdateTrackingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	tracking := h.collector.Tracking()
+	tracking.Symbols = req.Symbols
+	tracking.Intervals = req.Intervals
+
+	change, err := h.collector.Apply(tracking)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondSuccess(c, change)
+}
+
+// ReloadTracking handles POST /api/v1/admin/tracking/reload request
+// Re-reads the tracking configuration, as SIGHUP does
+func (h *AdminHandler) ReloadTracking(c *gin.Context) {
+	tracking, err := h.reload()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to reload configuration: "+err.Error())
+		return
+	}
+
+	change, err := h.collector.Apply(tracking)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondSuccess(c, change)
+}
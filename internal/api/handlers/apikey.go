@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyStore stores API keys
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uint64) (bool, error)
+}
+
+// APIKeyHandler handles API key management requests
+type APIKeyHandler struct {
+	store APIKeyStore
+	authn *auth.Authenticator
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance
+// Revoked keys are dropped from authn's cache at once.
+func NewAPIKeyHandler(store APIKeyStore, authn *auth.Authenticator) *APIKeyHandler {
+	return &APIKeyHandler{store: store, authn: authn}
+}
+
+// CreateAPIKeyRequest is the body of POST /api/v1/admin/keys
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+}
+
+// CreateAPIKeyResponse returns a new key; it is the only time the key is shown
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"api_key"`
+}
+
+// ListKeys handles GET /api/v1/admin/keys request
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.store.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondSuccess(c, keys)
+}
+
+// CreateKey handles POST /api/v1/admin/keys request
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		respondError(c, http.StatusBadRequest, "name must be 1 to 100 characters")
+		return
+	}
+	scopes, err := auth.ParseScopes(req.Scopes)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, record, err := auth.NewKey(name, scopes)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := h.store.CreateAPIKey(c.Request.Context(), record); err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIResponse{
+		Code:    http.StatusCreated,
+		Message: "created",
+		Data:    CreateAPIKeyResponse{Key: key, APIKey: record},
+	})
+}
+
+// RevokeKey handles DELETE /api/v1/admin/keys/:id request
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid id parameter")
+		return
+	}
+
+	revoked, err := h.store.RevokeAPIKey(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !revoked {
+		respondError(c, http.StatusNotFound, "API key not found")
+		return
+	}
+	h.authn.Forget(id)
+	respondSuccess(c, gin.H{"id": id, "revoked": true})
+}
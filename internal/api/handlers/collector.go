@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"crypto-monitor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CollectorHandler reports the state of always-on ingestion
+type CollectorHandler struct {
+	collector service.HealthSource
+}
+
+// NewCollectorHandler creates a new CollectorHandler instance
+func NewCollectorHandler(collector service.HealthSource) *CollectorHandler {
+	return &CollectorHandler{collector: collector}
+}
+
+// CollectorStatusResponse summarizes the health of every tracked series
+type CollectorStatusResponse struct {
+	Total   int                    `json:"total"`
+	Healthy int                    `json:"healthy"`
+	Series  []service.SeriesHealth `json:"series"`
+}
+
+// GetStatus handles GET /api/v1/collector/status request
+func (h *CollectorHandler) GetStatus(c *gin.Context) {
+	health := h.collector.Health()
+
+	resp := CollectorStatusResponse{Total: len(health), Series: health}
+	for _, series := range health {
+		if series.Healthy {
+			resp.Healthy++
+		}
+	}
+	respondSuccess(c, resp)
+}
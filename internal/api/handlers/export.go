@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+
+	"crypto-monitor/internal/export"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var handlerLog = logging.For(logging.HTTP)
+
+// ExportKlines handles GET /api/v1/klines/export request
+// Rows are streamed straight from the database cursor to the response
+// Query parameters:
+//   - symbol (required): trading pair symbol, e.g., "BTCUSDT"
+//   - interval (required): time interval, e.g., "1m", "5m", "1h"
+//   - start_time (optional): start timestamp in milliseconds
+//   - end_time (optional): end timestamp in milliseconds
+//   - format (optional): "csv" (default), "ndjson" or "parquet"
+//   - compression (optional): "gzip" to gzip the output
+func (h *KlineHandler) ExportKlines(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		respondError(c, http.StatusBadRequest, "symbol parameter is required")
+		return
+	}
+
+	interval := c.Query("interval")
+	if interval == "" {
+		respondError(c, http.StatusBadRequest, "interval parameter is required")
+		return
+	}
+
+	startTime, endTime, ok := parseTimeRange(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.IsValidFormat(format) {
+		respondError(c, http.StatusBadRequest, "invalid format parameter")
+		return
+	}
+
+	var gzipped bool
+	switch c.Query("compression") {
+	case "":
+	case "gzip":
+		gzipped = true
+	default:
+		respondError(c, http.StatusBadRequest, "invalid compression parameter")
+		return
+	}
+
+	if !h.klineRepo.IsConnected(c.Request.Context()) {
+		respondError(c, http.StatusServiceUnavailable, "database connection is not available")
+		return
+	}
+
+	// The writer is created before the status is set, so that it can still be
+	// refused; it writes nothing until the first row
+	w, err := export.NewWriter(c.Writer, format, gzipped)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format, gzipped))
+	c.Header("Content-Disposition", `attachment; filename="`+export.FileName(symbol, interval, format, gzipped)+`"`)
+	c.Status(http.StatusOK)
+
+	// Headers are already sent once the first row is written, so a failure
+	// past this point breaks the connection instead of ending the body
+	// cleanly, which would pass a truncated file off as complete
+	err = h.klineRepo.StreamKlines(c.Request.Context(), symbol, interval, startTime, endTime, func(kline models.Kline) error {
+		return w.Write(kline)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		handlerLog.WarnContext(c.Request.Context(), "Export aborted", "symbol", symbol, "interval", interval, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
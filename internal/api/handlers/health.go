@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"crypto-monitor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthChecks are the dependencies probed by the readiness endpoint
+type HealthChecks struct {
+	// Database reports whether the database answers
+	Database interface{ IsConnected(context.Context) bool }
+	// Migrations returns an error while the schema is not in place
+	Migrations func() error
+	// Series reports the state of the upstream streams
+	Series service.HealthSource
+}
+
+// HealthHandler handles liveness, readiness and data freshness requests
+type HealthHandler struct {
+	checks HealthChecks
+	store  service.LatestOpenTimeStore
+}
+
+// NewHealthHandler creates a new HealthHandler instance
+// store may be nil when the freshness endpoint is not served
+func NewHealthHandler(checks HealthChecks, store service.LatestOpenTimeStore) *HealthHandler {
+	return &HealthHandler{checks: checks, store: store}
+}
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ReadinessResponse lists the outcome of every readiness check
+type ReadinessResponse struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// FreshnessResponse reports how far stored klines trail the exchange
+type FreshnessResponse struct {
+	Total  int                       `json:"total"`
+	Stale  int                       `json:"stale"`
+	Series []service.SeriesFreshness `json:"series"`
+}
+
+// Liveness handles GET /healthz request
+// It only shows that the process serves requests; dependencies are left to readiness
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	respondSuccess(c, gin.H{"status": "ok"})
+}
+
+// Readiness handles GET /readyz request
+// Responds 503 unless the database answers, migrations are applied and the
+// upstream streams deliver
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	resp := ReadinessResponse{Ready: true, Checks: make(map[string]CheckResult)}
+	record := func(name string, result CheckResult) {
+		resp.Checks[name] = result
+		resp.Ready = resp.Ready && result.OK
+	}
+
+	if h.checks.Database != nil {
+		result := CheckResult{OK: h.checks.Database.IsConnected(c.Request.Context())}
+		if !result.OK {
+			result.Detail = "database is not reachable"
+		}
+		record("database", result)
+	}
+
+	if h.checks.Migrations != nil {
+		result := CheckResult{OK: true}
+		if err := h.checks.Migrations(); err != nil {
+			result = CheckResult{OK: false, Detail: err.Error()}
+		}
+		record("migrations", result)
+	}
+
+	if h.checks.Series != nil {
+		record("streams", checkStreams(h.checks.Series.Health()))
+	}
+
+	if !resp.Ready {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{
+			Code:    http.StatusServiceUnavailable,
+			Message: "not ready",
+			Data:    resp,
+		})
+		return
+	}
+	respondSuccess(c, resp)
+}
+
+// checkStreams passes while no series is tracked or at least one is healthy,
+// so a single delisted symbol does not take the server out of rotation
+func checkStreams(health []service.SeriesHealth) CheckResult {
+	healthy := 0
+	for _, series := range health {
+		if series.Healthy {
+			healthy++
+		}
+	}
+
+	result := CheckResult{OK: len(health) == 0 || healthy > 0}
+	if healthy < len(health) {
+		result.Detail = fmt.Sprintf("%d of %d series unhealthy", len(health)-healthy, len(health))
+	}
+	return result
+}
+
+// GetFreshness handles GET /api/v1/status/freshness request
+// Compares the latest stored open_time of every tracked series with the most
+// recently closed candle
+func (h *HealthHandler) GetFreshness(c *gin.Context) {
+	health := h.checks.Series.Health()
+	series := make([]service.Series, len(health))
+	for i, s := range health {
+		series[i] = service.Series{Symbol: s.Symbol, Interval: s.Interval}
+	}
+
+	freshness, err := service.CheckFreshness(c.Request.Context(), h.store, series, time.Now())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := FreshnessResponse{Total: len(freshness), Series: freshness}
+	for _, f := range freshness {
+		if f.Stale {
+			resp.Stale++
+		}
+	}
+	respondSuccess(c, resp)
+}
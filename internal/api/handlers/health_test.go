@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"crypto-monitor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeDatabase bool
+
+func (f fakeDatabase) IsConnected(context.Context) bool { return bool(f) }
+
+type fakeHealth []service.SeriesHealth
+
+func (f fakeHealth) Health() []service.SeriesHealth { return f }
+
+// serveReadiness returns the status and body of GET /readyz with checks
+func serveReadiness(t *testing.T, checks HealthChecks) (int, ReadinessResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", NewHealthHandler(checks, nil).Readiness)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var body struct {
+		Data ReadinessResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w.Code, body.Data
+}
+
+// TestHealthHandler_Readiness tests that each failing check makes the server unready
+func TestHealthHandler_Readiness(t *testing.T) {
+	healthy := fakeHealth{
+		{Symbol: "BTCUSDT", Interval: "1m", Healthy: true},
+		{Symbol: "ETHUSDT", Interval: "1m"},
+	}
+	ready := HealthChecks{
+		Database:   fakeDatabase(true),
+		Migrations: func() error { return nil },
+		Series:     healthy,
+	}
+
+	code, resp := serveReadiness(t, ready)
+	if code != http.StatusOK || !resp.Ready {
+		t.Fatalf("Expected ready, got %d %+v", code, resp)
+	}
+	if resp.Checks["streams"].Detail != "1 of 2 series unhealthy" {
+		t.Errorf("Expected unhealthy series to be reported, got %+v", resp.Checks["streams"])
+	}
+
+	tests := []struct {
+		name   string
+		check  string
+		modify func(*HealthChecks)
+	}{
+		{"database down", "database", func(c *HealthChecks) { c.Database = fakeDatabase(false) }},
+		{"schema missing", "migrations", func(c *HealthChecks) { c.Migrations = func() error { return errors.New("table missing") } }},
+		{"no stream delivering", "streams", func(c *HealthChecks) { c.Series = fakeHealth{{Symbol: "BTCUSDT", Interval: "1m"}} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := ready
+			tt.modify(&checks)
+			code, resp := serveReadiness(t, checks)
+			if code != http.StatusServiceUnavailable || resp.Ready {
+				t.Errorf("Expected 503, got %d %+v", code, resp)
+			}
+			if resp.Checks[tt.check].OK {
+				t.Errorf("Expected %s check to fail, got %+v", tt.check, resp.Checks)
+			}
+		})
+	}
+}
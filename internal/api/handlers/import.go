@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"crypto-monitor/internal/importer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles bulk kline import requests
+type ImportHandler struct {
+	jobs    *importer.JobManager
+	baseDir string
+}
+
+// NewImportHandler creates a new ImportHandler instance
+// Import paths are resolved relative to baseDir and may not escape it
+func NewImportHandler(jobs *importer.JobManager, baseDir string) *ImportHandler {
+	return &ImportHandler{
+		jobs:    jobs,
+		baseDir: baseDir,
+	}
+}
+
+// CreateImportRequest is the body of POST /api/v1/imports
+type CreateImportRequest struct {
+	Path     string                  `json:"path" binding:"required"`
+	Symbol   string                  `json:"symbol"`
+	Interval string                  `json:"interval"`
+	Format   string                  `json:"format"`
+	Mapping  *importer.ColumnMapping `json:"mapping"`
+	TimeUnit string                  `json:"time_unit"`
+	Force    bool                    `json:"force"`
+}
+
+// CreateImport handles POST /api/v1/imports request
+// Starts an asynchronous import of a file or directory under the import directory
+// and responds with the queued job
+func (h *ImportHandler) CreateImport(c *gin.Context) {
+	var req CreateImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	switch req.Format {
+	case "", importer.FormatBinance, importer.FormatGeneric:
+	default:
+		respondError(c, http.StatusBadRequest, "invalid format parameter")
+		return
+	}
+
+	if req.Format == importer.FormatGeneric && (req.Symbol == "" || req.Interval == "") {
+		respondError(c, http.StatusBadRequest, "symbol and interval are required for generic imports")
+		return
+	}
+
+	opts := importer.Options{
+		Symbol:   strings.ToUpper(req.Symbol),
+		Interval: req.Interval,
+		Format:   req.Format,
+		TimeUnit: req.TimeUnit,
+		Force:    req.Force,
+	}
+	if req.Mapping != nil {
+		opts.Mapping = *req.Mapping
+	}
+
+	path, err := h.resolve(req.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		respondError(c, http.StatusNotFound, "import path not found")
+		return
+	case errors.Is(err, errOutsideImportDir):
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		handlerLog.ErrorContext(c.Request.Context(), "Failed to resolve import path", "path", req.Path, "error", err)
+		respondError(c, http.StatusInternalServerError, "failed to resolve import path")
+		return
+	}
+
+	job := h.jobs.Start(c.Request.Context(), path, opts)
+	c.JSON(http.StatusAccepted, APIResponse{
+		Code:    http.StatusAccepted,
+		Message: "accepted",
+		Data:    job,
+	})
+}
+
+// errOutsideImportDir is returned for import paths leaving the import directory
+var errOutsideImportDir = errors.New("import path must be inside the import directory")
+
+// resolve returns the path of an import request inside baseDir, with its
+// symlinks resolved so that none leads out of it
+func (h *ImportHandler) resolve(path string) (string, error) {
+	base, err := filepath.EvalSymlinks(h.baseDir)
+	if err != nil {
+		return "", err
+	}
+	// Cleaning against the root keeps ".." segments from leaving baseDir
+	resolved, err := filepath.EvalSymlinks(filepath.Join(base, filepath.Clean("/"+path)))
+	if err != nil {
+		return "", err
+	}
+	if resolved != base && !strings.HasPrefix(resolved, base+string(filepath.Separator)) {
+		return "", errOutsideImportDir
+	}
+	return resolved, nil
+}
+
+// ListImports handles GET /api/v1/imports request
+func (h *ImportHandler) ListImports(c *gin.Context) {
+	respondSuccess(c, h.jobs.List())
+}
+
+// GetImport handles GET /api/v1/imports/:id request
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	job, ok := h.jobs.Get(c.Param("id"))
+	if !ok {
+		respondError(c, http.StatusNotFound, "import job not found")
+		return
+	}
+	respondSuccess(c, job)
+}
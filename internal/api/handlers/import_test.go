@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crypto-monitor/internal/importer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestImportHandler_CreateImport_Paths tests that imports are confined to the
+// import directory, symlinks included
+func TestImportHandler_CreateImport_Paths(t *testing.T) {
+	base, outside := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(base, "archives"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(base, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/imports", NewImportHandler(importer.NewJobManager(importer.NewImporter(nil, nil)), base).CreateImport)
+
+	resolvedBase, _ := filepath.EvalSymlinks(base)
+	for _, tt := range []struct {
+		path     string
+		want     int
+		wantPath string
+	}{
+		{"archives", http.StatusAccepted, filepath.Join(resolvedBase, "archives")},
+		{"../../archives", http.StatusAccepted, filepath.Join(resolvedBase, "archives")},
+		{"escape", http.StatusBadRequest, ""},
+		{"escape/archives", http.StatusNotFound, ""},
+		{outside, http.StatusNotFound, ""},
+		{"missing", http.StatusNotFound, ""},
+	} {
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(CreateImportRequest{Path: tt.path})
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/imports", bytes.NewReader(body)))
+		if w.Code != tt.want {
+			t.Errorf("Expected %d importing %q, got %d: %s", tt.want, tt.path, w.Code, w.Body.String())
+			continue
+		}
+		if tt.wantPath == "" {
+			continue
+		}
+		var resp struct {
+			Data importer.Job `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.Path != tt.wantPath {
+			t.Errorf("Expected the job to import %s, got %+v %v", tt.wantPath, resp.Data, err)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
-	"crypto-monitor/internal/repository"
+	"context"
+	"crypto-monitor/internal/models"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -15,15 +17,25 @@ type APIResponse struct {
 	Data    interface{} `json:"data"`
 }
 
+// KlineStore reads stored klines
+type KlineStore interface {
+	GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error)
+	StreamKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, fn func(models.Kline) error) error
+	IsConnected(ctx context.Context) bool
+}
+
 // KlineHandler handles K-line related API requests
 type KlineHandler struct {
-	klineRepo *repository.KlineRepository
+	klineRepo KlineStore
+	maxLimit  int
 }
 
 // NewKlineHandler creates a new KlineHandler instance
-func NewKlineHandler(klineRepo *repository.KlineRepository) *KlineHandler {
+// Queries may ask for at most maxLimit klines; 0 is unlimited
+func NewKlineHandler(klineRepo KlineStore, maxLimit int) *KlineHandler {
 	return &KlineHandler{
 		klineRepo: klineRepo,
+		maxLimit:  maxLimit,
 	}
 }
 
@@ -33,7 +45,8 @@ func NewKlineHandler(klineRepo *repository.KlineRepository) *KlineHandler {
 //   - interval (required): time interval, e.g., "1m", "5m", "1h"
 //   - start_time (optional): start timestamp in milliseconds
 //   - end_time (optional): end timestamp in milliseconds
-//   - limit (optional): maximum number of records, default 1000
+//   - limit (optional): maximum number of records, default 1000, at most the
+//     configured maximum
 func (h *KlineHandler) GetKlines(c *gin.Context) {
 	// Validate required parameters
 	symbol := c.Query("symbol")
@@ -49,24 +62,9 @@ func (h *KlineHandler) GetKlines(c *gin.Context) {
 	}
 
 	// Parse optional parameters
-	var startTime *int64
-	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
-		val, err := strconv.ParseInt(startTimeStr, 10, 64)
-		if err != nil {
-			respondError(c, http.StatusBadRequest, "invalid start_time parameter")
-			return
-		}
-		startTime = &val
-	}
-
-	var endTime *int64
-	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
-		val, err := strconv.ParseInt(endTimeStr, 10, 64)
-		if err != nil {
-			respondError(c, http.StatusBadRequest, "invalid end_time parameter")
-			return
-		}
-		endTime = &val
+	startTime, endTime, ok := parseTimeRange(c)
+	if !ok {
+		return
 	}
 
 	limit := 1000 // default limit
@@ -78,9 +76,13 @@ func (h *KlineHandler) GetKlines(c *gin.Context) {
 		}
 		limit = val
 	}
+	if h.maxLimit > 0 && limit > h.maxLimit {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("limit must not exceed %d", h.maxLimit))
+		return
+	}
 
 	// Query klines from repository
-	klines, err := h.klineRepo.GetKlines(symbol, interval, startTime, endTime, limit)
+	klines, err := h.klineRepo.GetKlines(c.Request.Context(), symbol, interval, startTime, endTime, limit)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "failed to query klines: "+err.Error())
 		return
@@ -128,6 +130,30 @@ func (h *KlineHandler) GetSymbols(c *gin.Context) {
 	respondSuccess(c, symbols)
 }
 
+// parseTimeRange parses the optional start_time and end_time query parameters
+// On invalid input it writes a 400 response and returns ok=false
+func parseTimeRange(c *gin.Context) (startTime, endTime *int64, ok bool) {
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		val, err := strconv.ParseInt(startTimeStr, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid start_time parameter")
+			return nil, nil, false
+		}
+		startTime = &val
+	}
+
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		val, err := strconv.ParseInt(endTimeStr, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid end_time parameter")
+			return nil, nil, false
+		}
+		endTime = &val
+	}
+
+	return startTime, endTime, true
+}
+
 // respondSuccess sends a successful API response
 func respondSuccess(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, APIResponse{
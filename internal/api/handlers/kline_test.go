@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"crypto-monitor/internal/config"
 	"crypto-monitor/internal/models"
 	"crypto-monitor/internal/repository"
 	"crypto-monitor/pkg/database"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -23,14 +28,19 @@ func setupTestHandler(t *testing.T) (*KlineHandler, *gin.Engine) {
 	os.Setenv("DB_PASSWORD", "postgres")
 	os.Setenv("DB_NAME", "crypto_monitor")
 
-	db, err := database.InitDB()
+	cfg, err := config.LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		t.Skipf("Skipping test: database connection failed: %v", err)
 		return nil, nil
 	}
 
 	klineRepo := repository.NewKlineRepository(db)
-	handler := NewKlineHandler(klineRepo)
+	handler := NewKlineHandler(klineRepo, 0)
 
 	// Create test data
 	now := time.Now().UnixMilli()
@@ -46,13 +56,14 @@ func setupTestHandler(t *testing.T) (*KlineHandler, *gin.Engine) {
 			ClosePrice: 50500.0 + float64(i),
 			Volume:     100.5 + float64(i),
 		}
-		klineRepo.CreateOrUpdateKline(kline)
+		klineRepo.CreateOrUpdateKline(t.Context(), kline)
 	}
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
 	router.GET("/api/v1/klines", handler.GetKlines)
 	router.GET("/api/v1/symbols", handler.GetSymbols)
+	router.GET("/api/v1/klines/export", handler.ExportKlines)
 
 	return handler, router
 }
@@ -208,3 +219,97 @@ func TestKlineHandler_GetSymbols(t *testing.T) {
 		}
 	}
 }
+
+// TestKlineHandler_ExportKlines tests GET /api/v1/klines/export endpoint
+func TestKlineHandler_ExportKlines(t *testing.T) {
+	_, router := setupTestHandler(t)
+	if router == nil {
+		return
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/klines/export?symbol=BTCUSDT&interval=1m&format=csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code 200, got %d", w.Code)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Expected Content-Type text/csv, got %s", ct)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	if len(records) < 2 {
+		t.Fatal("Expected header and at least one row")
+	}
+
+	// Verify ordering (should be ascending by open_time)
+	for i := 2; i < len(records); i++ {
+		if records[i][2] < records[i-1][2] {
+			t.Error("Expected rows to be ordered by open_time ascending")
+		}
+	}
+
+	// Test invalid format
+	req, _ = http.NewRequest("GET", "/api/v1/klines/export?symbol=BTCUSDT&interval=1m&format=xlsx", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code 400, got %d", w.Code)
+	}
+}
+
+// failingKlines streams a number of klines and then fails, as a database
+// connection lost partway through an export does
+type failingKlines struct {
+	rows int
+}
+
+func (f failingKlines) GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error) {
+	return nil, nil
+}
+
+func (f failingKlines) StreamKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, fn func(models.Kline) error) error {
+	for i := 0; i < f.rows; i++ {
+		if err := fn(models.Kline{Symbol: symbol, Interval: interval, OpenTime: int64(i) * 60000}); err != nil {
+			return err
+		}
+	}
+	return errors.New("connection reset")
+}
+
+func (f failingKlines) IsConnected(ctx context.Context) bool {
+	return true
+}
+
+// TestKlineHandler_ExportKlines_StreamFailure tests that an export failing
+// partway breaks the connection rather than ending a truncated file cleanly
+func TestKlineHandler_ExportKlines_StreamFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/klines/export", NewKlineHandler(failingKlines{rows: 10000}, 0).ExportKlines)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/klines/export?symbol=BTCUSDT&interval=1m")
+	if err != nil {
+		t.Fatalf("Failed to request export: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the export to start with status code 200, got %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("Expected the transfer to break, read %d bytes cleanly", len(body))
+	}
+	if len(body) == 0 {
+		t.Error("Expected rows to be sent before the failure")
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"crypto-monitor/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KlineStreams opens server-sent event streams of live klines
+type KlineStreams interface {
+	OpenEventStream(req service.StreamRequest) (*service.EventStream, error)
+}
+
+// StreamHandler serves live klines as server-sent events
+type StreamHandler struct {
+	streams KlineStreams
+}
+
+// NewStreamHandler creates a new StreamHandler instance
+func NewStreamHandler(streams KlineStreams) *StreamHandler {
+	return &StreamHandler{streams: streams}
+}
+
+// StreamKlines handles GET /api/v1/stream/klines request
+// Events are the websocket messages of the series, named by their type; the
+// messages of the series carry their seq, prefixed with the epoch of the
+// series, as the event id, so a client reconnecting with Last-Event-ID gets
+// the updates it missed replayed, or a new snapshot when they are no longer
+// kept.
+// Query parameters:
+//   - symbol (required): trading pair symbol, e.g., "BTCUSDT"
+//   - interval (required): time interval, e.g., "1m", "5m", "1h"
+//   - snapshot (optional): number of recent klines sent before the updates
+//   - throttle_ms (optional): minimum interval between updates
+func (h *StreamHandler) StreamKlines(c *gin.Context) {
+	req := service.StreamRequest{
+		Symbol:     c.Query("symbol"),
+		Interval:   c.Query("interval"),
+		RemoteAddr: c.ClientIP(),
+	}
+	if req.Symbol == "" {
+		respondError(c, http.StatusBadRequest, "symbol parameter is required")
+		return
+	}
+	if req.Interval == "" {
+		respondError(c, http.StatusBadRequest, "interval parameter is required")
+		return
+	}
+	if s := c.Query("snapshot"); s != "" {
+		val, err := strconv.Atoi(s)
+		if err != nil || val < 0 {
+			respondError(c, http.StatusBadRequest, "invalid snapshot parameter")
+			return
+		}
+		req.Snapshot = val
+	}
+	if s := c.Query("throttle_ms"); s != "" {
+		val, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || val < 0 {
+			respondError(c, http.StatusBadRequest, "invalid throttle_ms parameter")
+			return
+		}
+		req.ThrottleMs = val
+	}
+	// A reconnecting client sends the id of the last event it received
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		epoch, seq, err := service.ParseEventID(id)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid Last-Event-ID header")
+			return
+		}
+		req.Resume, req.Epoch, req.Seq = true, epoch, seq
+	}
+
+	stream, err := h.streams.OpenEventStream(req)
+	switch {
+	case errors.Is(err, service.ErrNotTracked):
+		respondError(c, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, service.ErrSnapshotUnavailable), errors.Is(err, service.ErrShuttingDown):
+		respondError(c, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The stream lasts as long as the client reads it, whatever the server's
+	// write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Keep proxies such as nginx from buffering the events
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	if err := stream.Serve(c.Request.Context(), c.Writer, c.Writer.Flush); err != nil {
+		handlerLog.DebugContext(c.Request.Context(), "Event stream ended", "symbol", req.Symbol, "interval", req.Interval, "error", err)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/service"
+	"crypto-monitor/internal/service/servicetest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupStreamServer serves the stream endpoint of a running websocket service
+func setupStreamServer(t *testing.T) (*service.WebSocketService, *httptest.Server) {
+	t.Helper()
+	wsSvc := service.NewWebSocketService(servicetest.TrackedSeries{}, nil, config.Default().WebSocket)
+	ctx, cancel := context.WithCancel(context.Background())
+	go wsSvc.Run(ctx)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/stream/klines", NewStreamHandler(wsSvc).StreamKlines)
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		server.Close()
+		cancel()
+	})
+	return wsSvc, server
+}
+
+// openStream requests a stream and returns the response and its lines
+func openStream(t *testing.T, url, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to request stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// readEventLines reads the lines of the next event
+func readEventLines(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if line = strings.TrimSuffix(line, "\n"); line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// TestStreamHandler_StreamKlines tests streaming updates as server-sent
+// events and resuming them with Last-Event-ID
+func TestStreamHandler_StreamKlines(t *testing.T) {
+	wsSvc, server := setupStreamServer(t)
+	url := server.URL + "/api/v1/stream/klines?symbol=BTCUSDT&interval=1m&throttle_ms=100"
+
+	resp, events := openStream(t, url, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if lines := readEventLines(t, events); len(lines) != 3 || lines[1] != "event: subscribed" {
+		t.Fatalf("Expected subscribed, got %q", lines)
+	}
+
+	var ids []string
+	for i := int64(1); i <= 2; i++ {
+		wsSvc.OnKline(t.Context(), models.Kline{Symbol: "BTCUSDT", Interval: "1m", OpenTime: i * 60000, ClosePrice: 50000})
+		lines := readEventLines(t, events)
+		if len(lines) != 3 || !strings.HasSuffix(lines[0], "-"+strconv.FormatInt(i, 10)) || lines[1] != "event: kline_update" || !strings.HasPrefix(lines[2], `data: {"type":"kline_update"`) {
+			t.Fatalf("Expected update %d, got %q", i, lines)
+		}
+		ids = append(ids, strings.TrimPrefix(lines[0], "id: "))
+	}
+
+	// Update 2 is replayed to a client that last received update 1
+	_, resumed := openStream(t, url, ids[0])
+	if lines := readEventLines(t, resumed); lines[1] != "event: resumed" {
+		t.Fatalf("Expected resumed, got %q", lines)
+	}
+	if lines := readEventLines(t, resumed); lines[0] != "id: "+ids[1] {
+		t.Errorf("Expected update 2 replayed, got %q", lines)
+	}
+
+	// The id of another topic of the series is not resumed
+	_, subscribed := openStream(t, url, "1-1")
+	if lines := readEventLines(t, subscribed); lines[1] != "event: subscribed" {
+		t.Errorf("Expected subscribed resuming another topic, got %q", lines)
+	}
+}
+
+// TestStreamHandler_StreamKlines_Errors tests that streams that cannot be
+// served are refused before streaming
+func TestStreamHandler_StreamKlines_Errors(t *testing.T) {
+	_, server := setupStreamServer(t)
+	for _, tt := range []struct {
+		query       string
+		lastEventID string
+		want        int
+	}{
+		{"interval=1m", "", http.StatusBadRequest},
+		{"symbol=BTCUSDT&interval=1m&snapshot=-1", "", http.StatusBadRequest},
+		{"symbol=BTCUSDT&interval=1m", "latest", http.StatusBadRequest},
+		{"symbol=BTCUSDT&interval=1m", "1", http.StatusBadRequest},
+		{"symbol=ETHUSDT&interval=1m", "", http.StatusNotFound},
+	} {
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/stream/klines?"+tt.query, nil)
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to request %s: %v", tt.query, err)
+		}
+		resp.Body.Close()
+		cancel()
+		if resp.StatusCode != tt.want {
+			t.Errorf("Expected %d for %s, got %d", tt.want, tt.query, resp.StatusCode)
+		}
+	}
+}
@@ -1,19 +1,81 @@
 package api
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client supplied request IDs
+const maxRequestIDLength = 128
+
+// serverName identifies the API in the attributes of request spans
+const serverName = "crypto-monitor"
+
+var httpLog = logging.For(logging.HTTP)
+
+// quietRoutes are polled by monitoring, so their successful requests are
+// logged at debug level only
+var quietRoutes = map[string]bool{"/metrics": true, "/healthz": true, "/readyz": true}
+
+// RequestIDMiddleware assigns every request an ID, reusing X-Request-ID when
+// the client sends one, echoes it in the response and stores it in the request
+// context so that log records of the request carry it
+// The request span started by TracingMiddleware, if any, is tagged with the ID.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		ctx := c.Request.Context()
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request_id", id))
+		c.Request = c.Request.WithContext(logging.WithRequestID(ctx, id))
+		c.Next()
+	}
+}
+
+// TracingMiddleware starts a span for every request, continuing the trace of
+// a traceparent header; polled monitoring routes are not traced
+func TracingMiddleware() gin.HandlerFunc {
+	return otelgin.Middleware(serverName, otelgin.WithFilter(func(r *http.Request) bool {
+		return !quietRoutes[r.URL.Path]
+	}))
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // ErrorHandlerMiddleware handles panics and errors
 func ErrorHandlerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				// A handler aborting a response already under way has the
+				// server break the connection
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				httpLog.ErrorContext(c.Request.Context(), "Panic recovered", "panic", err, "path", c.Request.URL.Path)
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"code":    500,
 					"message": "Internal server error",
@@ -26,7 +88,7 @@ func ErrorHandlerMiddleware() gin.HandlerFunc {
 	}
 }
 
-// LoggerMiddleware logs HTTP requests
+// LoggerMiddleware logs HTTP requests and records their latency
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -38,6 +100,30 @@ func LoggerMiddleware() gin.HandlerFunc {
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
-		log.Printf("%s %s %d %v", method, path, status, latency)
+		// Label by route pattern rather than path to keep cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(latency.Seconds())
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case quietRoutes[route]:
+			level = slog.LevelDebug
+		}
+		httpLog.LogAttrs(c.Request.Context(), level, "HTTP request",
+			slog.String("method", method),
+			slog.String("path", path),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		)
 	}
 }
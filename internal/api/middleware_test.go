@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestLoggerMiddleware_Metrics tests that request latency is recorded by route and served on /metrics
+func TestLoggerMiddleware_Metrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(LoggerMiddleware())
+	r.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	for _, path := range []string{"/items/1", "/items/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`crypto_monitor_http_request_duration_seconds_count{method="GET",route="/items/:id",status="204"} 2`,
+		`crypto_monitor_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected /metrics to contain %q", want)
+		}
+	}
+}
+
+// TestRequestIDMiddleware tests that request IDs are generated or reused and echoed
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	var seen string
+	r.GET("/", func(c *gin.Context) { seen = logging.RequestID(c.Request.Context()) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if generated := w.Header().Get(RequestIDHeader); len(generated) != 32 || seen != generated {
+		t.Errorf("Expected a generated ID in the header and context, got %q and %q", generated, seen)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "client-id")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get(RequestIDHeader) != "client-id" || seen != "client-id" {
+		t.Errorf("Expected the client's request ID to be reused, got %q", seen)
+	}
+}
+
+// TestTracingMiddleware tests that requests continue the caller's trace and
+// that monitoring routes are not traced
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TracingMiddleware(), RequestIDMiddleware())
+	var handlerSpan trace.SpanContext
+	r.GET("/items/:id", func(c *gin.Context) { handlerSpan = trace.SpanContextFromContext(c.Request.Context()) })
+	r.GET("/healthz", func(c *gin.Context) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	req.Header.Set(RequestIDHeader, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one request span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "/items/:id" || span.Parent().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("Expected a /items/:id span continuing the caller's trace, got %q in trace %s", span.Name(), span.Parent().TraceID())
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Error("Expected handlers to run in the request span")
+	}
+	var requestID string
+	for _, attr := range span.Attributes() {
+		if attr.Key == "http.request_id" {
+			requestID = attr.Value.AsString()
+		}
+	}
+	if requestID != "req-1" {
+		t.Errorf("Expected the request ID on the span, got %q", requestID)
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientID identifies the client of a request for rate limiting: its API key
+// once authenticated, otherwise its address
+func ClientID(c *gin.Context) string {
+	if principal := auth.PrincipalFrom(c.Request.Context()); principal != nil {
+		return "key:" + strconv.FormatUint(principal.KeyID, 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitMiddleware rejects requests of clients whose token bucket is
+// empty with 429 and a Retry-After header; it must follow AuthMiddleware
+// for clients to be limited by key
+func RateLimitMiddleware(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, retry := limiter.Allow(ClientID(c))
+		if !ok {
+			metrics.RateLimited.WithLabelValues("rest").Inc()
+			abortTooManyRequests(c, fmt.Sprintf("rate limit exceeded, retry in %s", retry.Round(time.Millisecond)), retry)
+			return
+		}
+		c.Next()
+	}
+}
+
+// WebSocketConnectionLimit rejects handshakes of clients that already hold
+// the maximum number of websocket connections
+// The connection is counted until the rest of the handler chain, which serves
+// it, returns.
+func WebSocketConnectionLimit(conns *ratelimit.Counter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, ok := conns.Acquire(ClientID(c))
+		if !ok {
+			metrics.RateLimited.WithLabelValues("ws_connections").Inc()
+			abortTooManyRequests(c, fmt.Sprintf("too many websocket connections, at most %d per client; close one before reconnecting", conns.Max()), 0)
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
+
+// abortTooManyRequests rejects a request with 429; a positive retry is sent
+// as Retry-After, rounded up to whole seconds, and in the body in milliseconds
+func abortTooManyRequests(c *gin.Context, message string, retry time.Duration) {
+	var data gin.H
+	if retry > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		data = gin.H{"retry_after_ms": retry.Milliseconds()}
+	}
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"code":    http.StatusTooManyRequests,
+		"message": message,
+		"data":    data,
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/auth/authtest"
+	"crypto-monitor/internal/ratelimit"
+	"crypto-monitor/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRateLimitMiddleware tests that clients are limited by key with a retry hint
+func TestRateLimitMiddleware(t *testing.T) {
+	store := &authtest.KeyStore{}
+	first := store.Issue(t, auth.ScopeMarketRead)
+	second := store.Issue(t, auth.ScopeMarketRead)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, Dependencies{
+		KlineRepo:   repository.NewKlineRepository(nil),
+		Auth:        auth.NewAuthenticator(store, time.Minute),
+		RateLimiter: ratelimit.NewLimiter(0.5, 2),
+	})
+
+	for i := 0; i < 2; i++ {
+		if w := serve(r, http.MethodGet, "/api/v1/klines", first, ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected request %d within the burst to reach the handler, got %d", i+1, w.Code)
+		}
+	}
+
+	w := serve(r, http.MethodGet, "/api/v1/klines", first, "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 past the burst, got %d", w.Code)
+	}
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry < 1 || retry > 2 {
+		t.Errorf("Expected Retry-After of one or two seconds, got %q", w.Header().Get("Retry-After"))
+	}
+
+	if w := serve(r, http.MethodGet, "/api/v1/klines", second, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected another key to have its own bucket, got %d", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/healthz", "", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected health endpoints not to be limited, got %d", w.Code)
+	}
+}
+
+// TestKlineHandler_MaxLimit tests that kline queries cannot ask for more than the configured maximum
+func TestKlineHandler_MaxLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, Dependencies{
+		KlineRepo: repository.NewKlineRepository(nil),
+		MaxKlines: 100,
+	})
+
+	if w := serve(r, http.MethodGet, "/api/v1/klines?symbol=BTCUSDT&interval=1m&limit=101", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a limit over the maximum, got %d", w.Code)
+	}
+}
+
+// TestWebSocketConnectionLimit tests that a client holds at most the maximum number of connections
+func TestWebSocketConnectionLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	r.GET("/ws", WebSocketConnectionLimit(ratelimit.NewCounter(1)), func(c *gin.Context) {
+		if c.Query("hold") != "" {
+			entered <- struct{}{}
+			<-release
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	held := make(chan int)
+	go func() {
+		held <- serve(r, http.MethodGet, "/ws?hold=1", "", "").Code
+	}()
+	<-entered
+
+	if w := serve(r, http.MethodGet, "/ws", "", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 while the client holds a connection, got %d", w.Code)
+	}
+
+	close(release)
+	if code := <-held; code != http.StatusNoContent {
+		t.Fatalf("Expected the held connection to be served, got %d", code)
+	}
+	if w := serve(r, http.MethodGet, "/ws", "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected a connection once the previous one closed, got %d", w.Code)
+	}
+}
@@ -2,25 +2,143 @@ package api
 
 import (
 	"crypto-monitor/internal/api/handlers"
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/importer"
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/ratelimit"
 	"crypto-monitor/internal/repository"
+	"crypto-monitor/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Dependencies are the services the API routes are served from
+type Dependencies struct {
+	KlineRepo  *repository.KlineRepository
+	ImportJobs *importer.JobManager
+	// ImportDir is the only directory imports may read from
+	ImportDir string
+	// CollectorStatus reports the health of the tracked series
+	CollectorStatus service.HealthSource
+	// Tracking changes the tracked series; admin endpoints are only served when
+	// the collector runs in the same process
+	Tracking       handlers.TrackingManager
+	ReloadTracking func() (config.TrackingConfig, error)
+	// CheckMigrations returns an error while the schema is not in place
+	CheckMigrations func() error
+	// Auth verifies API keys; nil leaves the API open
+	Auth    *auth.Authenticator
+	APIKeys handlers.APIKeyStore
+	// RateLimiter limits the requests of each client; nil disables limiting
+	RateLimiter *ratelimit.Limiter
+	// MaxKlines caps the limit of a kline query; 0 is unlimited
+	MaxKlines int
+	// Streams serves live klines as server-sent events; nil disables the
+	// stream endpoint
+	Streams handlers.KlineStreams
+	// Connections counts the websocket connections and event streams of each
+	// client; nil leaves streams uncounted
+	Connections *ratelimit.Counter
+}
+
+// SetupOpsRoutes configures the metrics, liveness and readiness endpoints
+func SetupOpsRoutes(r *gin.Engine, healthHandler *handlers.HealthHandler) {
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+}
+
 // SetupRoutes configures all API routes
-func SetupRoutes(r *gin.Engine, klineRepo *repository.KlineRepository) {
+func SetupRoutes(r *gin.Engine, deps Dependencies) {
 	// Apply middleware
+	r.Use(TracingMiddleware())
+	r.Use(RequestIDMiddleware())
 	r.Use(LoggerMiddleware())
 	r.Use(ErrorHandlerMiddleware())
 
-	// API v1 routes
+	healthHandler := handlers.NewHealthHandler(handlers.HealthChecks{
+		Database:   deps.KlineRepo,
+		Migrations: deps.CheckMigrations,
+		Series:     deps.CollectorStatus,
+	}, deps.KlineRepo)
+	SetupOpsRoutes(r, healthHandler)
+
+	// API v1 routes; with authentication enabled every route needs a key
+	// granting its scope
 	v1 := r.Group("/api/v1")
+	if deps.Auth != nil {
+		v1.Use(AuthMiddleware(deps.Auth))
+	}
+	if deps.RateLimiter != nil {
+		v1.Use(RateLimitMiddleware(deps.RateLimiter))
+	}
+	market := v1.Group("", scoped(deps.Auth, auth.ScopeMarketRead)...)
+	admin := v1.Group("", scoped(deps.Auth, auth.ScopeAdmin)...)
 	{
 		// Initialize handlers
-		klineHandler := handlers.NewKlineHandler(klineRepo)
+		klineHandler := handlers.NewKlineHandler(deps.KlineRepo, deps.MaxKlines)
+		importHandler := handlers.NewImportHandler(deps.ImportJobs, deps.ImportDir)
+		collectorHandler := handlers.NewCollectorHandler(deps.CollectorStatus)
 
 		// Kline endpoints
-		v1.GET("/klines", klineHandler.GetKlines)
-		v1.GET("/symbols", klineHandler.GetSymbols)
+		market.GET("/klines", klineHandler.GetKlines)
+		market.GET("/klines/export", klineHandler.ExportKlines)
+		market.GET("/symbols", klineHandler.GetSymbols)
+
+		// Import endpoints
+		admin.POST("/imports", importHandler.CreateImport)
+		admin.GET("/imports", importHandler.ListImports)
+		admin.GET("/imports/:id", importHandler.GetImport)
+
+		// Collector endpoints
+		market.GET("/collector/status", collectorHandler.GetStatus)
+
+		// Status endpoints
+		market.GET("/status/freshness", healthHandler.GetFreshness)
+
+		// Admin endpoints
+		if deps.Tracking != nil {
+			adminHandler := handlers.NewAdminHandler(deps.Tracking, deps.ReloadTracking)
+			admin.GET("/admin/tracking", adminHandler.GetTracking)
+			admin.PUT("/admin/tracking", adminHandler.UpdateTracking)
+			admin.POST("/admin/tracking/reload", adminHandler.ReloadTracking)
+		}
+
+		// API keys are only managed while they are enforced, so that keys
+		// cannot be minted by anyone while the API is open
+		if deps.Auth != nil {
+			keyHandler := handlers.NewAPIKeyHandler(deps.APIKeys, deps.Auth)
+			admin.GET("/admin/keys", keyHandler.ListKeys)
+			admin.POST("/admin/keys", keyHandler.CreateKey)
+			admin.DELETE("/admin/keys/:id", keyHandler.RevokeKey)
+		}
+	}
+
+	// Event streams take the key in the token query parameter too, since
+	// EventSource cannot set headers any more than websocket handshakes can,
+	// and count as connections of the client like them
+	if deps.Streams != nil {
+		stream := r.Group("/api/v1/stream")
+		if deps.Auth != nil {
+			stream.Use(WebSocketAuthMiddleware(deps.Auth))
+		}
+		if deps.RateLimiter != nil {
+			stream.Use(RateLimitMiddleware(deps.RateLimiter))
+		}
+		stream.Use(scoped(deps.Auth, auth.ScopeMarketRead)...)
+		if deps.Connections != nil {
+			stream.Use(WebSocketConnectionLimit(deps.Connections))
+		}
+		stream.GET("/klines", handlers.NewStreamHandler(deps.Streams).StreamKlines)
+	}
+}
+
+// scoped returns the middleware requiring scope, or none when authentication
+// is disabled
+func scoped(authn *auth.Authenticator, scope auth.Scope) []gin.HandlerFunc {
+	if authn == nil {
+		return nil
 	}
+	return []gin.HandlerFunc{RequireScope(scope)}
 }
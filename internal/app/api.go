@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"crypto-monitor/internal/api"
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/bus"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/grpcapi"
+	"crypto-monitor/internal/importer"
+	"crypto-monitor/internal/ratelimit"
+	"crypto-monitor/internal/repository"
+	"crypto-monitor/internal/service"
+	"crypto-monitor/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+)
+
+// APIServer serves the REST API, the websocket feed and the gRPC API,
+// relaying live klines received from the collector over the bus
+type APIServer struct {
+	cfg    *config.Config
+	server *http.Server
+	// grpc serves the gRPC API, nil when it is disabled
+	grpc   *grpc.Server
+	remote *service.RemoteCollector
+	wsSvc  *service.WebSocketService
+	// imports runs the import jobs started through the API
+	imports *importer.JobManager
+	// stopWS ends the websocket service and wsDone is closed once it has
+	stopWS context.CancelFunc
+	wsDone chan struct{}
+}
+
+// NewAPIServer builds the HTTP and gRPC servers
+// collector is the in-process collector, or nil when it runs elsewhere; the
+// admin endpoints are only served with it
+func NewAPIServer(cfg *config.Config, db *gorm.DB, b bus.Bus, collector *Collector, flags *config.Flags) (*APIServer, error) {
+	remote, err := service.NewRemoteCollector(b, cfg.Bus.StatusInterval.Std())
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to collector events: %w", err)
+	}
+
+	klineRepo := repository.NewKlineRepository(db)
+	wsSvc := service.NewWebSocketService(remote, klineRepo, cfg.WebSocket)
+	klineImporter := importer.NewImporter(klineRepo, repository.NewKlineImportRepository(db))
+
+	imports := importer.NewJobManager(klineImporter)
+
+	deps := api.Dependencies{
+		KlineRepo:       klineRepo,
+		ImportJobs:      imports,
+		ImportDir:       cfg.Import.Dir,
+		CollectorStatus: remote,
+		CheckMigrations: func() error { return database.CheckMigrations(db) },
+		MaxKlines:       cfg.RateLimit.MaxKlines,
+		Streams:         wsSvc,
+		Connections:     ratelimit.NewCounter(cfg.WebSocket.MaxConnectionsPerClient),
+	}
+	if cfg.RateLimit.Enabled {
+		deps.RateLimiter = ratelimit.NewLimiter(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+	}
+	if cfg.Auth.Enabled {
+		keyRepo := repository.NewAPIKeyRepository(db)
+		deps.Auth = auth.NewAuthenticator(keyRepo, cfg.Auth.CacheTTL.Std())
+		deps.APIKeys = keyRepo
+	} else {
+		appLog.Warn("API authentication is disabled, every endpoint is open; set auth.enabled (AUTH_ENABLED) before exposing the server")
+	}
+	if collector != nil {
+		deps.CollectorStatus = collector
+		deps.Tracking = collector
+		// reloadTracking re-reads the tracking configuration from file, env and flags
+		deps.ReloadTracking = func() (config.TrackingConfig, error) {
+			reloaded, err := flags.Load()
+			if err != nil {
+				return config.TrackingConfig{}, err
+			}
+			return reloaded.Tracking, nil
+		}
+	} else {
+		// Without a local collector, export the series health seen on the bus
+		registerSeriesMetrics(remote)
+	}
+
+	// Initialize Gin router; requests are logged by the API middleware only
+	r := newRouter(cfg.Server.TrustedProxies)
+
+	// Setup API routes; imports read archives from cfg.Import.Dir only
+	api.SetupRoutes(r, deps)
+
+	// Setup WebSocket route; browsers may only connect from the allowed origins
+	// Clients offering permessage-deflate get large frames compressed
+	upgrader := websocket.Upgrader{
+		CheckOrigin:       api.OriginChecker(cfg.WebSocket.AllowedOrigins),
+		Subprotocols:      service.Subprotocols,
+		EnableCompression: cfg.WebSocket.Compression,
+	}
+
+	// Connections are counted per key once authenticated, otherwise per address
+	var wsChain []gin.HandlerFunc
+	if deps.Auth != nil {
+		wsChain = []gin.HandlerFunc{api.WebSocketAuthMiddleware(deps.Auth), api.RequireScope(auth.ScopeMarketRead)}
+	}
+	wsChain = append(wsChain, api.WebSocketConnectionLimit(deps.Connections))
+	r.GET("/ws", append(wsChain, func(c *gin.Context) {
+		// The encoding is negotiated as a subprotocol, or chosen with the
+		// encoding query parameter by clients that cannot set one
+		encoding, err := service.ParseEncoding(c.Query("encoding"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		conn, err := upgrader.Upgrade(service.MeterUpgrade(c.Writer, c.Request, upgrader.EnableCompression), c.Request, nil)
+		if err != nil {
+			appLog.WarnContext(c.Request.Context(), "WebSocket upgrade failed", "error", err)
+			return
+		}
+		if negotiated, ok := service.SubprotocolEncoding(conn.Subprotocol()); ok {
+			encoding = negotiated
+		}
+		wsSvc.HandleConnection(conn, encoding)
+	})...)
+
+	// The gRPC API is served from the same repository and fan-out, with its
+	// streams counted with the websocket connections
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpcapi.NewServer(grpcapi.Dependencies{
+			Klines:      klineRepo,
+			Streams:     wsSvc,
+			Series:      deps.CollectorStatus,
+			Auth:        deps.Auth,
+			RateLimiter: deps.RateLimiter,
+			Connections: deps.Connections,
+			MaxKlines:   cfg.RateLimit.MaxKlines,
+			Reflection:  cfg.GRPC.Reflection,
+		})
+	}
+
+	return &APIServer{
+		cfg:  cfg,
+		grpc: grpcServer,
+		server: &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+			Handler:           r,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Std(),
+			ReadTimeout:       cfg.Server.ReadTimeout.Std(),
+			WriteTimeout:      cfg.Server.WriteTimeout.Std(),
+			IdleTimeout:       cfg.Server.IdleTimeout.Std(),
+		},
+		remote:  remote,
+		wsSvc:   wsSvc,
+		imports: imports,
+	}, nil
+}
+
+// Start launches the websocket service and the HTTP and gRPC listeners
+func (a *APIServer) Start() {
+	a.remote.Start()
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopWS, a.wsDone = cancel, make(chan struct{})
+	// Event streams are requests in flight until the websocket service ends
+	// them, so it stops as soon as the server shuts down
+	a.server.RegisterOnShutdown(cancel)
+	go func() {
+		defer close(a.wsDone)
+		a.wsSvc.Run(ctx)
+	}()
+	appLog.Info("WebSocket service started")
+
+	go func() {
+		appLog.Info("Server starting", "port", a.cfg.Server.Port)
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			Fatal("Failed to start server", err)
+		}
+	}()
+
+	if a.grpc != nil {
+		go func() {
+			appLog.Info("gRPC server starting", "port", a.cfg.GRPC.Port)
+			lis, err := net.Listen("tcp", fmt.Sprintf(":%d", a.cfg.GRPC.Port))
+			if err != nil {
+				Fatal("Failed to listen for gRPC", err)
+			}
+			if err := a.grpc.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+				Fatal("Failed to start gRPC server", err)
+			}
+		}()
+	}
+}
+
+// StopImports cancels the import jobs and waits for them to record their
+// outcome, so none is left writing once the database is closed
+func (a *APIServer) StopImports(ctx context.Context) error {
+	return a.imports.Stop(ctx)
+}
+
+// Shutdown stops accepting requests and calls and waits for in-flight ones,
+// closes the websocket clients and stops relaying klines from the bus
+// It returns ctx.Err() if ctx is done first.
+func (a *APIServer) Shutdown(ctx context.Context) error {
+	// Hijacked websocket connections are not tracked by the HTTP server, so
+	// they are closed by the websocket service
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		appLog.Warn("Server forced to shutdown", "error", err)
+	}
+
+	// Shutting the HTTP server down stopped the websocket service, which ends
+	// the gRPC streams
+	if a.grpc != nil {
+		stopped := make(chan struct{})
+		go func() {
+			a.grpc.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			err = ctx.Err()
+			appLog.Warn("gRPC server forced to shutdown", "error", err)
+			a.grpc.Stop()
+		}
+	}
+
+	a.stopWS()
+	select {
+	case <-a.wsDone:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	a.remote.Stop()
+	return err
+}
@@ -0,0 +1,208 @@
+// Package app wires the collector and API server processes shared by the
+// combined server and the standalone collector and API binaries.
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"crypto-monitor/internal/api"
+	"crypto-monitor/internal/api/handlers"
+	"crypto-monitor/internal/bus"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/repository"
+	"crypto-monitor/internal/service"
+	"crypto-monitor/internal/tracing"
+	"crypto-monitor/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var appLog = logging.For(logging.App)
+
+// LoadConfig parses the command-line flags of the named binary, loads the
+// configuration and sets up logging; with -print-config it prints the
+// effective configuration and exits
+func LoadConfig(name string, args []string) (*config.Config, *config.Flags, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	fs.Parse(args)
+
+	cfg, err := flags.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if *printConfig {
+		fmt.Print(cfg.String())
+		os.Exit(0)
+	}
+	if err := logging.Setup(cfg.Log, os.Stderr); err != nil {
+		return nil, nil, err
+	}
+	return cfg, flags, nil
+}
+
+// SetupTracing starts exporting spans of the named binary when tracing is
+// enabled; the returned function flushes pending spans on shutdown
+func SetupTracing(cfg *config.Config, name string) (func(), error) {
+	shutdown, err := tracing.Setup(context.Background(), cfg.Tracing, "crypto-monitor-"+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	if cfg.Tracing.Enabled {
+		appLog.Info("Exporting traces over OTLP", "endpoint", cfg.Tracing.Endpoint, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			appLog.Warn("Failed to flush traces", "error", err)
+		}
+	}, nil
+}
+
+// OpenDatabase connects to the database and checks the connection
+func OpenDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	appLog.Info("Database connection test successful")
+	return db, nil
+}
+
+// OpenBus creates the message bus selected by cfg.Bus.Driver
+func OpenBus(cfg *config.Config, db *gorm.DB) (bus.Bus, error) {
+	switch cfg.Bus.Driver {
+	case config.BusMemory:
+		return bus.NewMemory(), nil
+	case config.BusPostgres:
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database instance: %w", err)
+		}
+		appLog.Info("Using PostgreSQL LISTEN/NOTIFY message bus")
+		return bus.NewPostgres(sqlDB, cfg.Database.DSN()), nil
+	default:
+		return nil, fmt.Errorf("unknown bus driver %q", cfg.Bus.Driver)
+	}
+}
+
+// RequireSharedBus rejects the in-process bus for binaries that run only one
+// side of the pipeline, since nothing would reach the other process
+func RequireSharedBus(cfg *config.Config, name string) error {
+	if cfg.Bus.Driver == config.BusMemory {
+		return fmt.Errorf("%s needs a bus shared with the other processes, set bus.driver (BUS_DRIVER) to %q", name, config.BusPostgres)
+	}
+	return nil
+}
+
+// Fatal logs err and exits the process
+func Fatal(msg string, err error) {
+	appLog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// newRouter creates a gin engine without gin's own logger and recovery,
+// which the API middleware replaces
+func newRouter(trustedProxies []string) *gin.Engine {
+	// Debug mode prints unstructured route listings; GIN_MODE still wins
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	r := gin.New()
+	// Gin trusts X-Forwarded-For from anyone by default, which would let
+	// clients pick the address they are rate limited by
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		appLog.Warn("Invalid trusted proxies, trusting none", "error", err)
+		r.SetTrustedProxies(nil)
+	}
+	return r
+}
+
+// registerSeriesMetrics exports the per-series health of source
+func registerSeriesMetrics(source service.HealthSource) {
+	if err := metrics.Registry.Register(service.NewSeriesMetrics(source)); err != nil {
+		appLog.Warn("Failed to register series metrics", "error", err)
+	}
+}
+
+// OpsServer serves /metrics, /healthz and /readyz for processes without the API server
+type OpsServer struct {
+	server *http.Server
+}
+
+// StartOpsServer starts serving the operational endpoints of a collector on
+// the server port
+func StartOpsServer(cfg *config.Config, db *gorm.DB, collector *Collector) *OpsServer {
+	r := newRouter(nil)
+	r.Use(api.RequestIDMiddleware(), api.LoggerMiddleware(), api.ErrorHandlerMiddleware())
+	api.SetupOpsRoutes(r, handlers.NewHealthHandler(handlers.HealthChecks{
+		Database:   repository.NewKlineRepository(db),
+		Migrations: func() error { return database.CheckMigrations(db) },
+		Series:     collector,
+	}, nil))
+
+	o := &OpsServer{
+		server: &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+			Handler:           r,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Std(),
+			ReadTimeout:       cfg.Server.ReadTimeout.Std(),
+			IdleTimeout:       cfg.Server.IdleTimeout.Std(),
+		},
+	}
+	go func() {
+		appLog.Info("Ops server starting", "port", cfg.Server.Port)
+		if err := o.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			Fatal("Failed to start ops server", err)
+		}
+	}()
+	return o
+}
+
+// Shutdown stops the ops server, waiting for in-flight requests until ctx is done
+func (o *OpsServer) Shutdown(ctx context.Context) error {
+	return o.server.Shutdown(ctx)
+}
+
+// WaitForSignals calls reload on every SIGHUP, when reload is not nil, and
+// returns once SIGINT or SIGTERM is received
+func WaitForSignals(reload func()) {
+	hup := make(chan os.Signal, 1)
+	if reload != nil {
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	for {
+		select {
+		case <-hup:
+			reload()
+		case <-quit:
+			return
+		}
+	}
+}
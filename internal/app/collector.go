@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"crypto-monitor/internal/bus"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/repository"
+	"crypto-monitor/internal/service"
+
+	"gorm.io/gorm"
+)
+
+// Collector runs the always-on exchange streams, stores their klines and
+// publishes them with the health of every series to the bus
+type Collector struct {
+	writer    *service.KlineWriter
+	collector *service.Collector
+	publisher *service.CollectorPublisher
+}
+
+// StartCollector starts streaming the series in cfg.Tracking
+func StartCollector(cfg *config.Config, db *gorm.DB, b bus.Bus) (*Collector, error) {
+	klineRepo := repository.NewKlineRepository(db)
+	binanceSvc := service.NewBinanceService(cfg.Exchange)
+	writer, err := service.NewKlineWriter(klineRepo, cfg.Writer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize kline writer: %w", err)
+	}
+	writer.Start()
+
+	collector := service.NewCollector(binanceSvc, klineRepo, writer)
+	publisher, err := service.NewCollectorPublisher(b, collector, cfg.Bus.StatusInterval.Std())
+	if err != nil {
+		writer.Close(context.Background())
+		return nil, fmt.Errorf("failed to publish collector events: %w", err)
+	}
+	publisher.Start()
+
+	c := &Collector{writer: writer, collector: collector, publisher: publisher}
+	registerSeriesMetrics(collector)
+
+	// Klines are stored whether or not any client is subscribed
+	if _, err := c.Apply(cfg.Tracking); err != nil {
+		c.Stop(context.Background())
+		return nil, fmt.Errorf("failed to start collector: %w", err)
+	}
+	return c, nil
+}
+
+// Tracking returns the tracking configuration in effect
+func (c *Collector) Tracking() config.TrackingConfig {
+	return c.collector.Tracking()
+}
+
+// Series returns the tracked series
+func (c *Collector) Series() []service.Series {
+	return c.collector.Series()
+}
+
+// Health returns the ingestion health of every tracked series
+func (c *Collector) Health() []service.SeriesHealth {
+	return c.collector.Health()
+}
+
+// Apply changes the tracked series and announces them right away, so API
+// servers accept subscriptions without waiting for the next status interval
+func (c *Collector) Apply(tracking config.TrackingConfig) (service.TrackingChange, error) {
+	change, err := c.collector.Apply(tracking)
+	if err != nil {
+		return change, err
+	}
+	c.publisher.PublishStatus()
+	return change, nil
+}
+
+// Reload re-reads the configuration from flags and applies the tracked series
+// and log levels
+func (c *Collector) Reload(flags *config.Flags) {
+	appLog.Info("Received SIGHUP, reloading tracked series and log levels")
+	cfg, err := flags.Load()
+	if err != nil {
+		appLog.Error("Failed to reload configuration", "error", err)
+		return
+	}
+	if err := logging.Setup(cfg.Log, os.Stderr); err != nil {
+		appLog.Error("Failed to apply log configuration", "error", err)
+	}
+	if _, err := c.Apply(cfg.Tracking); err != nil {
+		appLog.Error("Failed to apply tracked series", "error", err)
+	}
+}
+
+// Stop ends the upstream streams, then flushes pending klines to the database
+// or journal; call it before the database closes
+// Klines that cannot be written before ctx is done are kept in the journal.
+func (c *Collector) Stop(ctx context.Context) error {
+	c.publisher.Stop()
+	if err := c.collector.Stop(ctx); err != nil {
+		appLog.Warn("Upstream streams did not close in time", "error", err)
+	}
+	if err := c.writer.Close(ctx); err != nil {
+		return fmt.Errorf("failed to close kline writer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ShutdownStep is one stage of an orderly shutdown
+type ShutdownStep struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
+// Shutdown runs steps in order, each after the previous one has returned,
+// until all are done or ctx is done
+// Steps left when ctx is done are skipped, and a step still running is
+// abandoned; the errors of all steps are returned joined.
+func Shutdown(ctx context.Context, steps ...ShutdownStep) error {
+	var errs []error
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			appLog.Error("Shutdown step skipped", "step", step.Name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.Name, err))
+			continue
+		}
+
+		start := time.Now()
+		done := make(chan error, 1)
+		go func() { done <- step.Stop(ctx) }()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			appLog.Error("Shutdown step failed", "step", step.Name, "duration", time.Since(start), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.Name, err))
+			continue
+		}
+		appLog.Info("Shutdown step completed", "step", step.Name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// CloseStep makes a shutdown step of a close function that cannot be cancelled
+func CloseStep(name string, close func() error) ShutdownStep {
+	return ShutdownStep{Name: name, Stop: func(context.Context) error { return close() }}
+}
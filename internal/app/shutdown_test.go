@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestShutdown_Order tests that steps run in order and their errors are joined
+func TestShutdown_Order(t *testing.T) {
+	var order []string
+	step := func(name string, err error) ShutdownStep {
+		return ShutdownStep{Name: name, Stop: func(context.Context) error {
+			order = append(order, name)
+			return err
+		}}
+	}
+
+	failure := errors.New("close failed")
+	err := Shutdown(context.Background(),
+		step("server", nil),
+		step("collector", failure),
+		CloseStep("database", func() error {
+			order = append(order, "database")
+			return nil
+		}),
+	)
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the collector error, got %v", err)
+	}
+	if len(order) != 3 || order[0] != "server" || order[1] != "collector" || order[2] != "database" {
+		t.Errorf("Expected steps in order, got %v", order)
+	}
+}
+
+// TestShutdown_Timeout tests that a hung step does not hold up shutdown past
+// the deadline and that the remaining steps are skipped
+func TestShutdown_Timeout(t *testing.T) {
+	hung := make(chan struct{})
+	defer close(hung)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	skipped := true
+	start := time.Now()
+	err := Shutdown(ctx,
+		ShutdownStep{Name: "collector", Stop: func(context.Context) error {
+			<-hung
+			return nil
+		}},
+		CloseStep("database", func() error {
+			skipped = false
+			return nil
+		}),
+	)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected shutdown to return at the deadline, took %v", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+	if !skipped {
+		t.Error("Expected steps after the deadline to be skipped")
+	}
+}
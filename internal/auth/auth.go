@@ -0,0 +1,214 @@
+// Package auth authenticates API clients by API key and checks the scopes
+// their keys grant.
+//
+// Keys are random tokens shown to the client once when they are issued; only
+// their SHA-256 hash is stored. Since keys carry 256 bits of entropy a fast
+// hash is enough to make a leaked table useless.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/models"
+)
+
+// Scope is a permission granted to an API key
+type Scope string
+
+// Supported scopes
+const (
+	// ScopeMarketRead reads klines, symbols and collector status, and
+	// subscribes to the websocket feed
+	ScopeMarketRead Scope = "market:read"
+	// ScopeAlertsManage manages price alerts
+	ScopeAlertsManage Scope = "alerts:manage"
+	// ScopeAdmin manages API keys, imports and tracked series, and implies
+	// every other scope
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes lists every supported scope
+var Scopes = []Scope{ScopeMarketRead, ScopeAlertsManage, ScopeAdmin}
+
+// keyPrefix starts every issued key so that leaked keys are easy to recognize
+const keyPrefix = "cm_"
+
+// displayPrefixLength is how much of a key is stored in the clear to tell keys apart
+const displayPrefixLength = 10
+
+// ErrInvalidKey is returned for keys that are unknown or revoked
+var ErrInvalidKey = errors.New("invalid API key")
+
+var authLog = logging.For(logging.HTTP)
+
+// ParseScopes checks that every name is a supported scope
+func ParseScopes(names []string) ([]Scope, error) {
+	if len(names) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	scopes := make([]Scope, 0, len(names))
+	for _, name := range names {
+		scope := Scope(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", name)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// HashKey returns the hex SHA-256 of key, as stored in the database
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewKey issues a key with the given name and scopes
+// It returns the key, which must be handed to the client since it cannot be
+// recovered, and the record to store.
+func NewKey(name string, scopes []Scope) (string, *models.APIKey, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return key, &models.APIKey{
+		Name:    name,
+		Prefix:  key[:displayPrefixLength],
+		KeyHash: HashKey(key),
+		Scopes:  names,
+	}, nil
+}
+
+// Principal is the API key a request was authenticated with
+type Principal struct {
+	KeyID  uint64
+	Name   string
+	Scopes []Scope
+}
+
+// Allows reports whether the key grants scope; admin grants every scope
+func (p *Principal) Allows(scope Scope) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored by WithPrincipal, or nil
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// KeyStore looks up stored API keys
+type KeyStore interface {
+	// FindAPIKeyByHash returns the active key with the hash, or nil
+	FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uint64, at time.Time) error
+}
+
+// cachedPrincipal is a verified key and when it must be looked up again
+type cachedPrincipal struct {
+	principal *Principal
+	expires   time.Time
+}
+
+// Authenticator verifies API keys against the store
+// Verified keys are cached for the configured TTL, so the store is queried,
+// and the last use of a key recorded, at most once per TTL per key.
+type Authenticator struct {
+	store KeyStore
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedPrincipal // key hash -> principal
+}
+
+// NewAuthenticator creates an Authenticator caching verified keys for ttl;
+// a ttl of 0 looks up every request
+func NewAuthenticator(store KeyStore, ttl time.Duration) *Authenticator {
+	return &Authenticator{
+		store: store,
+		ttl:   ttl,
+		cache: make(map[string]cachedPrincipal),
+	}
+}
+
+// Authenticate returns the principal of key
+// Unknown and revoked keys return ErrInvalidKey; other errors mean the store
+// could not be queried.
+func (a *Authenticator) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	hash := HashKey(key)
+	now := time.Now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[hash]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.principal, nil
+	}
+
+	record, err := a.store.FindAPIKeyByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		a.mu.Lock()
+		delete(a.cache, hash)
+		a.mu.Unlock()
+		return nil, ErrInvalidKey
+	}
+
+	principal := &Principal{KeyID: record.ID, Name: record.Name}
+	for _, scope := range record.Scopes {
+		principal.Scopes = append(principal.Scopes, Scope(scope))
+	}
+	if err := a.store.TouchAPIKey(ctx, record.ID, now); err != nil {
+		authLog.WarnContext(ctx, "Failed to record API key use", "key_id", record.ID, "error", err)
+	}
+
+	if a.ttl > 0 {
+		a.mu.Lock()
+		a.cache[hash] = cachedPrincipal{principal: principal, expires: now.Add(a.ttl)}
+		a.mu.Unlock()
+	}
+	return principal, nil
+}
+
+// Forget drops a key from the cache so that its revocation takes effect at
+// once on this server
+func (a *Authenticator) Forget(keyID uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for hash, cached := range a.cache {
+		if cached.principal.KeyID == keyID {
+			delete(a.cache, hash)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNewKey tests that issued keys are random and stored only as a hash
+func TestNewKey(t *testing.T) {
+	key, record, err := NewKey("dashboard", []Scope{ScopeMarketRead})
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	other, _, _ := NewKey("dashboard", []Scope{ScopeMarketRead})
+
+	if !strings.HasPrefix(key, keyPrefix) || key == other {
+		t.Errorf("Expected distinct %s keys, got %q and %q", keyPrefix, key, other)
+	}
+	if record.KeyHash != HashKey(key) || strings.Contains(record.KeyHash, key) {
+		t.Errorf("Expected only the hash of the key to be stored, got %+v", record)
+	}
+	if !strings.HasPrefix(key, record.Prefix) || len(record.Prefix) != displayPrefixLength {
+		t.Errorf("Expected the display prefix of the key, got %q", record.Prefix)
+	}
+	if len(record.Scopes) != 1 || record.Scopes[0] != "market:read" {
+		t.Errorf("Unexpected scopes: %v", record.Scopes)
+	}
+}
+
+// TestParseScopes tests validating scope names
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"Market:Read", "admin", "market:read"})
+	if err != nil {
+		t.Fatalf("Failed to parse scopes: %v", err)
+	}
+	if len(scopes) != 2 || scopes[0] != ScopeMarketRead || scopes[1] != ScopeAdmin {
+		t.Errorf("Expected deduplicated scopes, got %v", scopes)
+	}
+
+	if _, err := ParseScopes([]string{"market:write"}); err == nil {
+		t.Error("Expected an error for an unknown scope")
+	}
+	if _, err := ParseScopes(nil); err == nil {
+		t.Error("Expected an error for no scopes")
+	}
+}
+
+// TestPrincipal_Allows tests that admin implies every other scope
+func TestPrincipal_Allows(t *testing.T) {
+	reader := &Principal{Scopes: []Scope{ScopeMarketRead}}
+	if !reader.Allows(ScopeMarketRead) || reader.Allows(ScopeAdmin) || reader.Allows(ScopeAlertsManage) {
+		t.Errorf("Unexpected permissions for %v", reader.Scopes)
+	}
+	admin := &Principal{Scopes: []Scope{ScopeAdmin}}
+	for _, scope := range Scopes {
+		if !admin.Allows(scope) {
+			t.Errorf("Expected admin to allow %s", scope)
+		}
+	}
+}
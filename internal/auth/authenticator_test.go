@@ -0,0 +1,59 @@
+package auth_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/auth/authtest"
+)
+
+// TestAuthenticator tests verifying keys, caching them and forgetting revoked ones
+func TestAuthenticator(t *testing.T) {
+	store := &authtest.KeyStore{}
+	key := store.Issue(t, auth.ScopeMarketRead)
+	authn := auth.NewAuthenticator(store, time.Minute)
+
+	principal, err := authn.Authenticate(t.Context(), key)
+	if err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if principal.KeyID != 1 || !principal.Allows(auth.ScopeMarketRead) {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+	if !store.Touched(principal.KeyID) {
+		t.Error("Expected the use of the key to be recorded")
+	}
+
+	if _, err := authn.Authenticate(t.Context(), key); err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if lookups := store.Lookups(); lookups != 1 {
+		t.Errorf("Expected the second request to be served from the cache, got %d lookups", lookups)
+	}
+
+	for _, bad := range []string{"", "cm_unknown", key + "x"} {
+		if _, err := authn.Authenticate(t.Context(), bad); !errors.Is(err, auth.ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey for %q, got %v", bad, err)
+		}
+	}
+
+	store.RevokeAPIKey(t.Context(), principal.KeyID)
+	authn.Forget(principal.KeyID)
+	if _, err := authn.Authenticate(t.Context(), key); !errors.Is(err, auth.ErrInvalidKey) {
+		t.Errorf("Expected a revoked key to be rejected once forgotten, got %v", err)
+	}
+}
+
+// TestAuthenticator_StoreError tests that store failures are not reported as invalid keys
+func TestAuthenticator_StoreError(t *testing.T) {
+	store := &authtest.KeyStore{}
+	key := store.Issue(t, auth.ScopeMarketRead)
+	store.Fail(errors.New("connection refused"))
+
+	_, err := auth.NewAuthenticator(store, 0).Authenticate(t.Context(), key)
+	if err == nil || errors.Is(err, auth.ErrInvalidKey) {
+		t.Errorf("Expected the store error, got %v", err)
+	}
+}
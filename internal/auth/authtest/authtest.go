@@ -0,0 +1,113 @@
+// Package authtest provides an in-memory key store for the tests of the
+// packages authenticating API keys
+package authtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/models"
+)
+
+// KeyStore keeps API keys in memory for both authentication and management,
+// and counts lookups
+type KeyStore struct {
+	mu      sync.Mutex
+	keys    []*models.APIKey
+	lookups int
+	touched map[uint64]time.Time
+	err     error
+}
+
+func (s *KeyStore) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key.ID = uint64(len(s.keys) + 1)
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *KeyStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]models.APIKey, len(s.keys))
+	for i, key := range s.keys {
+		keys[i] = *key
+	}
+	return keys, nil
+}
+
+func (s *KeyStore) RevokeAPIKey(ctx context.Context, id uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.keys {
+		if key.ID == id && key.RevokedAt == nil {
+			now := time.Now()
+			key.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *KeyStore) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lookups++
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, key := range s.keys {
+		if key.KeyHash == hash && key.RevokedAt == nil {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *KeyStore) TouchAPIKey(ctx context.Context, id uint64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.touched == nil {
+		s.touched = make(map[uint64]time.Time)
+	}
+	s.touched[id] = at
+	return nil
+}
+
+// Issue stores a new key with the given scopes and returns it
+func (s *KeyStore) Issue(t *testing.T, scopes ...auth.Scope) string {
+	t.Helper()
+	key, record, err := auth.NewKey("test", scopes)
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	s.CreateAPIKey(t.Context(), record)
+	return key
+}
+
+// Lookups returns the number of keys looked up by hash
+func (s *KeyStore) Lookups() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookups
+}
+
+// Touched reports whether the use of a key was recorded
+func (s *KeyStore) Touched(id uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.touched[id]
+	return ok
+}
+
+// Fail makes lookups fail with err; nil makes them succeed again
+func (s *KeyStore) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
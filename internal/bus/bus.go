@@ -0,0 +1,27 @@
+// Package bus provides the publish/subscribe transport that carries live events
+// from the collector to API servers.
+package bus
+
+import (
+	"context"
+	"errors"
+)
+
+// Handler processes one message published on a topic
+// Handlers must not modify or retain payload
+type Handler func(payload []byte)
+
+// Bus delivers published messages to every subscriber of the topic
+// Delivery is best effort: messages published while a subscriber is
+// disconnected are not replayed.
+type Bus interface {
+	// Publish sends payload to all current subscribers of topic
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe registers handler for messages on topic
+	Subscribe(topic string, handler Handler) error
+	// Close stops delivery and releases resources
+	Close() error
+}
+
+// ErrClosed is returned when publishing or subscribing on a closed bus
+var ErrClosed = errors.New("bus is closed")
@@ -0,0 +1,122 @@
+package bus
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/pkg/database"
+)
+
+// collect records payloads received by a handler
+type collect struct {
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (c *collect) handle(payload []byte) {
+	c.mu.Lock()
+	c.payloads = append(c.payloads, string(payload))
+	c.mu.Unlock()
+}
+
+func (c *collect) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.payloads...)
+}
+
+// waitFor polls cond until it holds or timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// TestMemory_PublishSubscribe tests delivery to every subscriber of a topic only
+func TestMemory_PublishSubscribe(t *testing.T) {
+	b := NewMemory()
+	var first, second, other collect
+	b.Subscribe("klines", first.handle)
+	b.Subscribe("klines", second.handle)
+	b.Subscribe("status", other.handle)
+
+	if err := b.Publish(context.Background(), "klines", []byte("a")); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	if got := first.received(); len(got) != 1 || got[0] != "a" {
+		t.Errorf("Expected first subscriber to receive the message, got %v", got)
+	}
+	if got := second.received(); len(got) != 1 {
+		t.Errorf("Expected second subscriber to receive the message, got %v", got)
+	}
+	if got := other.received(); len(got) != 0 {
+		t.Errorf("Expected subscriber of another topic to receive nothing, got %v", got)
+	}
+}
+
+// TestMemory_Closed tests that a closed bus rejects publishing and subscribing
+func TestMemory_Closed(t *testing.T) {
+	b := NewMemory()
+	b.Close()
+
+	if err := b.Publish(context.Background(), "klines", []byte("a")); err != ErrClosed {
+		t.Errorf("Expected ErrClosed from Publish, got %v", err)
+	}
+	if err := b.Subscribe("klines", func([]byte) {}); err != ErrClosed {
+		t.Errorf("Expected ErrClosed from Subscribe, got %v", err)
+	}
+}
+
+// TestPostgres_PublishSubscribe tests delivery through LISTEN/NOTIFY
+func TestPostgres_PublishSubscribe(t *testing.T) {
+	os.Setenv("DB_HOST", "127.0.0.1")
+	cfg, err := config.LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		t.Skipf("Skipping test: database connection failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database instance: %v", err)
+	}
+
+	b := NewPostgres(sqlDB, cfg.Database.DSN())
+	defer b.Close()
+
+	var received collect
+	topic := "test_" + time.Now().Format("150405.000000")
+	if err := b.Subscribe(topic, received.handle); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	// The listener LISTENs asynchronously, so publish until the first message arrives
+	ok := waitFor(t, 5*time.Second, func() bool {
+		b.Publish(context.Background(), topic, []byte(`{"n":1}`))
+		time.Sleep(50 * time.Millisecond)
+		return len(received.received()) > 0
+	})
+	if !ok {
+		t.Fatal("Expected a notification to be delivered")
+	}
+	if got := received.received(); got[0] != `{"n":1}` {
+		t.Errorf("Unexpected payload %q", got[0])
+	}
+
+	if err := b.Publish(context.Background(), topic, make([]byte, maxPayloadSize)); err == nil {
+		t.Error("Expected error for payload over the NOTIFY limit")
+	}
+}
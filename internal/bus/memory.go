@@ -0,0 +1,55 @@
+package bus
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is an in-process bus for running the collector and API in one process
+// Publish calls the handlers synchronously in subscription order.
+type Memory struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	closed   bool
+}
+
+// NewMemory creates a new in-process bus
+func NewMemory() *Memory {
+	return &Memory{handlers: make(map[string][]Handler)}
+}
+
+// Publish delivers payload to the handlers of topic before returning
+func (m *Memory) Publish(ctx context.Context, topic string, payload []byte) error {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+	handlers := m.handlers[topic]
+	m.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(payload)
+	}
+	return nil
+}
+
+// Subscribe registers handler for messages on topic
+func (m *Memory) Subscribe(topic string, handler Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.handlers[topic] = append(m.handlers[topic], handler)
+	return nil
+}
+
+// Close stops delivery to all handlers
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	m.handlers = nil
+	m.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,220 @@
+package bus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// channelPrefix namespaces NOTIFY channels of this application
+	channelPrefix = "crypto_monitor_"
+
+	// maxPayloadSize is the PostgreSQL limit for a NOTIFY payload
+	maxPayloadSize = 8000
+
+	// Delays between reconnect attempts of the listening connection
+	minListenRetryDelay = time.Second
+	maxListenRetryDelay = 30 * time.Second
+)
+
+var busLog = logging.For(logging.Bus)
+
+// Postgres is a bus built on PostgreSQL LISTEN/NOTIFY, shared by every process
+// connected to the same database
+// Messages are published through the application's connection pool and received
+// on a dedicated connection that is re-established after failures. NOTIFY is not
+// durable, so messages sent while the listener is reconnecting are lost.
+type Postgres struct {
+	db  *sql.DB
+	dsn string
+
+	mu       sync.Mutex
+	handlers map[string][]Handler
+	// resubscribe wakes the listener so it LISTENs on newly subscribed channels
+	resubscribe chan struct{}
+	closed      bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPostgres creates a Postgres bus that publishes through db and listens on
+// its own connection opened from dsn
+func NewPostgres(db *sql.DB, dsn string) *Postgres {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{
+		db:          db,
+		dsn:         dsn,
+		handlers:    make(map[string][]Handler),
+		resubscribe: make(chan struct{}, 1),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go p.listen()
+	return p
+}
+
+// Publish sends payload with pg_notify
+func (p *Postgres) Publish(ctx context.Context, topic string, payload []byte) error {
+	if len(payload) >= maxPayloadSize {
+		return fmt.Errorf("payload of %d bytes exceeds the NOTIFY limit of %d bytes", len(payload), maxPayloadSize)
+	}
+
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	if _, err := p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channelPrefix+topic, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Subscribe registers handler for messages on topic
+// The listener starts receiving the topic shortly after Subscribe returns
+func (p *Postgres) Subscribe(topic string, handler Handler) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	channel := channelPrefix + topic
+	newChannel := len(p.handlers[channel]) == 0
+	p.handlers[channel] = append(p.handlers[channel], handler)
+
+	if newChannel {
+		select {
+		case p.resubscribe <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close stops the listener and waits for it to exit
+func (p *Postgres) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	p.cancel()
+	<-p.done
+	return nil
+}
+
+// listen keeps a LISTEN connection open and dispatches notifications until Close
+func (p *Postgres) listen() {
+	defer close(p.done)
+
+	delay := minListenRetryDelay
+	for {
+		resubscribed, err := p.listenOnce()
+		if p.ctx.Err() != nil {
+			return
+		}
+		if resubscribed {
+			delay = minListenRetryDelay
+			continue
+		}
+
+		busLog.Warn("Bus listener connection lost, reconnecting", "error", err, "delay", delay.String())
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxListenRetryDelay)
+	}
+}
+
+// listenOnce connects, LISTENs on every subscribed channel and dispatches
+// notifications until the connection fails or new channels are subscribed
+// A cancelled wait closes the pgx connection, so picking up new channels
+// means reconnecting; subscriptions are only added at startup.
+func (p *Postgres) listenOnce() (resubscribed bool, err error) {
+	conn, err := pgx.Connect(p.ctx, p.dsn)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	// Drain a pending wake-up, the channels are read right after
+	select {
+	case <-p.resubscribe:
+	default:
+	}
+
+	p.mu.Lock()
+	channels := make([]string, 0, len(p.handlers))
+	for channel := range p.handlers {
+		channels = append(channels, channel)
+	}
+	p.mu.Unlock()
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(p.ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return false, fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+	}
+
+	for {
+		waitCtx, cancel := context.WithCancel(p.ctx)
+		woken := false
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			select {
+			case <-p.resubscribe:
+				woken = true
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+		<-exited
+
+		if err == nil {
+			p.dispatch(notification.Channel, []byte(notification.Payload))
+		}
+		if woken {
+			return true, nil
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) && p.ctx.Err() != nil {
+				return false, p.ctx.Err()
+			}
+			return false, err
+		}
+	}
+}
+
+// dispatch passes a notification to the handlers of its channel
+func (p *Postgres) dispatch(channel string, payload []byte) {
+	p.mu.Lock()
+	handlers := p.handlers[channel]
+	p.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(payload)
+	}
+}
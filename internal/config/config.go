@@ -0,0 +1,631 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/models"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported exchange networks
+const (
+	NetworkTestnet = "testnet"
+	NetworkMainnet = "mainnet"
+)
+
+// redacted replaces secret values when the configuration is printed
+const redacted = "******"
+
+// Config is the complete application configuration
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	GRPC      GRPCConfig      `yaml:"grpc" toml:"grpc"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Exchange  ExchangeConfig  `yaml:"exchange" toml:"exchange"`
+	Tracking  TrackingConfig  `yaml:"tracking" toml:"tracking"`
+	WebSocket WebSocketConfig `yaml:"websocket" toml:"websocket"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Writer    WriterConfig    `yaml:"writer" toml:"writer"`
+	Import    ImportConfig    `yaml:"import" toml:"import"`
+	Bus       BusConfig       `yaml:"bus" toml:"bus"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Port              int      `yaml:"port" toml:"port"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout"`
+	// WriteTimeout is 0 by default because exports stream for as long as they need
+	WriteTimeout    Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For is believed; client addresses identify clients
+	// for rate limiting, so by default no proxy is trusted
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+// GRPCConfig configures the gRPC API served next to the HTTP server
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	Port    int  `yaml:"port" toml:"port"`
+	// Reflection serves the reflection service, which lets tools such as
+	// grpcurl discover the API
+	Reflection bool `yaml:"reflection" toml:"reflection"`
+}
+
+// DatabaseConfig configures the PostgreSQL connection and pool
+type DatabaseConfig struct {
+	Host            string   `yaml:"host" toml:"host"`
+	Port            int      `yaml:"port" toml:"port"`
+	User            string   `yaml:"user" toml:"user"`
+	Password        string   `yaml:"password" toml:"password"`
+	Name            string   `yaml:"name" toml:"name"`
+	SSLMode         string   `yaml:"sslmode" toml:"sslmode"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+}
+
+// DSN returns the PostgreSQL connection string
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+}
+
+// ExchangeConfig configures the Binance endpoints
+// Empty URLs default to the endpoints of the selected network
+type ExchangeConfig struct {
+	Network     string   `yaml:"network" toml:"network"`
+	APIURL      string   `yaml:"api_url" toml:"api_url"`
+	WSURL       string   `yaml:"ws_url" toml:"ws_url"`
+	HTTPTimeout Duration `yaml:"http_timeout" toml:"http_timeout"`
+}
+
+// WithDefaults returns a copy with empty URLs filled in from the network
+func (e ExchangeConfig) WithDefaults() ExchangeConfig {
+	if e.Network == NetworkMainnet {
+		if e.APIURL == "" {
+			e.APIURL = "https://api.binance.com"
+		}
+		if e.WSURL == "" {
+			e.WSURL = "wss://stream.binance.com:9443/ws"
+		}
+	} else {
+		if e.APIURL == "" {
+			e.APIURL = "https://testnet.binance.vision"
+		}
+		if e.WSURL == "" {
+			e.WSURL = "wss://stream.testnet.binance.vision/ws"
+		}
+	}
+	return e
+}
+
+// TrackingConfig lists the symbol/interval series the server follows
+// Every symbol is tracked at every interval
+type TrackingConfig struct {
+	Symbols   []string `yaml:"symbols" toml:"symbols" json:"symbols"`
+	Intervals []string `yaml:"intervals" toml:"intervals" json:"intervals"`
+	// BackfillLimit is the maximum number of candles fetched over REST when a
+	// series starts tracking or its stream reconnects, 0 disables backfill
+	BackfillLimit int `yaml:"backfill_limit" toml:"backfill_limit" json:"-"`
+}
+
+// Normalized returns a copy with symbols upper-cased and whitespace trimmed
+func (t TrackingConfig) Normalized() TrackingConfig {
+	symbols := make([]string, len(t.Symbols))
+	for i, symbol := range t.Symbols {
+		symbols[i] = strings.ToUpper(strings.TrimSpace(symbol))
+	}
+	intervals := make([]string, len(t.Intervals))
+	for i, interval := range t.Intervals {
+		intervals[i] = strings.TrimSpace(interval)
+	}
+	t.Symbols, t.Intervals = symbols, intervals
+	return t
+}
+
+// Validate checks the tracked symbols and intervals
+func (t TrackingConfig) Validate() error {
+	var errs []error
+	if len(t.Symbols) == 0 {
+		errs = append(errs, errors.New("tracking.symbols must not be empty"))
+	}
+	for _, symbol := range t.Symbols {
+		if symbol == "" {
+			errs = append(errs, errors.New("tracking.symbols must not contain empty values"))
+			break
+		}
+	}
+	if len(t.Intervals) == 0 {
+		errs = append(errs, errors.New("tracking.intervals must not be empty"))
+	}
+	for _, interval := range t.Intervals {
+		if _, err := models.IntervalDuration(interval); err != nil {
+			errs = append(errs, fmt.Errorf("tracking.intervals: unsupported interval %q", interval))
+		}
+	}
+	if t.BackfillLimit < 0 {
+		errs = append(errs, errors.New("tracking.backfill_limit must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// WebSocketConfig configures client websocket delivery
+type WebSocketConfig struct {
+	// ThrottleInterval is the default minimum time between updates of one
+	// series to one client; updates arriving sooner are conflated
+	ThrottleInterval Duration `yaml:"throttle_interval" toml:"throttle_interval"`
+	// MinThrottleInterval and MaxThrottleInterval bound the interval clients
+	// may ask for per subscription
+	MinThrottleInterval Duration `yaml:"min_throttle_interval" toml:"min_throttle_interval"`
+	MaxThrottleInterval Duration `yaml:"max_throttle_interval" toml:"max_throttle_interval"`
+	// AllowedOrigins lists the browser origins, such as https://example.com,
+	// that may open websocket connections; "*" allows any origin and an empty
+	// list only the server's own. Clients that send no Origin are not browsers
+	// and are always allowed.
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	// MaxConnectionsPerClient caps the open connections of one API key, or
+	// one address without authentication; 0 is unlimited
+	MaxConnectionsPerClient int `yaml:"max_connections_per_client" toml:"max_connections_per_client"`
+	// MaxSubscriptions caps the series one connection subscribes to; 0 is unlimited
+	MaxSubscriptions int `yaml:"max_subscriptions" toml:"max_subscriptions"`
+	// MessageRate and MessageBurst bound the messages a connection sends, as a
+	// token bucket; a MessageRate of 0 is unlimited
+	MessageRate  float64 `yaml:"message_rate" toml:"message_rate"`
+	MessageBurst int     `yaml:"message_burst" toml:"message_burst"`
+	// MaxSnapshot caps the stored klines a client may ask for when
+	// subscribing; 0 disables snapshots
+	MaxSnapshot int `yaml:"max_snapshot" toml:"max_snapshot"`
+	// ReplayBuffer is how many updates of each series are kept for clients
+	// resuming a session; clients that missed more must take a new snapshot
+	ReplayBuffer int `yaml:"replay_buffer" toml:"replay_buffer"`
+	// SessionTTL is how long the subscriptions of a closed connection can be
+	// resumed; 0 disables resumption
+	SessionTTL Duration `yaml:"session_ttl" toml:"session_ttl"`
+	// SlowQueueDepth and SlowLag are the queued messages, out of 256, and the
+	// time a client's queue has not drained for at which it is warned that it
+	// is a slow consumer; 0 disables either
+	SlowQueueDepth int      `yaml:"slow_queue_depth" toml:"slow_queue_depth"`
+	SlowLag        Duration `yaml:"slow_lag" toml:"slow_lag"`
+	// EvictLag and EvictDrops are the lag and the number of messages dropped
+	// on a full queue since it last drained at which a client is
+	// disconnected; 0 disables either
+	EvictLag   Duration `yaml:"evict_lag" toml:"evict_lag"`
+	EvictDrops int      `yaml:"evict_drops" toml:"evict_drops"`
+	// HubShards is the number of workers fanning updates out to clients, each
+	// serving a share of the series; 0 is one per CPU
+	HubShards int `yaml:"hub_shards" toml:"hub_shards"`
+	// HubQueueSize is how many updates each worker queues before updates
+	// are dropped rather than holding up the collector
+	HubQueueSize int `yaml:"hub_queue_size" toml:"hub_queue_size"`
+	// Compression enables permessage-deflate for clients that offer it
+	Compression bool `yaml:"compression" toml:"compression"`
+	// CompressionLevel is the flate level, from -2 (Huffman only) through 9;
+	// 1 is the fastest
+	CompressionLevel int `yaml:"compression_level" toml:"compression_level"`
+	// CompressionThreshold is the size in bytes below which frames are sent
+	// uncompressed, since deflating them costs more than it saves
+	CompressionThreshold int `yaml:"compression_threshold" toml:"compression_threshold"`
+}
+
+// AuthConfig configures API key authentication
+type AuthConfig struct {
+	// Enabled requires an API key on every API and websocket request; the
+	// metrics and health endpoints stay open
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// CacheTTL is how long a verified key is trusted before it is looked up
+	// again, which bounds how long a revoked key keeps working on other servers
+	CacheTTL Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+}
+
+// RateLimitConfig configures per-client rate limiting of the REST API
+// Clients are identified by API key, or by address without authentication.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// RequestsPerSecond and Burst size the token bucket of each client
+	RequestsPerSecond float64 `yaml:"requests_per_second" toml:"requests_per_second"`
+	Burst             int     `yaml:"burst" toml:"burst"`
+	// MaxKlines caps the limit parameter of a kline query
+	MaxKlines int `yaml:"max_klines" toml:"max_klines"`
+}
+
+// WriterConfig configures the write-behind pipeline for live klines
+type WriterConfig struct {
+	BatchSize     int      `yaml:"batch_size" toml:"batch_size"`
+	FlushInterval Duration `yaml:"flush_interval" toml:"flush_interval"`
+	QueueSize     int      `yaml:"queue_size" toml:"queue_size"`
+	RetryInterval Duration `yaml:"retry_interval" toml:"retry_interval"`
+	JournalPath   string   `yaml:"journal_path" toml:"journal_path"`
+}
+
+// ImportConfig configures archive imports through the API
+type ImportConfig struct {
+	// Dir is the only directory the import endpoint may read from
+	Dir string `yaml:"dir" toml:"dir"`
+}
+
+// Supported message bus drivers
+const (
+	BusMemory   = "memory"
+	BusPostgres = "postgres"
+)
+
+// BusConfig configures the message bus between the collector and API servers
+type BusConfig struct {
+	// Driver is "memory" for a single process or "postgres" for LISTEN/NOTIFY
+	// between separate collector and api processes
+	Driver string `yaml:"driver" toml:"driver"`
+	// StatusInterval is how often the collector announces its tracked series
+	StatusInterval Duration `yaml:"status_interval" toml:"status_interval"`
+}
+
+// Supported log formats
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// LogComponents are the components whose log level can be set separately
+var LogComponents = []string{"app", "binance", "bus", "collector", "database", "grpc", "http", "importer", "repository", "websocket", "writer"}
+
+// LogConfig configures structured logging
+type LogConfig struct {
+	// Level is the minimum level of every component: debug, info, warn or error
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+	// Components overrides Level per component, e.g. {binance: debug}
+	Components map[string]string `yaml:"components" toml:"components"`
+	// SampleEvery logs one in every N per-kline messages of an upstream stream
+	SampleEvery int `yaml:"sample_every" toml:"sample_every"`
+}
+
+// TracingConfig configures OpenTelemetry trace export over OTLP/HTTP
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Endpoint is the host:port of the OTLP/HTTP collector
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// Insecure sends spans over plain HTTP instead of HTTPS
+	Insecure bool `yaml:"insecure" toml:"insecure"`
+	// SampleRatio is the fraction of new traces that are recorded, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+	// ServiceName overrides the service.name resource attribute, which
+	// defaults to the name of the binary
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			ReadTimeout:       Duration(30 * time.Second),
+			IdleTimeout:       Duration(120 * time.Second),
+			ShutdownTimeout:   Duration(5 * time.Second),
+		},
+		GRPC: GRPCConfig{
+			Enabled:    true,
+			Port:       50051,
+			Reflection: true,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Password:        "postgres",
+			Name:            "crypto_monitor",
+			SSLMode:         "disable",
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnMaxIdleTime: Duration(5 * time.Minute),
+		},
+		Exchange: ExchangeConfig{
+			Network:     NetworkTestnet,
+			HTTPTimeout: Duration(30 * time.Second),
+		},
+		Tracking: TrackingConfig{
+			Symbols:       []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"},
+			Intervals:     []string{"1m", "5m", "1h"},
+			BackfillLimit: 1000,
+		},
+		WebSocket: WebSocketConfig{
+			ThrottleInterval:        Duration(time.Second),
+			MinThrottleInterval:     Duration(100 * time.Millisecond),
+			MaxThrottleInterval:     Duration(time.Minute),
+			MaxConnectionsPerClient: 10,
+			MaxSubscriptions:        50,
+			MessageRate:             5,
+			MessageBurst:            20,
+			MaxSnapshot:             1000,
+			ReplayBuffer:            128,
+			SessionTTL:              Duration(2 * time.Minute),
+			SlowQueueDepth:          128,
+			SlowLag:                 Duration(5 * time.Second),
+			EvictLag:                Duration(30 * time.Second),
+			EvictDrops:              10,
+			HubQueueSize:            1024,
+			Compression:             true,
+			CompressionLevel:        1,
+			CompressionThreshold:    512,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           true,
+			RequestsPerSecond: 10,
+			Burst:             20,
+			MaxKlines:         5000,
+		},
+		Auth: AuthConfig{
+			CacheTTL: Duration(30 * time.Second),
+		},
+		Writer: WriterConfig{
+			BatchSize:     500,
+			FlushInterval: Duration(time.Second),
+			QueueSize:     10000,
+			RetryInterval: Duration(5 * time.Second),
+			JournalPath:   filepath.Join("data", "journal", "klines.ndjson"),
+		},
+		Import: ImportConfig{
+			Dir: filepath.Join("data", "imports"),
+		},
+		Bus: BusConfig{
+			Driver:         BusMemory,
+			StatusInterval: Duration(5 * time.Second),
+		},
+		Log: LogConfig{
+			Level:       "info",
+			Format:      LogFormatJSON,
+			SampleEvery: 100,
+		},
+		Tracing: TracingConfig{
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
+	}
+}
+
+// LoadFile builds a configuration from defaults, an optional YAML or TOML file
+// and environment variable overrides, then validates it
+// An empty path falls back to the CONFIG_FILE environment variable
+func LoadFile(path string) (*Config, error) {
+	cfg, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// load applies defaults, file and environment without normalizing or validating
+func load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// readFile decodes a YAML or TOML file over the current values
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Unknown keys are rejected so that typos don't silently fall back to defaults
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(c)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+	default:
+		return fmt.Errorf("unsupported config file type %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// normalize canonicalizes values and resolves network endpoints once all
+// sources have been applied
+func (c *Config) normalize() {
+	c.Exchange.Network = strings.ToLower(strings.TrimSpace(c.Exchange.Network))
+	c.Exchange = c.Exchange.WithDefaults()
+	c.Tracking = c.Tracking.Normalized()
+	for i, origin := range c.WebSocket.AllowedOrigins {
+		c.WebSocket.AllowedOrigins[i] = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+	}
+	c.Bus.Driver = strings.ToLower(strings.TrimSpace(c.Bus.Driver))
+	c.Log.Level = strings.ToLower(strings.TrimSpace(c.Log.Level))
+	c.Log.Format = strings.ToLower(strings.TrimSpace(c.Log.Format))
+	for component, level := range c.Log.Components {
+		c.Log.Components[component] = strings.ToLower(strings.TrimSpace(level))
+	}
+	c.Tracing.Endpoint = strings.TrimSpace(c.Tracing.Endpoint)
+}
+
+// Validate checks the configuration for invalid or inconsistent values
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	if c.GRPC.Enabled {
+		check(c.GRPC.Port > 0 && c.GRPC.Port < 65536, "grpc.port must be between 1 and 65535, got %d", c.GRPC.Port)
+		check(c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+	}
+
+	check(c.Database.Host != "", "database.host is required")
+	check(c.Database.Port > 0 && c.Database.Port < 65536, "database.port must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.Name != "", "database.name is required")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+
+	check(c.Exchange.Network == NetworkTestnet || c.Exchange.Network == NetworkMainnet,
+		"exchange.network must be %q or %q, got %q", NetworkTestnet, NetworkMainnet, c.Exchange.Network)
+	check(c.Exchange.HTTPTimeout > 0, "exchange.http_timeout must be positive")
+
+	if err := c.Tracking.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	check(c.WebSocket.ThrottleInterval >= 0, "websocket.throttle_interval must not be negative")
+	check(c.WebSocket.MinThrottleInterval >= 0, "websocket.min_throttle_interval must not be negative")
+	check(c.WebSocket.MinThrottleInterval <= c.WebSocket.ThrottleInterval && c.WebSocket.ThrottleInterval <= c.WebSocket.MaxThrottleInterval,
+		"websocket.throttle_interval must be between websocket.min_throttle_interval and websocket.max_throttle_interval")
+	for _, origin := range c.WebSocket.AllowedOrigins {
+		check(validOrigin(origin), "websocket.allowed_origins: %q is not \"*\" or a scheme://host[:port] origin", origin)
+	}
+
+	check(c.WebSocket.MaxConnectionsPerClient >= 0, "websocket.max_connections_per_client must not be negative")
+	check(c.WebSocket.MaxSubscriptions >= 0, "websocket.max_subscriptions must not be negative")
+	check(c.WebSocket.MessageRate >= 0, "websocket.message_rate must not be negative")
+	check(c.WebSocket.MessageRate == 0 || c.WebSocket.MessageBurst > 0, "websocket.message_burst must be positive when websocket.message_rate is set")
+	check(c.WebSocket.MaxSnapshot >= 0, "websocket.max_snapshot must not be negative")
+	check(c.WebSocket.ReplayBuffer >= 0, "websocket.replay_buffer must not be negative")
+	check(c.WebSocket.SessionTTL >= 0, "websocket.session_ttl must not be negative")
+	check(c.WebSocket.SlowQueueDepth >= 0 && c.WebSocket.SlowQueueDepth <= 256, "websocket.slow_queue_depth must be between 0 and 256")
+	check(c.WebSocket.SlowLag >= 0, "websocket.slow_lag must not be negative")
+	check(c.WebSocket.EvictLag >= 0, "websocket.evict_lag must not be negative")
+	check(c.WebSocket.EvictLag == 0 || c.WebSocket.SlowLag <= c.WebSocket.EvictLag, "websocket.slow_lag must not exceed websocket.evict_lag")
+	check(c.WebSocket.EvictDrops >= 0, "websocket.evict_drops must not be negative")
+	check(c.WebSocket.HubShards >= 0, "websocket.hub_shards must not be negative")
+	check(c.WebSocket.HubQueueSize > 0, "websocket.hub_queue_size must be positive")
+	check(c.WebSocket.CompressionLevel >= -2 && c.WebSocket.CompressionLevel <= 9, "websocket.compression_level must be between -2 and 9")
+	check(c.WebSocket.CompressionThreshold >= 0, "websocket.compression_threshold must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		check(validProxy(proxy), "server.trusted_proxies: %q is not an IP address or CIDR range", proxy)
+	}
+
+	check(c.Auth.CacheTTL >= 0, "auth.cache_ttl must not be negative")
+
+	check(!c.RateLimit.Enabled || c.RateLimit.RequestsPerSecond > 0, "rate_limit.requests_per_second must be positive")
+	check(!c.RateLimit.Enabled || c.RateLimit.Burst > 0, "rate_limit.burst must be positive")
+	check(c.RateLimit.MaxKlines > 0, "rate_limit.max_klines must be positive")
+
+	check(c.Writer.BatchSize > 0, "writer.batch_size must be positive")
+	check(c.Writer.QueueSize >= c.Writer.BatchSize, "writer.queue_size must be at least writer.batch_size")
+	check(c.Writer.FlushInterval > 0, "writer.flush_interval must be positive")
+	check(c.Writer.RetryInterval > 0, "writer.retry_interval must be positive")
+	check(c.Writer.JournalPath != "", "writer.journal_path is required")
+
+	// An empty directory would not confine the import endpoint at all
+	check(c.Import.Dir != "", "import.dir is required")
+
+	check(c.Bus.Driver == BusMemory || c.Bus.Driver == BusPostgres,
+		"bus.driver must be %q or %q, got %q", BusMemory, BusPostgres, c.Bus.Driver)
+	check(c.Bus.StatusInterval > 0, "bus.status_interval must be positive")
+
+	check(validLogLevel(c.Log.Level), "log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.Format == LogFormatJSON || c.Log.Format == LogFormatText,
+		"log.format must be %q or %q, got %q", LogFormatJSON, LogFormatText, c.Log.Format)
+	for component, level := range c.Log.Components {
+		check(slices.Contains(LogComponents, component), "log.components: unknown component %q", component)
+		check(validLogLevel(level), "log.components.%s must be debug, info, warn or error, got %q", component, level)
+	}
+	check(c.Log.SampleEvery > 0, "log.sample_every must be positive")
+
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	check(!c.Tracing.Enabled || c.Tracing.Endpoint != "", "tracing.endpoint is required when tracing is enabled")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validLogLevel reports whether level names a slog level
+func validLogLevel(level string) bool {
+	switch level {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
+
+// validOrigin reports whether origin is "*" or a bare scheme://host[:port]
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.User == nil
+}
+
+// validProxy reports whether proxy is an IP address or CIDR range
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+// Redacted returns a copy of the configuration with secrets masked
+func (c *Config) Redacted() *Config {
+	copied := *c
+	if copied.Database.Password != "" {
+		copied.Database.Password = redacted
+	}
+	copied.Tracking.Symbols = append([]string(nil), c.Tracking.Symbols...)
+	copied.Tracking.Intervals = append([]string(nil), c.Tracking.Intervals...)
+	copied.WebSocket.AllowedOrigins = append([]string(nil), c.WebSocket.AllowedOrigins...)
+	copied.Server.TrustedProxies = append([]string(nil), c.Server.TrustedProxies...)
+	return &copied
+}
+
+// String renders the effective configuration as YAML with secrets masked
+func (c *Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("failed to render config: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,362 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every bound environment variable for the duration of the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, binding := range append(envBindings, envBinding{name: "CONFIG_FILE"}) {
+		if value, ok := os.LookupEnv(binding.name); ok {
+			os.Unsetenv(binding.name)
+			t.Cleanup(func() { os.Setenv(binding.name, value) })
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// TestLoadFile_Defaults tests that the built-in configuration is valid
+func TestLoadFile_Defaults(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+
+	if cfg.Exchange.Network != NetworkTestnet {
+		t.Errorf("Expected testnet by default, got %s", cfg.Exchange.Network)
+	}
+	if cfg.Exchange.APIURL != "https://testnet.binance.vision" {
+		t.Errorf("Expected testnet API URL, got %s", cfg.Exchange.APIURL)
+	}
+}
+
+// TestLoadFile_YAML tests loading a YAML file over the defaults
+func TestLoadFile_YAML(t *testing.T) {
+	clearEnv(t)
+
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 9090
+  shutdown_timeout: 15s
+database:
+  host: db.internal
+  max_open_conns: 50
+exchange:
+  network: mainnet
+tracking:
+  symbols: [btcusdt, ethusdt]
+  intervals: [1m, 1h]
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load YAML config: %v", err)
+	}
+
+	if cfg.Server.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", cfg.Server.Port)
+	}
+	if cfg.Server.ShutdownTimeout.Std() != 15*time.Second {
+		t.Errorf("Expected shutdown timeout 15s, got %s", cfg.Server.ShutdownTimeout)
+	}
+	if cfg.Database.Host != "db.internal" || cfg.Database.MaxOpenConns != 50 {
+		t.Errorf("Unexpected database config: %+v", cfg.Database)
+	}
+	if cfg.Database.Name != "crypto_monitor" {
+		t.Errorf("Expected unset keys to keep defaults, got database name %q", cfg.Database.Name)
+	}
+	if cfg.Exchange.APIURL != "https://api.binance.com" || cfg.Exchange.WSURL != "wss://stream.binance.com:9443/ws" {
+		t.Errorf("Expected mainnet endpoints, got %s and %s", cfg.Exchange.APIURL, cfg.Exchange.WSURL)
+	}
+	if strings.Join(cfg.Tracking.Symbols, ",") != "BTCUSDT,ETHUSDT" {
+		t.Errorf("Expected upper-cased symbols, got %v", cfg.Tracking.Symbols)
+	}
+}
+
+// TestLoadFile_TOML tests loading a TOML file
+func TestLoadFile_TOML(t *testing.T) {
+	clearEnv(t)
+
+	path := writeFile(t, "config.toml", `
+[server]
+port = 9191
+
+[websocket]
+throttle_interval = "250ms"
+
+[writer]
+batch_size = 100
+journal_path = "/var/lib/crypto-monitor/klines.ndjson"
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load TOML config: %v", err)
+	}
+
+	if cfg.Server.Port != 9191 {
+		t.Errorf("Expected port 9191, got %d", cfg.Server.Port)
+	}
+	if cfg.WebSocket.ThrottleInterval.Std() != 250*time.Millisecond {
+		t.Errorf("Expected throttle interval 250ms, got %s", cfg.WebSocket.ThrottleInterval)
+	}
+	if cfg.Writer.BatchSize != 100 || cfg.Writer.JournalPath != "/var/lib/crypto-monitor/klines.ndjson" {
+		t.Errorf("Unexpected writer config: %+v", cfg.Writer)
+	}
+}
+
+// TestLoadFile_UnknownKey tests that misspelled keys are rejected
+func TestLoadFile_UnknownKey(t *testing.T) {
+	clearEnv(t)
+
+	for name, content := range map[string]string{
+		"config.yaml": "server:\n  prot: 9090\n",
+		"config.toml": "[server]\nprot = 9090\n",
+	} {
+		if _, err := LoadFile(writeFile(t, name, content)); err == nil {
+			t.Errorf("Expected error for unknown key in %s", name)
+		}
+	}
+}
+
+// TestLoadFile_EnvOverridesFile tests that environment variables take precedence over the file
+func TestLoadFile_EnvOverridesFile(t *testing.T) {
+	clearEnv(t)
+
+	path := writeFile(t, "config.yaml", "server:\n  port: 9090\ndatabase:\n  password: from-file\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "7070")
+	t.Setenv("DB_PASSWORD", "from-env")
+	t.Setenv("TRACKED_SYMBOLS", "solusdt, xrpusdt")
+	t.Setenv("WS_THROTTLE_INTERVAL", "2s")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Server.Port != 7070 {
+		t.Errorf("Expected PORT to override the file, got %d", cfg.Server.Port)
+	}
+	if cfg.Database.Password != "from-env" {
+		t.Errorf("Expected DB_PASSWORD to override the file, got %q", cfg.Database.Password)
+	}
+	if strings.Join(cfg.Tracking.Symbols, ",") != "SOLUSDT,XRPUSDT" {
+		t.Errorf("Unexpected symbols from env: %v", cfg.Tracking.Symbols)
+	}
+	if cfg.WebSocket.ThrottleInterval.Std() != 2*time.Second {
+		t.Errorf("Expected throttle interval 2s, got %s", cfg.WebSocket.ThrottleInterval)
+	}
+}
+
+// TestLoadFile_InvalidEnv tests that malformed environment values are reported
+func TestLoadFile_InvalidEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_PORT", "five")
+
+	_, err := LoadFile("")
+	if err == nil || !strings.Contains(err.Error(), "DB_PORT") {
+		t.Fatalf("Expected error naming DB_PORT, got %v", err)
+	}
+}
+
+// TestFlags_Precedence tests that explicitly set flags override env and file values
+func TestFlags_Precedence(t *testing.T) {
+	clearEnv(t)
+
+	path := writeFile(t, "config.yaml", "server:\n  port: 9090\ndatabase:\n  host: file-host\n")
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("DB_USER", "env-user")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-config", path, "-db-host", "flag-host", "-network", "MAINNET", "-intervals", "5m,15m"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	cfg, err := flags.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Database.Host != "flag-host" {
+		t.Errorf("Expected flag to override env, got %s", cfg.Database.Host)
+	}
+	if cfg.Database.User != "env-user" {
+		t.Errorf("Expected unset flag to keep env value, got %s", cfg.Database.User)
+	}
+	if cfg.Server.Port != 9090 {
+		t.Errorf("Expected unset flag to keep file value, got %d", cfg.Server.Port)
+	}
+	if cfg.Exchange.Network != NetworkMainnet || cfg.Exchange.APIURL != "https://api.binance.com" {
+		t.Errorf("Expected mainnet endpoints from -network flag, got %+v", cfg.Exchange)
+	}
+	if strings.Join(cfg.Tracking.Intervals, ",") != "5m,15m" {
+		t.Errorf("Unexpected intervals from flag: %v", cfg.Tracking.Intervals)
+	}
+}
+
+// TestValidate tests that invalid values are all reported together
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Exchange.Network = "devnet"
+	cfg.Tracking.Intervals = []string{"1m", "7m"}
+	cfg.Writer.QueueSize = 10
+	cfg.Log.Components = map[string]string{"binance": "verbose", "gateway": "info"}
+	cfg.Tracing.SampleRatio = 1.5
+	cfg.WebSocket.AllowedOrigins = []string{"https://example.com/app"}
+	cfg.RateLimit.Burst = 0
+	cfg.Server.TrustedProxies = []string{"proxy.internal"}
+	cfg.GRPC.Port = 70000
+	cfg.Import.Dir = ""
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	for _, want := range []string{"server.port", "exchange.network", `"7m"`, "writer.queue_size", "log.components.binance", `unknown component "gateway"`, "tracing.sample_ratio", "websocket.allowed_origins", "rate_limit.burst", "server.trusted_proxies", "grpc.port", "import.dir"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+// TestString_RedactsSecrets tests that the printed configuration masks the password
+func TestString_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "s3cret"
+
+	out := cfg.String()
+	if strings.Contains(out, "s3cret") {
+		t.Errorf("Expected password to be redacted, got:\n%s", out)
+	}
+	if !strings.Contains(out, redacted) {
+		t.Errorf("Expected redaction marker in output, got:\n%s", out)
+	}
+	if !strings.Contains(out, "throttle_interval: 1s") {
+		t.Errorf("Expected durations rendered as strings, got:\n%s", out)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Error("Expected String not to modify the configuration")
+	}
+}
+
+// TestLoadFile_LogComponentsEnv tests parsing per-component log levels from the environment
+func TestLoadFile_LogComponentsEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("LOG_COMPONENTS", "binance=DEBUG, websocket=warn")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Log.Components["binance"] != "debug" || cfg.Log.Components["websocket"] != "warn" {
+		t.Errorf("Unexpected component levels: %v", cfg.Log.Components)
+	}
+
+	t.Setenv("LOG_COMPONENTS", "binance")
+	if _, err := LoadFile(""); err == nil || !strings.Contains(err.Error(), "LOG_COMPONENTS") {
+		t.Errorf("Expected error for malformed LOG_COMPONENTS, got %v", err)
+	}
+}
+
+// TestLoadFile_TracingEnv tests enabling tracing from the environment
+func TestLoadFile_TracingEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TRACING_ENABLED", "true")
+	t.Setenv("TRACING_ENDPOINT", "otel-collector:4318")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.Tracing.Enabled || cfg.Tracing.Endpoint != "otel-collector:4318" || cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("Unexpected tracing config: %+v", cfg.Tracing)
+	}
+
+	t.Setenv("TRACING_ENABLED", "maybe")
+	if _, err := LoadFile(""); err == nil || !strings.Contains(err.Error(), "TRACING_ENABLED") {
+		t.Errorf("Expected error for malformed TRACING_ENABLED, got %v", err)
+	}
+}
+
+// TestLoadFile_AuthEnv tests enabling authentication and listing websocket origins from the environment
+func TestLoadFile_AuthEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("AUTH_ENABLED", "true")
+	t.Setenv("AUTH_CACHE_TTL", "1m")
+	t.Setenv("WS_ALLOWED_ORIGINS", "https://App.example.com/, http://localhost:5173")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.Auth.Enabled || cfg.Auth.CacheTTL.Std() != time.Minute {
+		t.Errorf("Unexpected auth config: %+v", cfg.Auth)
+	}
+	if strings.Join(cfg.WebSocket.AllowedOrigins, ",") != "https://app.example.com,http://localhost:5173" {
+		t.Errorf("Expected normalized origins, got %v", cfg.WebSocket.AllowedOrigins)
+	}
+}
+
+// TestLoadFile_RateLimitEnv tests setting rate limits from the environment
+func TestLoadFile_RateLimitEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("RATE_LIMIT_REQUESTS_PER_SECOND", "2.5")
+	t.Setenv("RATE_LIMIT_BURST", "5")
+	t.Setenv("WS_MAX_SUBSCRIPTIONS", "0")
+	t.Setenv("SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 127.0.0.1")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.RateLimit.Enabled || cfg.RateLimit.RequestsPerSecond != 2.5 || cfg.RateLimit.Burst != 5 {
+		t.Errorf("Unexpected rate limit config: %+v", cfg.RateLimit)
+	}
+	if cfg.WebSocket.MaxSubscriptions != 0 {
+		t.Errorf("Expected subscriptions to be unlimited, got %d", cfg.WebSocket.MaxSubscriptions)
+	}
+	if strings.Join(cfg.Server.TrustedProxies, ",") != "10.0.0.0/8,127.0.0.1" {
+		t.Errorf("Unexpected trusted proxies: %v", cfg.Server.TrustedProxies)
+	}
+}
+
+// TestLoadFile_GRPCEnv tests configuring the gRPC API from the environment
+func TestLoadFile_GRPCEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GRPC_PORT", "9443")
+	t.Setenv("GRPC_REFLECTION", "false")
+
+	cfg, err := LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.GRPC.Enabled || cfg.GRPC.Port != 9443 || cfg.GRPC.Reflection {
+		t.Errorf("Unexpected gRPC config: %+v", cfg.GRPC)
+	}
+
+	t.Setenv("GRPC_PORT", "8080")
+	if _, err := LoadFile(""); err == nil || !strings.Contains(err.Error(), "grpc.port must differ") {
+		t.Errorf("Expected the gRPC port to conflict with the server port, got %v", err)
+	}
+}
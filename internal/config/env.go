@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envBinding maps an environment variable to a configuration value
+type envBinding struct {
+	name string
+	set  func(c *Config, value string) error
+}
+
+// envBindings lists every supported environment override
+var envBindings = []envBinding{
+	{"PORT", intSetter(func(c *Config) *int { return &c.Server.Port })},
+	{"SERVER_READ_TIMEOUT", durationSetter(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+	{"SERVER_WRITE_TIMEOUT", durationSetter(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+	{"SERVER_SHUTDOWN_TIMEOUT", durationSetter(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"SERVER_TRUSTED_PROXIES", listSetter(func(c *Config) *[]string { return &c.Server.TrustedProxies })},
+
+	{"GRPC_ENABLED", boolSetter(func(c *Config) *bool { return &c.GRPC.Enabled })},
+	{"GRPC_PORT", intSetter(func(c *Config) *int { return &c.GRPC.Port })},
+	{"GRPC_REFLECTION", boolSetter(func(c *Config) *bool { return &c.GRPC.Reflection })},
+
+	{"DB_HOST", stringSetter(func(c *Config) *string { return &c.Database.Host })},
+	{"DB_PORT", intSetter(func(c *Config) *int { return &c.Database.Port })},
+	{"DB_USER", stringSetter(func(c *Config) *string { return &c.Database.User })},
+	{"DB_PASSWORD", stringSetter(func(c *Config) *string { return &c.Database.Password })},
+	{"DB_NAME", stringSetter(func(c *Config) *string { return &c.Database.Name })},
+	{"DB_SSLMODE", stringSetter(func(c *Config) *string { return &c.Database.SSLMode })},
+	{"DB_MAX_OPEN_CONNS", intSetter(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"DB_MAX_IDLE_CONNS", intSetter(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"DB_CONN_MAX_LIFETIME", durationSetter(func(c *Config) *Duration { return &c.Database.ConnMaxLifetime })},
+
+	{"BINANCE_NETWORK", stringSetter(func(c *Config) *string { return &c.Exchange.Network })},
+	{"BINANCE_API_URL", stringSetter(func(c *Config) *string { return &c.Exchange.APIURL })},
+	{"BINANCE_WS_URL", stringSetter(func(c *Config) *string { return &c.Exchange.WSURL })},
+
+	{"TRACKED_SYMBOLS", listSetter(func(c *Config) *[]string { return &c.Tracking.Symbols })},
+	{"TRACKED_INTERVALS", listSetter(func(c *Config) *[]string { return &c.Tracking.Intervals })},
+	{"TRACKED_BACKFILL_LIMIT", intSetter(func(c *Config) *int { return &c.Tracking.BackfillLimit })},
+
+	{"WS_THROTTLE_INTERVAL", durationSetter(func(c *Config) *Duration { return &c.WebSocket.ThrottleInterval })},
+	{"WS_MIN_THROTTLE_INTERVAL", durationSetter(func(c *Config) *Duration { return &c.WebSocket.MinThrottleInterval })},
+	{"WS_MAX_THROTTLE_INTERVAL", durationSetter(func(c *Config) *Duration { return &c.WebSocket.MaxThrottleInterval })},
+	{"WS_ALLOWED_ORIGINS", listSetter(func(c *Config) *[]string { return &c.WebSocket.AllowedOrigins })},
+	{"WS_MAX_CONNECTIONS_PER_CLIENT", intSetter(func(c *Config) *int { return &c.WebSocket.MaxConnectionsPerClient })},
+	{"WS_MAX_SUBSCRIPTIONS", intSetter(func(c *Config) *int { return &c.WebSocket.MaxSubscriptions })},
+	{"WS_MESSAGE_RATE", floatSetter(func(c *Config) *float64 { return &c.WebSocket.MessageRate })},
+	{"WS_MESSAGE_BURST", intSetter(func(c *Config) *int { return &c.WebSocket.MessageBurst })},
+	{"WS_MAX_SNAPSHOT", intSetter(func(c *Config) *int { return &c.WebSocket.MaxSnapshot })},
+	{"WS_REPLAY_BUFFER", intSetter(func(c *Config) *int { return &c.WebSocket.ReplayBuffer })},
+	{"WS_SESSION_TTL", durationSetter(func(c *Config) *Duration { return &c.WebSocket.SessionTTL })},
+	{"WS_SLOW_QUEUE_DEPTH", intSetter(func(c *Config) *int { return &c.WebSocket.SlowQueueDepth })},
+	{"WS_SLOW_LAG", durationSetter(func(c *Config) *Duration { return &c.WebSocket.SlowLag })},
+	{"WS_EVICT_LAG", durationSetter(func(c *Config) *Duration { return &c.WebSocket.EvictLag })},
+	{"WS_EVICT_DROPS", intSetter(func(c *Config) *int { return &c.WebSocket.EvictDrops })},
+	{"WS_HUB_SHARDS", intSetter(func(c *Config) *int { return &c.WebSocket.HubShards })},
+	{"WS_HUB_QUEUE_SIZE", intSetter(func(c *Config) *int { return &c.WebSocket.HubQueueSize })},
+	{"WS_COMPRESSION", boolSetter(func(c *Config) *bool { return &c.WebSocket.Compression })},
+	{"WS_COMPRESSION_LEVEL", intSetter(func(c *Config) *int { return &c.WebSocket.CompressionLevel })},
+	{"WS_COMPRESSION_THRESHOLD", intSetter(func(c *Config) *int { return &c.WebSocket.CompressionThreshold })},
+
+	{"AUTH_ENABLED", boolSetter(func(c *Config) *bool { return &c.Auth.Enabled })},
+	{"AUTH_CACHE_TTL", durationSetter(func(c *Config) *Duration { return &c.Auth.CacheTTL })},
+
+	{"RATE_LIMIT_ENABLED", boolSetter(func(c *Config) *bool { return &c.RateLimit.Enabled })},
+	{"RATE_LIMIT_REQUESTS_PER_SECOND", floatSetter(func(c *Config) *float64 { return &c.RateLimit.RequestsPerSecond })},
+	{"RATE_LIMIT_BURST", intSetter(func(c *Config) *int { return &c.RateLimit.Burst })},
+	{"RATE_LIMIT_MAX_KLINES", intSetter(func(c *Config) *int { return &c.RateLimit.MaxKlines })},
+
+	{"WRITER_BATCH_SIZE", intSetter(func(c *Config) *int { return &c.Writer.BatchSize })},
+	{"WRITER_FLUSH_INTERVAL", durationSetter(func(c *Config) *Duration { return &c.Writer.FlushInterval })},
+	{"WRITER_QUEUE_SIZE", intSetter(func(c *Config) *int { return &c.Writer.QueueSize })},
+	{"WRITER_JOURNAL_PATH", stringSetter(func(c *Config) *string { return &c.Writer.JournalPath })},
+
+	{"IMPORT_DIR", stringSetter(func(c *Config) *string { return &c.Import.Dir })},
+
+	{"BUS_DRIVER", stringSetter(func(c *Config) *string { return &c.Bus.Driver })},
+	{"BUS_STATUS_INTERVAL", durationSetter(func(c *Config) *Duration { return &c.Bus.StatusInterval })},
+
+	{"LOG_LEVEL", stringSetter(func(c *Config) *string { return &c.Log.Level })},
+	{"LOG_FORMAT", stringSetter(func(c *Config) *string { return &c.Log.Format })},
+	{"LOG_COMPONENTS", mapSetter(func(c *Config) *map[string]string { return &c.Log.Components })},
+	{"LOG_SAMPLE_EVERY", intSetter(func(c *Config) *int { return &c.Log.SampleEvery })},
+
+	{"TRACING_ENABLED", boolSetter(func(c *Config) *bool { return &c.Tracing.Enabled })},
+	{"TRACING_ENDPOINT", stringSetter(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"TRACING_INSECURE", boolSetter(func(c *Config) *bool { return &c.Tracing.Insecure })},
+	{"TRACING_SAMPLE_RATIO", floatSetter(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{"TRACING_SERVICE_NAME", stringSetter(func(c *Config) *string { return &c.Tracing.ServiceName })},
+}
+
+// applyEnv overrides values from set, non-empty environment variables
+func (c *Config) applyEnv() error {
+	for _, binding := range envBindings {
+		value, ok := os.LookupEnv(binding.name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := binding.set(c, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", binding.name, err)
+		}
+	}
+	return nil
+}
+
+func stringSetter(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func intSetter(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolSetter(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func floatSetter(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func durationSetter(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		return field(c).UnmarshalText([]byte(value))
+	}
+}
+
+func listSetter(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = splitList(value)
+		return nil
+	}
+}
+
+// mapSetter parses a comma separated list of key=value pairs
+func mapSetter(field func(*Config) *map[string]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		m := make(map[string]string)
+		for _, item := range splitList(value) {
+			key, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got %q", item)
+			}
+			m[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		*field(c) = m
+		return nil
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
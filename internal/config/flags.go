@@ -0,0 +1,75 @@
+package config
+
+import (
+	"flag"
+)
+
+// Flags holds command-line overrides registered on a FlagSet
+// Only flags that were explicitly set override the file and environment
+type Flags struct {
+	fs        *flag.FlagSet
+	path      string
+	port      int
+	network   string
+	dbHost    string
+	dbPort    int
+	dbUser    string
+	dbName    string
+	symbols   string
+	intervals string
+	logLevel  string
+}
+
+// RegisterFlags defines the configuration flags on fs
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.path, "config", "", "path to a YAML or TOML config file (default $CONFIG_FILE)")
+	fs.IntVar(&f.port, "port", 0, "HTTP server port")
+	fs.StringVar(&f.network, "network", "", "exchange network: testnet or mainnet")
+	fs.StringVar(&f.dbHost, "db-host", "", "database host")
+	fs.IntVar(&f.dbPort, "db-port", 0, "database port")
+	fs.StringVar(&f.dbUser, "db-user", "", "database user")
+	fs.StringVar(&f.dbName, "db-name", "", "database name")
+	fs.StringVar(&f.symbols, "symbols", "", "comma separated list of tracked symbols")
+	fs.StringVar(&f.intervals, "intervals", "", "comma separated list of tracked intervals")
+	fs.StringVar(&f.logLevel, "log-level", "", "log level of every component: debug, info, warn or error")
+	return f
+}
+
+// Load builds the effective configuration from defaults, the config file,
+// environment variables and the parsed flags, in increasing precedence
+func (f *Flags) Load() (*Config, error) {
+	cfg, err := load(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "port":
+			cfg.Server.Port = f.port
+		case "network":
+			cfg.Exchange.Network = f.network
+		case "db-host":
+			cfg.Database.Host = f.dbHost
+		case "db-port":
+			cfg.Database.Port = f.dbPort
+		case "db-user":
+			cfg.Database.User = f.dbUser
+		case "db-name":
+			cfg.Database.Name = f.dbName
+		case "symbols":
+			cfg.Tracking.Symbols = splitList(f.symbols)
+		case "intervals":
+			cfg.Tracking.Intervals = splitList(f.intervals)
+		case "log-level":
+			cfg.Log.Level = f.logLevel
+		}
+	})
+
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,206 @@
+package export
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"crypto-monitor/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Supported export formats
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Writer encodes klines one at a time into an export format
+// Close must be called to flush buffered data and write any trailer
+type Writer interface {
+	Write(kline models.Kline) error
+	Close() error
+}
+
+// Row is the flat, numeric representation of a kline used by all export formats
+type Row struct {
+	Symbol    string  `json:"symbol" parquet:"symbol,dict"`
+	Interval  string  `json:"interval" parquet:"interval,dict"`
+	OpenTime  int64   `json:"open_time" parquet:"open_time"`
+	CloseTime int64   `json:"close_time" parquet:"close_time"`
+	Open      float64 `json:"open" parquet:"open"`
+	High      float64 `json:"high" parquet:"high"`
+	Low       float64 `json:"low" parquet:"low"`
+	Close     float64 `json:"close" parquet:"close"`
+	Volume    float64 `json:"volume" parquet:"volume"`
+}
+
+// columns is the CSV header, matching the field order of Row
+var columns = []string{"symbol", "interval", "open_time", "close_time", "open", "high", "low", "close", "volume"}
+
+// NewRow converts a kline model into an export row
+func NewRow(kline models.Kline) Row {
+	return Row{
+		Symbol:    kline.Symbol,
+		Interval:  kline.Interval,
+		OpenTime:  kline.OpenTime,
+		CloseTime: kline.CloseTime,
+		Open:      kline.OpenPrice,
+		High:      kline.HighPrice,
+		Low:       kline.LowPrice,
+		Close:     kline.ClosePrice,
+		Volume:    kline.Volume,
+	}
+}
+
+// IsValidFormat reports whether format is a supported export format
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type for an export format
+func ContentType(format string, gzipped bool) string {
+	if gzipped {
+		return "application/gzip"
+	}
+	switch format {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv"
+	}
+}
+
+// FileName returns the conventional file name for an export of one series
+func FileName(symbol, interval, format string, gzipped bool) string {
+	name := fmt.Sprintf("%s_%s.%s", symbol, interval, format)
+	if gzipped {
+		name += ".gz"
+	}
+	return name
+}
+
+// NewWriter creates a Writer for the given format on top of w
+// If gzipped is true, the encoded stream is gzip-compressed
+// Closing the returned Writer does not close w
+func NewWriter(w io.Writer, format string, gzipped bool) (Writer, error) {
+	if !IsValidFormat(format) {
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+
+	var enc Writer
+	switch format {
+	case FormatCSV:
+		enc = newCSVWriter(w)
+	case FormatNDJSON:
+		enc = &ndjsonWriter{enc: json.NewEncoder(w)}
+	case FormatParquet:
+		enc = &parquetWriter{w: parquet.NewGenericWriter[Row](w)}
+	}
+
+	if gz != nil {
+		return &gzipWriter{Writer: enc, gz: gz}, nil
+	}
+	return enc, nil
+}
+
+// csvWriter writes klines as CSV with a header row
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+	record      []string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), record: make([]string, len(columns))}
+}
+
+func (c *csvWriter) Write(kline models.Kline) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(columns); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+
+	c.record[0] = kline.Symbol
+	c.record[1] = kline.Interval
+	c.record[2] = strconv.FormatInt(kline.OpenTime, 10)
+	c.record[3] = strconv.FormatInt(kline.CloseTime, 10)
+	c.record[4] = strconv.FormatFloat(kline.OpenPrice, 'f', -1, 64)
+	c.record[5] = strconv.FormatFloat(kline.HighPrice, 'f', -1, 64)
+	c.record[6] = strconv.FormatFloat(kline.LowPrice, 'f', -1, 64)
+	c.record[7] = strconv.FormatFloat(kline.ClosePrice, 'f', -1, 64)
+	c.record[8] = strconv.FormatFloat(kline.Volume, 'f', -1, 64)
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	// An empty export still gets a header so consumers can infer the schema
+	if !c.wroteHeader {
+		if err := c.w.Write(columns); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonWriter writes one JSON object per line
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(kline models.Kline) error {
+	return n.enc.Encode(NewRow(kline))
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// parquetWriter buffers rows into row groups and writes the footer on Close
+type parquetWriter struct {
+	w   *parquet.GenericWriter[Row]
+	row [1]Row
+}
+
+func (p *parquetWriter) Write(kline models.Kline) error {
+	p.row[0] = NewRow(kline)
+	_, err := p.w.Write(p.row[:])
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
+
+// gzipWriter closes the format encoder before the gzip stream
+type gzipWriter struct {
+	Writer
+	gz *gzip.Writer
+}
+
+func (g *gzipWriter) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		return err
+	}
+	return g.gz.Close()
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"crypto-monitor/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// testKlines returns a small ordered series for encoding tests
+func testKlines() []models.Kline {
+	klines := make([]models.Kline, 3)
+	for i := range klines {
+		openTime := int64(1699000000000 + i*60000)
+		klines[i] = models.Kline{
+			Symbol:     "BTCUSDT",
+			Interval:   "1m",
+			OpenTime:   openTime,
+			CloseTime:  openTime + 59999,
+			OpenPrice:  35000.5 + float64(i),
+			HighPrice:  35100.25 + float64(i),
+			LowPrice:   34900.125 + float64(i),
+			ClosePrice: 35050.0 + float64(i),
+			Volume:     100.5 + float64(i),
+		}
+	}
+	return klines
+}
+
+// encode writes klines with the given format into a buffer
+func encode(t *testing.T, format string, gzipped bool, klines []models.Kline) *bytes.Buffer {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, gzipped)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for _, kline := range klines {
+		if err := w.Write(kline); err != nil {
+			t.Fatalf("Failed to write kline: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	return &buf
+}
+
+// TestNewWriter_CSV tests CSV output has a header and numeric columns
+func TestNewWriter_CSV(t *testing.T) {
+	klines := testKlines()
+	buf := encode(t, FormatCSV, false, klines)
+
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	if len(records) != len(klines)+1 {
+		t.Fatalf("Expected %d records including header, got %d", len(klines)+1, len(records))
+	}
+	if records[0][2] != "open_time" {
+		t.Errorf("Expected header column open_time, got %s", records[0][2])
+	}
+	if records[1][2] != "1699000000000" || records[1][4] != "35000.5" {
+		t.Errorf("Unexpected first row: %v", records[1])
+	}
+}
+
+// TestNewWriter_NDJSONGzip tests gzip-compressed NDJSON output
+func TestNewWriter_NDJSONGzip(t *testing.T) {
+	klines := testKlines()
+	buf := encode(t, FormatNDJSON, true, klines)
+
+	gz, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatalf("Output is not gzip: %v", err)
+	}
+
+	scanner := bufio.NewScanner(gz)
+	lines := 0
+	for scanner.Scan() {
+		var row Row
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("Failed to parse line %d: %v", lines, err)
+		}
+		if row.OpenTime != klines[lines].OpenTime || row.High != klines[lines].HighPrice {
+			t.Errorf("Row %d does not match input: %+v", lines, row)
+		}
+		lines++
+	}
+
+	if lines != len(klines) {
+		t.Errorf("Expected %d lines, got %d", len(klines), lines)
+	}
+}
+
+// TestNewWriter_Parquet tests Parquet output can be read back
+func TestNewWriter_Parquet(t *testing.T) {
+	klines := testKlines()
+	buf := encode(t, FormatParquet, false, klines)
+
+	rows, err := parquet.Read[Row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read parquet: %v", err)
+	}
+
+	if len(rows) != len(klines) {
+		t.Fatalf("Expected %d rows, got %d", len(klines), len(rows))
+	}
+	if rows[2] != NewRow(klines[2]) {
+		t.Errorf("Expected %+v, got %+v", NewRow(klines[2]), rows[2])
+	}
+}
+
+// TestNewWriter_InvalidFormat tests unsupported formats are rejected
+func TestNewWriter_InvalidFormat(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, "xlsx", false); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -0,0 +1,227 @@
+package grpcapi
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/service"
+	"crypto-monitor/pkg/marketpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// defaultPageSize is the page size of GetKlines requests without one
+const defaultPageSize = 500
+
+// marketServer implements the market service
+type marketServer struct {
+	marketpb.UnimplementedMarketServiceServer
+
+	klines    KlineStore
+	streams   KlineStreams
+	series    service.HealthSource
+	maxKlines int
+}
+
+// GetKlines returns a page of stored klines, most recent first
+// The page token is the open time the next page ends before, so pages stay
+// contiguous as new klines are stored.
+func (s *marketServer) GetKlines(ctx context.Context, req *marketpb.GetKlinesRequest) (*marketpb.GetKlinesResponse, error) {
+	if req.Symbol == "" || req.Interval == "" {
+		return nil, status.Error(codes.InvalidArgument, "symbol and interval are required")
+	}
+	symbol := strings.ToUpper(req.Symbol)
+
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	}
+	if s.maxKlines > 0 && pageSize > s.maxKlines {
+		pageSize = s.maxKlines
+	}
+
+	startTime, endTime := req.StartTime, req.EndTime
+	if req.PageToken != "" {
+		before, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		endTime = &before
+	}
+
+	// One more kline than the page tells whether another page follows
+	klines, err := s.klines.GetKlines(ctx, symbol, req.Interval, startTime, endTime, pageSize+1)
+	if err != nil {
+		grpcLog.ErrorContext(ctx, "Failed to query klines", "symbol", symbol, "interval", req.Interval, "error", err)
+		return nil, status.Error(codes.Internal, "failed to query klines")
+	}
+
+	resp := &marketpb.GetKlinesResponse{}
+	if len(klines) > pageSize {
+		klines = klines[:pageSize]
+		resp.NextPageToken = encodePageToken(klines[len(klines)-1].OpenTime - 1)
+	}
+	resp.Klines = make([]*marketpb.Kline, len(klines))
+	for i, kline := range klines {
+		resp.Klines[i] = storedKline(kline)
+	}
+	return resp, nil
+}
+
+// encodePageToken returns the token of the page ending at open time before
+func encodePageToken(before int64) string {
+	return base64.RawURLEncoding.EncodeToString(strconv.AppendInt(nil, before, 10))
+}
+
+// decodePageToken returns the open time the page of a token ends at
+func decodePageToken(token string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(b), 10, 64)
+}
+
+// StreamKlines streams the live updates of a series from the websocket
+// fan-out, until the client cancels, the series is no longer tracked, the
+// client is evicted or the server shuts down
+func (s *marketServer) StreamKlines(req *marketpb.StreamKlinesRequest, stream grpc.ServerStreamingServer[marketpb.StreamKlinesResponse]) error {
+	if req.Snapshot < 0 {
+		return status.Error(codes.InvalidArgument, "snapshot must not be negative")
+	}
+	if req.ThrottleMs < 0 {
+		return status.Error(codes.InvalidArgument, "throttle_ms must not be negative")
+	}
+	if req.ResumeSeq != nil && req.ResumeEpoch == 0 {
+		return status.Error(codes.InvalidArgument, "resume_epoch is required with resume_seq")
+	}
+
+	ctx := stream.Context()
+	symbol := strings.ToUpper(req.Symbol)
+	open := service.StreamRequest{
+		Symbol:     symbol,
+		Interval:   req.Interval,
+		Snapshot:   int(req.Snapshot),
+		ThrottleMs: req.ThrottleMs,
+		Encoding:   service.EncodingMsgpack,
+	}
+	if req.ResumeSeq != nil {
+		open.Resume, open.Epoch, open.Seq = true, req.ResumeEpoch, *req.ResumeSeq
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		open.RemoteAddr = p.Addr.String()
+	}
+	es, err := s.streams.OpenEventStream(open)
+	if err != nil {
+		return streamStatus(err)
+	}
+	defer es.Close()
+
+	for {
+		msg, err := es.Recv(ctx)
+		switch {
+		case errors.Is(err, service.ErrEvicted):
+			return status.Error(codes.ResourceExhausted, err.Error())
+		case errors.Is(err, io.EOF):
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case ctx.Err() != nil:
+			return status.FromContextError(ctx.Err()).Err()
+		case err != nil:
+			grpcLog.ErrorContext(ctx, "Failed to receive stream message", "symbol", symbol, "interval", req.Interval, "error", err)
+			return status.Error(codes.Internal, "failed to receive update")
+		}
+
+		resp := &marketpb.StreamKlinesResponse{Seq: msg.Seq, Epoch: es.Epoch()}
+		switch msg.Type {
+		case "subscribed":
+			resp.Event = &marketpb.StreamKlinesResponse_Subscribed{Subscribed: &marketpb.Subscribed{ThrottleMs: msg.ThrottleMs}}
+		case "snapshot":
+			klines := make([]*marketpb.Kline, len(msg.Klines))
+			for i, kline := range msg.Klines {
+				klines[i] = liveKline(symbol, req.Interval, kline)
+			}
+			resp.Event = &marketpb.StreamKlinesResponse_Snapshot{Snapshot: &marketpb.Snapshot{Klines: klines}}
+		case "kline_update":
+			resp.Event = &marketpb.StreamKlinesResponse_Update{Update: liveKline(symbol, req.Interval, msg.Klines[0])}
+		case "resumed":
+			resp.Event = &marketpb.StreamKlinesResponse_Resumed{Resumed: &marketpb.Resumed{ThrottleMs: msg.ThrottleMs}}
+		case "slow_consumer":
+			resp.Event = &marketpb.StreamKlinesResponse_SlowConsumer{SlowConsumer: &marketpb.SlowConsumer{Message: msg.Message}}
+		case "series_removed":
+			return status.Errorf(codes.NotFound, "%s %s is no longer tracked", symbol, req.Interval)
+		case "error":
+			return status.Error(codes.Internal, msg.Message)
+		default:
+			continue
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// ListSymbols returns the tracked symbols, with their intervals from the
+// shortest
+func (s *marketServer) ListSymbols(ctx context.Context, req *marketpb.ListSymbolsRequest) (*marketpb.ListSymbolsResponse, error) {
+	intervals := make(map[string][]string)
+	for _, series := range s.series.Health() {
+		intervals[series.Symbol] = append(intervals[series.Symbol], series.Interval)
+	}
+
+	resp := &marketpb.ListSymbolsResponse{Symbols: make([]*marketpb.Symbol, 0, len(intervals))}
+	for symbol, list := range intervals {
+		slices.SortFunc(list, func(a, b string) int {
+			da, _ := models.IntervalDuration(a)
+			db, _ := models.IntervalDuration(b)
+			return cmp.Compare(da, db)
+		})
+		resp.Symbols = append(resp.Symbols, &marketpb.Symbol{Symbol: symbol, Intervals: list})
+	}
+	slices.SortFunc(resp.Symbols, func(a, b *marketpb.Symbol) int {
+		return strings.Compare(a.Symbol, b.Symbol)
+	})
+	return resp, nil
+}
+
+// storedKline converts a stored kline
+func storedKline(kline models.Kline) *marketpb.Kline {
+	return &marketpb.Kline{
+		Symbol:    kline.Symbol,
+		Interval:  kline.Interval,
+		OpenTime:  kline.OpenTime,
+		CloseTime: kline.CloseTime,
+		Open:      kline.OpenPrice,
+		High:      kline.HighPrice,
+		Low:       kline.LowPrice,
+		Close:     kline.ClosePrice,
+		Volume:    kline.Volume,
+	}
+}
+
+// liveKline converts a kline of the websocket fan-out
+func liveKline(symbol, interval string, kline service.KlineData) *marketpb.Kline {
+	return &marketpb.Kline{
+		Symbol:    symbol,
+		Interval:  interval,
+		OpenTime:  kline.OpenTime,
+		CloseTime: kline.CloseTime,
+		Open:      kline.Open,
+		High:      kline.High,
+		Low:       kline.Low,
+		Close:     kline.Close,
+		Volume:    kline.Volume,
+	}
+}
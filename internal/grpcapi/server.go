@@ -0,0 +1,218 @@
+// Package grpcapi serves the market API over gRPC, from the same repository
+// and websocket fan-out as the REST and websocket APIs
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/ratelimit"
+	"crypto-monitor/internal/service"
+	"crypto-monitor/pkg/marketpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+var grpcLog = logging.For(logging.GRPC)
+
+// apiKeyMetadata carries an API key as an alternative to an authorization
+// bearer token, as the X-API-Key header does over HTTP
+const apiKeyMetadata = "x-api-key"
+
+// KlineStore reads stored klines, most recent first
+type KlineStore interface {
+	GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error)
+}
+
+// KlineStreams opens streams of live klines
+type KlineStreams interface {
+	OpenEventStream(req service.StreamRequest) (*service.EventStream, error)
+}
+
+// Dependencies are the services the gRPC API is served from
+type Dependencies struct {
+	Klines  KlineStore
+	Streams KlineStreams
+	// Series reports the tracked series, listed by ListSymbols
+	Series service.HealthSource
+	// Auth requires calls to carry an API key granting market:read; nil
+	// serves every call
+	Auth *auth.Authenticator
+	// RateLimiter limits the calls of each client; nil is unlimited
+	RateLimiter *ratelimit.Limiter
+	// Connections caps the streams each client holds open, counted with its
+	// websocket connections
+	Connections *ratelimit.Counter
+	// MaxKlines caps the page size of GetKlines; 0 is unlimited
+	MaxKlines int
+	// Reflection registers the reflection service
+	Reflection bool
+}
+
+// NewServer creates a gRPC server serving the market service
+func NewServer(deps Dependencies, opts ...grpc.ServerOption) *grpc.Server {
+	g := &guard{
+		auth:        deps.Auth,
+		limiter:     deps.RateLimiter,
+		connections: deps.Connections,
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(g.unary), grpc.ChainStreamInterceptor(g.stream))
+	s := grpc.NewServer(opts...)
+	marketpb.RegisterMarketServiceServer(s, &marketServer{
+		klines:    deps.Klines,
+		streams:   deps.Streams,
+		series:    deps.Series,
+		maxKlines: deps.MaxKlines,
+	})
+	if deps.Reflection {
+		reflection.Register(s)
+	}
+	return s
+}
+
+// guard authenticates and limits the calls of the market service; the
+// reflection service is open to every client
+type guard struct {
+	auth        *auth.Authenticator
+	limiter     *ratelimit.Limiter
+	connections *ratelimit.Counter
+}
+
+// marketMethod reports whether a method belongs to the market service
+func marketMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+marketpb.MarketService_ServiceDesc.ServiceName+"/")
+}
+
+func (g *guard) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !marketMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := g.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *guard) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !marketMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	ctx, err := g.admit(ss.Context())
+	if err != nil {
+		return err
+	}
+	// Streams are counted until they end, like websocket connections
+	if g.connections != nil {
+		release, ok := g.connections.Acquire(clientID(ctx))
+		if !ok {
+			metrics.RateLimited.WithLabelValues("grpc_streams").Inc()
+			return status.Errorf(codes.ResourceExhausted, "too many streams, at most %d per client; close one before opening another", g.connections.Max())
+		}
+		defer release()
+	}
+	return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+}
+
+// admit authenticates a call and takes a token of its client's bucket,
+// returning the context carrying the principal
+func (g *guard) admit(ctx context.Context) (context.Context, error) {
+	if g.auth != nil {
+		key := callKey(ctx)
+		if key == "" {
+			return nil, status.Error(codes.Unauthenticated, "API key required")
+		}
+		principal, err := g.auth.Authenticate(ctx, key)
+		if errors.Is(err, auth.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		if err != nil {
+			grpcLog.ErrorContext(ctx, "Failed to verify API key", "error", err)
+			return nil, status.Error(codes.Unavailable, "authentication unavailable")
+		}
+		if !principal.Allows(auth.ScopeMarketRead) {
+			return nil, status.Errorf(codes.PermissionDenied, "API key lacks the %s scope", auth.ScopeMarketRead)
+		}
+		ctx = auth.WithPrincipal(ctx, principal)
+	}
+
+	if g.limiter != nil {
+		if ok, retry := g.limiter.Allow(clientID(ctx)); !ok {
+			metrics.RateLimited.WithLabelValues("grpc").Inc()
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", retry.Round(time.Millisecond))
+		}
+	}
+	return ctx, nil
+}
+
+// callKey returns the API key sent with a call, if any
+func callKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if scheme, token, ok := strings.Cut(value, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if keys := md.Get(apiKeyMetadata); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// clientID identifies the client of a call for rate limiting: its API key
+// when authenticated, otherwise its address
+func clientID(ctx context.Context) string {
+	if principal := auth.PrincipalFrom(ctx); principal != nil {
+		return "key:" + strconv.FormatUint(principal.KeyID, 10)
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:"
+	}
+	return "ip:" + remoteHost(p.Addr)
+}
+
+// remoteHost returns the host of a peer address, or the whole address when it
+// has no port
+func remoteHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// principalStream is a server stream whose context carries the principal
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// streamStatus converts an error opening a stream to a gRPC status
+func streamStatus(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotTracked):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrSnapshotUnavailable), errors.Is(err, service.ErrShuttingDown):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
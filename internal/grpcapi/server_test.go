@@ -0,0 +1,303 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/auth"
+	"crypto-monitor/internal/auth/authtest"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/ratelimit"
+	"crypto-monitor/internal/service"
+	"crypto-monitor/internal/service/servicetest"
+	"crypto-monitor/pkg/marketpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// memoryKlines stores klines in memory, ascending by open time
+type memoryKlines struct {
+	klines []models.Kline
+	err    error
+}
+
+func (m *memoryKlines) GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var found []models.Kline
+	for _, kline := range slices.Backward(m.klines) {
+		if kline.Symbol != symbol || kline.Interval != interval ||
+			(startTime != nil && kline.OpenTime < *startTime) || (endTime != nil && kline.OpenTime > *endTime) {
+			continue
+		}
+		if found = append(found, kline); len(found) == limit {
+			break
+		}
+	}
+	return found, nil
+}
+
+// serve serves deps on an in-process listener and returns a connection to it
+func serve(t *testing.T, deps Dependencies) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(deps)
+	go server.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial server: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return conn
+}
+
+// setupMarketServer serves the market service from klines and a running
+// websocket service tracking BTCUSDT 1m
+func setupMarketServer(t *testing.T, klines *memoryKlines) (*service.WebSocketService, marketpb.MarketServiceClient) {
+	t.Helper()
+	wsSvc := service.NewWebSocketService(servicetest.TrackedSeries{}, nil, config.Default().WebSocket)
+	go wsSvc.Run(t.Context())
+
+	conn := serve(t, Dependencies{
+		Klines:     klines,
+		Streams:    wsSvc,
+		Series:     servicetest.TrackedSeries{},
+		MaxKlines:  100,
+		Reflection: true,
+	})
+	return wsSvc, marketpb.NewMarketServiceClient(conn)
+}
+
+// TestMarketServer_GetKlines tests paging through stored klines, most recent
+// first
+func TestMarketServer_GetKlines(t *testing.T) {
+	store := &memoryKlines{}
+	for i := int64(1); i <= 5; i++ {
+		store.klines = append(store.klines, models.Kline{Symbol: "BTCUSDT", Interval: "1m", OpenTime: i * 60000, ClosePrice: 50000})
+	}
+	_, client := setupMarketServer(t, store)
+
+	var openTimes []int64
+	req := &marketpb.GetKlinesRequest{Symbol: "btcusdt", Interval: "1m", StartTime: proto.Int64(120000), PageSize: 2}
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("Expected the last page after 3 pages")
+		}
+		resp, err := client.GetKlines(t.Context(), req)
+		if err != nil {
+			t.Fatalf("Failed to get klines: %v", err)
+		}
+		for _, kline := range resp.Klines {
+			openTimes = append(openTimes, kline.OpenTime)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	if want := []int64{300000, 240000, 180000, 120000}; !slices.Equal(openTimes, want) {
+		t.Errorf("Expected open times %v, got %v", want, openTimes)
+	}
+
+	for _, req := range []*marketpb.GetKlinesRequest{
+		{Interval: "1m"},
+		{Symbol: "BTCUSDT", Interval: "1m", PageSize: -1},
+		{Symbol: "BTCUSDT", Interval: "1m", PageToken: "not a token"},
+	} {
+		if _, err := client.GetKlines(t.Context(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for %v, got %v", req, err)
+		}
+	}
+
+	store.err = errors.New("connection refused")
+	if _, err := client.GetKlines(t.Context(), &marketpb.GetKlinesRequest{Symbol: "BTCUSDT", Interval: "1m"}); status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal when the query fails, got %v", err)
+	}
+}
+
+// TestMarketServer_StreamKlines tests streaming live updates and resuming
+// after the last one received
+func TestMarketServer_StreamKlines(t *testing.T) {
+	wsSvc, client := setupMarketServer(t, &memoryKlines{})
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamKlines(ctx, &marketpb.StreamKlinesRequest{Symbol: "BTCUSDT", Interval: "1m", ThrottleMs: 100})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil || resp.GetSubscribed().GetThrottleMs() != 100 || resp.Epoch == 0 {
+		t.Fatalf("Expected subscribed with an epoch, got %v %v", resp, err)
+	}
+	epoch := resp.Epoch
+	for i := int64(1); i <= 2; i++ {
+		wsSvc.OnKline(t.Context(), models.Kline{Symbol: "BTCUSDT", Interval: "1m", OpenTime: i * 60000, ClosePrice: 50000.5})
+		resp, err := stream.Recv()
+		if err != nil || resp.Seq != uint64(i) || resp.Epoch != epoch || resp.GetUpdate().GetOpenTime() != i*60000 || resp.GetUpdate().GetClose() != 50000.5 || resp.GetUpdate().GetSymbol() != "BTCUSDT" {
+			t.Fatalf("Expected update %d, got %v %v", i, resp, err)
+		}
+	}
+
+	// Update 2 is replayed to a client that last received update 1
+	resumed, err := client.StreamKlines(ctx, &marketpb.StreamKlinesRequest{Symbol: "BTCUSDT", Interval: "1m", ResumeSeq: proto.Uint64(1), ResumeEpoch: epoch})
+	if err != nil {
+		t.Fatalf("Failed to resume stream: %v", err)
+	}
+	if resp, err := resumed.Recv(); err != nil || resp.GetResumed() == nil || resp.Seq != 1 {
+		t.Fatalf("Expected resumed, got %v %v", resp, err)
+	}
+	if resp, err := resumed.Recv(); err != nil || resp.Seq != 2 || resp.GetUpdate().GetOpenTime() != 120000 {
+		t.Errorf("Expected update 2 replayed, got %v %v", resp, err)
+	}
+
+	// The seq of another epoch is not resumed
+	restarted, err := client.StreamKlines(ctx, &marketpb.StreamKlinesRequest{Symbol: "BTCUSDT", Interval: "1m", ResumeSeq: proto.Uint64(1), ResumeEpoch: epoch + 1})
+	if err != nil {
+		t.Fatalf("Failed to resume stream: %v", err)
+	}
+	if resp, err := restarted.Recv(); err != nil || resp.GetSubscribed() == nil || resp.Seq != 2 || resp.Epoch != epoch {
+		t.Errorf("Expected subscribed resuming another epoch, got %v %v", resp, err)
+	}
+
+	// resume_seq means nothing without its epoch
+	unscoped, err := client.StreamKlines(ctx, &marketpb.StreamKlinesRequest{Symbol: "BTCUSDT", Interval: "1m", ResumeSeq: proto.Uint64(1)})
+	if err == nil {
+		_, err = unscoped.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument resuming without an epoch, got %v", err)
+	}
+
+	wsSvc.OnSeriesRemoved(service.Series{Symbol: "BTCUSDT", Interval: "1m"})
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound once the series is removed, got %v", err)
+	}
+
+	untracked, err := client.StreamKlines(ctx, &marketpb.StreamKlinesRequest{Symbol: "ETHUSDT", Interval: "1m"})
+	if err == nil {
+		_, err = untracked.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an untracked series, got %v", err)
+	}
+}
+
+// TestMarketServer_ListSymbols tests listing the tracked symbols
+func TestMarketServer_ListSymbols(t *testing.T) {
+	wsSvc := service.NewWebSocketService(servicetest.TrackedSeries{}, nil, config.Default().WebSocket)
+	health := healthSource{
+		{Symbol: "ETHUSDT", Interval: "1h"},
+		{Symbol: "BTCUSDT", Interval: "1h"},
+		{Symbol: "ETHUSDT", Interval: "5m"},
+		{Symbol: "BTCUSDT", Interval: "1m"},
+	}
+	client := marketpb.NewMarketServiceClient(serve(t, Dependencies{Streams: wsSvc, Series: health}))
+
+	resp, err := client.ListSymbols(t.Context(), &marketpb.ListSymbolsRequest{})
+	if err != nil {
+		t.Fatalf("Failed to list symbols: %v", err)
+	}
+	want := []*marketpb.Symbol{
+		{Symbol: "BTCUSDT", Intervals: []string{"1m", "1h"}},
+		{Symbol: "ETHUSDT", Intervals: []string{"5m", "1h"}},
+	}
+	if !slices.EqualFunc(resp.Symbols, want, func(a, b *marketpb.Symbol) bool { return proto.Equal(a, b) }) {
+		t.Errorf("Expected %v, got %v", want, resp.Symbols)
+	}
+}
+
+// healthSource reports a fixed list of series
+type healthSource []service.SeriesHealth
+
+func (h healthSource) Health() []service.SeriesHealth {
+	return h
+}
+
+// TestNewServer_Auth tests that market calls need an API key granting
+// market:read, while reflection stays open
+func TestNewServer_Auth(t *testing.T) {
+	store := &authtest.KeyStore{}
+	conn := serve(t, Dependencies{
+		Klines:      &memoryKlines{},
+		Series:      servicetest.TrackedSeries{},
+		Auth:        auth.NewAuthenticator(store, time.Minute),
+		RateLimiter: ratelimit.NewLimiter(1, 2),
+		Reflection:  true,
+	})
+	client := marketpb.NewMarketServiceClient(conn)
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(t.Context(), "authorization", "Bearer "+key)
+	}
+
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"no key", t.Context(), codes.Unauthenticated},
+		{"invalid key", withKey("cm_invalid"), codes.Unauthenticated},
+		{"wrong scope", withKey(store.Issue(t, auth.ScopeAlertsManage)), codes.PermissionDenied},
+		{"x-api-key", metadata.AppendToOutgoingContext(t.Context(), "x-api-key", store.Issue(t, auth.ScopeMarketRead)), codes.OK},
+	} {
+		if _, err := client.ListSymbols(tt.ctx, &marketpb.ListSymbolsRequest{}); status.Code(err) != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Calls are limited per key
+	ctx := withKey(store.Issue(t, auth.ScopeMarketRead))
+	var err error
+	for range 3 {
+		if _, err = client.ListSymbols(ctx, &marketpb.ListSymbolsRequest{}); err != nil {
+			break
+		}
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted past the burst, got %v", err)
+	}
+
+	// Reflection lists the market service without a key
+	refl, err := grpc_reflection_v1.NewServerReflectionClient(conn).ServerReflectionInfo(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to open reflection stream: %v", err)
+	}
+	if err := refl.Send(&grpc_reflection_v1.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Failed to send reflection request: %v", err)
+	}
+	resp, err := refl.Recv()
+	if err != nil {
+		t.Fatalf("Failed to list services: %v", err)
+	}
+	var services []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		services = append(services, s.Name)
+	}
+	if !slices.Contains(services, marketpb.MarketService_ServiceDesc.ServiceName) {
+		t.Errorf("Expected reflection to list the market service, got %v", services)
+	}
+}
@@ -0,0 +1,378 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	importerLog    = logging.For(logging.Importer)
+	importerTracer = tracing.Tracer(logging.Importer)
+)
+
+const (
+	// defaultBatchSize is the number of klines loaded per database call
+	defaultBatchSize = 5000
+
+	// checksumSuffix is appended to an archive name to find its checksum file
+	checksumSuffix = ".CHECKSUM"
+)
+
+// KlineStore persists imported klines with upsert semantics
+type KlineStore interface {
+	BulkUpsertKlines(ctx context.Context, klines []models.Kline) error
+}
+
+// ImportLog remembers which files have already been imported into which
+// series
+type ImportLog interface {
+	IsImported(ctx context.Context, checksum, symbol, interval string) (bool, error)
+	RecordImport(ctx context.Context, record *models.KlineImport) error
+}
+
+// Options configures an import run
+type Options struct {
+	// Symbol and Interval apply to every file; for Binance archives they are
+	// inferred from the file name when empty
+	Symbol   string        `json:"symbol"`
+	Interval string        `json:"interval"`
+	Format   string        `json:"format"`
+	Mapping  ColumnMapping `json:"mapping"`
+	TimeUnit string        `json:"time_unit"`
+	// Force re-imports files that are already recorded as imported
+	Force     bool `json:"force"`
+	BatchSize int  `json:"batch_size"`
+
+	// OnProgress is called after every loaded batch and at the end of each file
+	OnProgress func(Progress) `json:"-"`
+}
+
+// Progress describes how far an import has advanced
+type Progress struct {
+	File       string `json:"file"`
+	FileIndex  int    `json:"file_index"`
+	FileCount  int    `json:"file_count"`
+	BytesRead  int64  `json:"bytes_read"`
+	TotalBytes int64  `json:"total_bytes"`
+	Rows       int64  `json:"rows"`
+	TotalRows  int64  `json:"total_rows"`
+}
+
+// Result summarizes a completed import
+type Result struct {
+	Files   int   `json:"files"`
+	Skipped int   `json:"skipped"`
+	Rows    int64 `json:"rows"`
+}
+
+// Importer loads kline archives from local disk into the database
+type Importer struct {
+	store     KlineStore
+	importLog ImportLog
+}
+
+// NewImporter creates a new Importer instance
+func NewImporter(store KlineStore, importLog ImportLog) *Importer {
+	return &Importer{store: store, importLog: importLog}
+}
+
+// Import imports a single file, or every .zip and .csv file in a directory in name order
+func (im *Importer) Import(ctx context.Context, path string, opts Options) (Result, error) {
+	if opts.Format == "" {
+		opts.Format = FormatBinance
+	}
+	if opts.Format == FormatGeneric && (opts.Symbol == "" || opts.Interval == "") {
+		return Result{}, fmt.Errorf("symbol and interval are required for generic CSV imports")
+	}
+	if opts.Mapping == (ColumnMapping{}) {
+		opts.Mapping = DefaultColumnMapping()
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	files, err := listFiles(path)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	progress := Progress{FileCount: len(files)}
+	for i, file := range files {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		progress.File = filepath.Base(file)
+		progress.FileIndex = i + 1
+		progress.BytesRead = 0
+		progress.TotalBytes = 0
+		progress.Rows = 0
+
+		rows, skipped, err := im.importFile(ctx, file, opts, &progress)
+		if err != nil {
+			return result, fmt.Errorf("failed to import %s: %w", filepath.Base(file), err)
+		}
+
+		result.Files++
+		result.Rows += rows
+		if skipped {
+			result.Skipped++
+		}
+	}
+
+	return result, nil
+}
+
+// importFile verifies, parses and loads one file
+func (im *Importer) importFile(ctx context.Context, path string, opts Options, progress *Progress) (rows int64, skipped bool, err error) {
+	ctx, span := importerTracer.Start(ctx, "importer.file", trace.WithAttributes(attribute.String("import.file", filepath.Base(path))))
+	defer func() {
+		span.SetAttributes(attribute.Int64("klines.count", rows), attribute.Bool("import.skipped", skipped))
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	symbol, interval := opts.Symbol, opts.Interval
+	if symbol == "" || interval == "" {
+		fileSymbol, fileInterval, ok := ParseBinanceFileName(path)
+		if !ok {
+			return 0, false, fmt.Errorf("cannot infer symbol and interval from file name, specify them explicitly")
+		}
+		if symbol == "" {
+			symbol = fileSymbol
+		}
+		if interval == "" {
+			interval = fileInterval
+		}
+	}
+
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := verifyChecksum(path, checksum); err != nil {
+		return 0, false, err
+	}
+
+	if !opts.Force {
+		imported, err := im.importLog.IsImported(ctx, checksum, symbol, interval)
+		if err != nil {
+			return 0, false, err
+		}
+		if imported {
+			importerLog.Info("Skipping file, already imported", "file", filepath.Base(path), "symbol", symbol, "interval", interval)
+			report(opts, *progress)
+			return 0, true, nil
+		}
+	}
+
+	batch := make([]models.Kline, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := im.store.BulkUpsertKlines(ctx, batch); err != nil {
+			return err
+		}
+		progress.Rows += int64(len(batch))
+		progress.TotalRows += int64(len(batch))
+		batch = batch[:0]
+		report(opts, *progress)
+		return nil
+	}
+
+	err = openCSV(path, progress, func(r *csv.Reader) error {
+		parser, err := newRowParser(r, opts, symbol, interval)
+		if err != nil {
+			return err
+		}
+		return readRecords(r, parser, func(kline models.Kline) error {
+			batch = append(batch, kline)
+			if len(batch) >= opts.BatchSize {
+				return flush()
+			}
+			return nil
+		})
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return progress.Rows, false, err
+	}
+
+	record := &models.KlineImport{
+		FileName: filepath.Base(path),
+		Checksum: checksum,
+		Symbol:   symbol,
+		Interval: interval,
+		Rows:     progress.Rows,
+	}
+	if err := im.importLog.RecordImport(ctx, record); err != nil {
+		return progress.Rows, false, err
+	}
+
+	importerLog.Info("Imported klines", "symbol", symbol, "interval", interval, "file", filepath.Base(path), "klines", progress.Rows)
+	return progress.Rows, false, nil
+}
+
+// report invokes the progress callback if one is set
+func report(opts Options, progress Progress) {
+	if opts.OnProgress != nil {
+		opts.OnProgress(progress)
+	}
+}
+
+// listFiles returns path itself, or the importable files in a directory
+func listFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat import path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".zip" || ext == ".csv") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .zip or .csv files found in %s", path)
+	}
+	return files, nil
+}
+
+// openCSV opens a plain CSV or every CSV entry of a zip archive and passes
+// a reader for each to fn, tracking uncompressed bytes read in progress
+func openCSV(path string, progress *Progress, fn func(*csv.Reader) error) error {
+	if strings.ToLower(filepath.Ext(path)) != ".zip" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
+
+		if info, err := f.Stat(); err == nil {
+			progress.TotalBytes = info.Size()
+		}
+		return fn(newCSVReader(&countingReader{r: f, n: &progress.BytesRead}))
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	var entries []*zip.File
+	for _, f := range zr.File {
+		if strings.ToLower(filepath.Ext(f.Name)) == ".csv" {
+			entries = append(entries, f)
+			progress.TotalBytes += int64(f.UncompressedSize64)
+		}
+	}
+	if len(entries) == 0 {
+		return errors.New("zip archive contains no CSV files")
+	}
+
+	for _, entry := range entries {
+		rc, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s in archive: %w", entry.Name, err)
+		}
+		err = fn(newCSVReader(&countingReader{r: rc, n: &progress.BytesRead}))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newCSVReader creates a CSV reader tolerant of varying column counts
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	return cr
+}
+
+// countingReader adds the number of bytes read to n
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// fileChecksum returns the hex SHA-256 of a file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyChecksum compares checksum against a "<path>.CHECKSUM" file if one exists
+// The checksum file uses the sha256sum layout: "<hex digest>  <file name>"
+func verifyChecksum(path, checksum string) error {
+	data, err := os.ReadFile(path + checksumSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum file %s is empty", filepath.Base(path)+checksumSuffix)
+	}
+	if !strings.EqualFold(fields[0], checksum) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", fields[0], checksum)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"crypto-monitor/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memoryStore is an in-memory KlineStore and ImportLog keyed like the database
+type memoryStore struct {
+	klines  map[string]models.Kline
+	imports map[string]*models.KlineImport
+	batches int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		klines:  make(map[string]models.Kline),
+		imports: make(map[string]*models.KlineImport),
+	}
+}
+
+func (m *memoryStore) BulkUpsertKlines(ctx context.Context, klines []models.Kline) error {
+	m.batches++
+	for _, k := range klines {
+		m.klines[fmt.Sprintf("%s:%s:%d", k.Symbol, k.Interval, k.OpenTime)] = k
+	}
+	return nil
+}
+
+func (m *memoryStore) IsImported(ctx context.Context, checksum, symbol, interval string) (bool, error) {
+	_, ok := m.imports[checksum+":"+symbol+":"+interval]
+	return ok, nil
+}
+
+func (m *memoryStore) RecordImport(ctx context.Context, record *models.KlineImport) error {
+	m.imports[record.Checksum+":"+record.Symbol+":"+record.Interval] = record
+	return nil
+}
+
+// binanceArchiveCSV is a three-candle excerpt in the data.binance.vision layout
+const binanceArchiveCSV = `1704067200000,42283.58,42298.62,42261.02,42298.61,35.92724,1704067259999,1518930.8,1327,20.34,860203.3,0
+1704067260000,42298.62,42320.00,42298.61,42320.00,21.07084,1704067319999,891425.6,924,12.01,508094.1,0
+1704067320000,42319.99,42331.54,42292.37,42295.04,28.32745,1704067379999,1198690.5,1116,11.40,482472.2,0
+`
+
+// writeArchive writes a Binance style zip archive and its checksum file into dir
+func writeArchive(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(strings.TrimSuffix(name, ".zip") + ".csv")
+	if err != nil {
+		t.Fatalf("Failed to create zip entry: %v", err)
+	}
+	w.Write([]byte(content))
+	zw.Close()
+	f.Close()
+
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		t.Fatalf("Failed to hash archive: %v", err)
+	}
+	os.WriteFile(path+checksumSuffix, []byte(checksum+"  "+name+"\n"), 0o644)
+	return path
+}
+
+// TestImporter_BinanceArchive tests importing a zipped archive and skipping it on re-run
+func TestImporter_BinanceArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, dir, "BTCUSDT-1m-2024-01-01.zip", binanceArchiveCSV)
+
+	store := newMemoryStore()
+	im := NewImporter(store, store)
+
+	var last Progress
+	result, err := im.Import(context.Background(), dir, Options{OnProgress: func(p Progress) { last = p }})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if result.Rows != 3 || len(store.klines) != 3 {
+		t.Errorf("Expected 3 klines imported, got result %d, stored %d", result.Rows, len(store.klines))
+	}
+	if last.BytesRead != last.TotalBytes || last.TotalBytes == 0 {
+		t.Errorf("Expected progress to reach total bytes, got %d/%d", last.BytesRead, last.TotalBytes)
+	}
+
+	for _, k := range store.klines {
+		if k.Symbol != "BTCUSDT" || k.Interval != "1m" {
+			t.Errorf("Expected symbol and interval from file name, got %s %s", k.Symbol, k.Interval)
+		}
+	}
+
+	// Re-running is a no-op
+	batches := store.batches
+	result, err = im.Import(context.Background(), dir, Options{})
+	if err != nil {
+		t.Fatalf("Re-import failed: %v", err)
+	}
+	if result.Skipped != 1 || store.batches != batches {
+		t.Errorf("Expected archive to be skipped on re-run, got %+v", result)
+	}
+}
+
+// TestImporter_ChecksumMismatch tests corrupted archives are rejected before loading
+func TestImporter_ChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	path := writeArchive(t, dir, "ETHUSDT-5m-2024-01.zip", binanceArchiveCSV)
+	os.WriteFile(path+checksumSuffix, []byte(strings.Repeat("0", 64)+"  ETHUSDT-5m-2024-01.zip\n"), 0o644)
+
+	store := newMemoryStore()
+	_, err := NewImporter(store, store).Import(context.Background(), path, Options{})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Expected checksum mismatch error, got %v", err)
+	}
+	if store.batches != 0 {
+		t.Error("Expected no klines to be loaded")
+	}
+}
+
+// TestImporter_GenericCSV tests a mapped OHLCV CSV with second timestamps and no close time
+func TestImporter_GenericCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bnb.csv")
+	content := "ts,o,h,l,c,vol\n1704067200,300.1,301.2,299.9,300.5,10\n1704067260,300.5,300.9,300.0,300.2,12\n"
+	os.WriteFile(path, []byte(content), 0o644)
+
+	mapping, err := ParseColumnMapping("open_time=ts,open=o,high=h,low=l,close=c,volume=vol")
+	if err != nil {
+		t.Fatalf("Failed to parse mapping: %v", err)
+	}
+
+	store := newMemoryStore()
+	result, err := NewImporter(store, store).Import(context.Background(), path, Options{
+		Symbol:   "BNBUSDT",
+		Interval: "1m",
+		Format:   FormatGeneric,
+		Mapping:  mapping,
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Rows != 2 {
+		t.Fatalf("Expected 2 klines, got %d", result.Rows)
+	}
+
+	for _, k := range store.klines {
+		if k.OpenTime != 1704067200000 && k.OpenTime != 1704067260000 {
+			t.Errorf("Expected open_time in milliseconds, got %d", k.OpenTime)
+		}
+		if k.CloseTime != k.OpenTime+59999 {
+			t.Errorf("Expected close_time derived from interval, got %d", k.CloseTime)
+		}
+	}
+}
+
+// TestImporter_GenericCSVOtherSeries tests that a file imported into one
+// series is still imported into another, and skipped for the same one
+func TestImporter_GenericCSVOtherSeries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.csv")
+	os.WriteFile(path, []byte("ts,o,h,l,c,vol\n1704067200,300.1,301.2,299.9,300.5,10\n"), 0o644)
+	mapping, err := ParseColumnMapping("open_time=ts,open=o,high=h,low=l,close=c,volume=vol")
+	if err != nil {
+		t.Fatalf("Failed to parse mapping: %v", err)
+	}
+
+	store := newMemoryStore()
+	im := NewImporter(store, store)
+	for _, tt := range []struct {
+		symbol, interval string
+		skipped          int
+	}{
+		{"BNBUSDT", "1m", 0},
+		{"SOLUSDT", "1m", 0},
+		{"BNBUSDT", "5m", 0},
+		{"BNBUSDT", "1m", 1},
+	} {
+		result, err := im.Import(context.Background(), path, Options{Symbol: tt.symbol, Interval: tt.interval, Format: FormatGeneric, Mapping: mapping})
+		if err != nil {
+			t.Fatalf("Import into %s %s failed: %v", tt.symbol, tt.interval, err)
+		}
+		if result.Skipped != tt.skipped {
+			t.Errorf("Expected %d skipped importing into %s %s, got %+v", tt.skipped, tt.symbol, tt.interval, result)
+		}
+	}
+	if len(store.klines) != 3 {
+		t.Errorf("Expected the kline in 3 series, got %d", len(store.klines))
+	}
+}
@@ -0,0 +1,214 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// maxFinishedJobs is how many finished jobs are kept for listing; older ones
+// are forgotten
+const maxFinishedJobs = 100
+
+// Job is a snapshot of an asynchronous import
+type Job struct {
+	ID         string     `json:"id"`
+	Path       string     `json:"path"`
+	Status     string     `json:"status"`
+	Progress   Progress   `json:"progress"`
+	Result     *Result    `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobManager runs imports in the background, one at a time, and tracks their progress
+type JobManager struct {
+	importer *Importer
+	jobs     map[string]*Job
+	nextID   int
+	stopped  bool
+	mu       sync.RWMutex
+	running  sync.Mutex // Serializes imports so they don't compete for the database
+
+	// ctx is canceled by Stop, which waits for the jobs in wg
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobManager creates a new JobManager instance
+func NewJobManager(importer *Importer) *JobManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobManager{
+		importer: importer,
+		jobs:     make(map[string]*Job),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start queues an import of path and returns the new job
+// The job keeps the values of ctx, such as its trace, but outlives its
+// cancellation; it is canceled by Stop instead.
+func (m *JobManager) Start(ctx context.Context, path string, opts Options) Job {
+	m.mu.Lock()
+	m.nextID++
+	job := &Job{
+		ID:        strconv.Itoa(m.nextID),
+		Path:      path,
+		Status:    JobPending,
+		CreatedAt: time.Now(),
+	}
+	m.jobs[job.ID] = job
+	snapshot := *job
+	stopped := m.stopped
+	if !stopped {
+		m.wg.Add(1)
+	}
+	m.mu.Unlock()
+
+	if stopped {
+		m.finish(job, Result{}, context.Canceled)
+		return snapshot
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(m.ctx, cancel)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		defer stop()
+		m.run(ctx, job, path, opts)
+	}()
+
+	return snapshot
+}
+
+// Stop cancels the running and queued jobs and waits for them to record
+// their outcome, until ctx is done
+func (m *JobManager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	m.stopped = true
+	m.mu.Unlock()
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get returns a snapshot of the job with the given ID
+func (m *JobManager) Get(id string) (Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns snapshots of all jobs, oldest first
+func (m *JobManager) List() []Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// run executes a queued job and records its outcome
+func (m *JobManager) run(ctx context.Context, job *Job, path string, opts Options) {
+	m.running.Lock()
+	defer m.running.Unlock()
+
+	m.update(job, func(j *Job) { j.Status = JobRunning })
+
+	opts.OnProgress = func(p Progress) {
+		m.update(job, func(j *Job) { j.Progress = p })
+	}
+
+	result, err := m.importer.Import(ctx, path, opts)
+	status := m.finish(job, result, err)
+
+	switch status {
+	case JobCanceled:
+		importerLog.WarnContext(ctx, "Import job canceled", "job_id", job.ID, "files", result.Files, "klines", result.Rows)
+	case JobFailed:
+		importerLog.ErrorContext(ctx, "Import job failed", "job_id", job.ID, "error", err)
+	default:
+		importerLog.InfoContext(ctx, "Import job completed", "job_id", job.ID, "files", result.Files, "skipped", result.Skipped, "klines", result.Rows)
+	}
+}
+
+// finish records the outcome of a job, forgets the oldest finished jobs
+// beyond maxFinishedJobs and returns the final status
+func (m *JobManager) finish(job *Job, result Result, err error) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Result = &result
+	switch {
+	case err == nil:
+		job.Status = JobCompleted
+	case errors.Is(err, context.Canceled) && m.ctx.Err() != nil:
+		job.Status = JobCanceled
+		job.Error = "import stopped by server shutdown"
+	default:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	}
+
+	var finished []*Job
+	for _, j := range m.jobs {
+		if j.FinishedAt != nil {
+			finished = append(finished, j)
+		}
+	}
+	if excess := len(finished) - maxFinishedJobs; excess > 0 {
+		sort.Slice(finished, func(i, k int) bool {
+			return finished[i].FinishedAt.Before(*finished[k].FinishedAt)
+		})
+		for _, j := range finished[:excess] {
+			delete(m.jobs, j.ID)
+		}
+	}
+	return job.Status
+}
+
+// update applies fn to a job under the manager lock
+func (m *JobManager) update(job *Job, fn func(*Job)) {
+	m.mu.Lock()
+	fn(job)
+	m.mu.Unlock()
+}
@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/models"
+)
+
+// blockingStore blocks writes until they are canceled
+type blockingStore struct {
+	*memoryStore
+	started chan struct{}
+}
+
+func (b *blockingStore) BulkUpsertKlines(ctx context.Context, klines []models.Kline) error {
+	close(b.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// waitForJob polls a job until it finishes
+func waitForJob(t *testing.T, m *JobManager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := m.Get(id); ok && job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for job %s", id)
+	return Job{}
+}
+
+// TestJobManager_Stop tests that stopping cancels a running import, which
+// outlives the request that started it, and waits for its outcome
+func TestJobManager_Stop(t *testing.T) {
+	path := writeArchive(t, t.TempDir(), "BTCUSDT-1m-2024-01-01.zip", binanceArchiveCSV)
+	store := &blockingStore{memoryStore: newMemoryStore(), started: make(chan struct{})}
+	m := NewJobManager(NewImporter(store, store))
+
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+	job := m.Start(requestCtx, path, Options{})
+	cancelRequest()
+	select {
+	case <-store.started:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the import to start")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop jobs: %v", err)
+	}
+	if got, _ := m.Get(job.ID); got.Status != JobCanceled || got.FinishedAt == nil {
+		t.Errorf("Expected the job to be canceled, got %+v", got)
+	}
+
+	late := m.Start(context.Background(), path, Options{})
+	if got, _ := m.Get(late.ID); got.Status != JobCanceled {
+		t.Errorf("Expected a job started after Stop to be canceled, got %+v", got)
+	}
+}
+
+// TestJobManager_ForgetsOldJobs tests that only the most recent finished
+// jobs are kept
+func TestJobManager_ForgetsOldJobs(t *testing.T) {
+	m := NewJobManager(NewImporter(newMemoryStore(), newMemoryStore()))
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	var last Job
+	for range maxFinishedJobs + 5 {
+		last = m.Start(context.Background(), missing, Options{})
+		waitForJob(t, m, last.ID)
+	}
+
+	jobs := m.List()
+	if len(jobs) != maxFinishedJobs {
+		t.Fatalf("Expected %d jobs to be kept, got %d", maxFinishedJobs, len(jobs))
+	}
+	for id := 1; id <= 5; id++ {
+		if _, ok := m.Get(strconv.Itoa(id)); ok {
+			t.Errorf("Expected job %d to be forgotten", id)
+		}
+	}
+	if _, ok := m.Get(last.ID); !ok {
+		t.Error("Expected the latest job to be kept")
+	}
+}
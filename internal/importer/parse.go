@@ -0,0 +1,354 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/models"
+)
+
+// Supported input formats
+const (
+	// FormatBinance is the headerless kline CSV published at data.binance.vision
+	FormatBinance = "binance"
+	// FormatGeneric is any OHLCV CSV with a header row, read through a ColumnMapping
+	FormatGeneric = "generic"
+)
+
+// Supported timestamp units for input files
+const (
+	TimeUnitAuto         = "auto"
+	TimeUnitSeconds      = "s"
+	TimeUnitMilliseconds = "ms"
+	TimeUnitMicroseconds = "us"
+)
+
+// ColumnMapping maps kline fields to header names in a generic OHLCV CSV
+// CloseTime is optional and derived from the interval when empty
+type ColumnMapping struct {
+	OpenTime  string `json:"open_time"`
+	CloseTime string `json:"close_time,omitempty"`
+	Open      string `json:"open"`
+	High      string `json:"high"`
+	Low       string `json:"low"`
+	Close     string `json:"close"`
+	Volume    string `json:"volume"`
+}
+
+// DefaultColumnMapping returns a mapping whose column names equal the field names
+func DefaultColumnMapping() ColumnMapping {
+	return ColumnMapping{
+		OpenTime:  "open_time",
+		CloseTime: "close_time",
+		Open:      "open",
+		High:      "high",
+		Low:       "low",
+		Close:     "close",
+		Volume:    "volume",
+	}
+}
+
+// ParseColumnMapping parses "field=column" pairs separated by commas,
+// e.g. "open_time=timestamp,volume=vol"; unspecified fields keep their defaults
+func ParseColumnMapping(s string) (ColumnMapping, error) {
+	mapping := DefaultColumnMapping()
+	if s == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || column == "" {
+			return mapping, fmt.Errorf("invalid column mapping %q, expected field=column", pair)
+		}
+		switch field {
+		case "open_time":
+			mapping.OpenTime = column
+		case "close_time":
+			mapping.CloseTime = column
+		case "open":
+			mapping.Open = column
+		case "high":
+			mapping.High = column
+		case "low":
+			mapping.Low = column
+		case "close":
+			mapping.Close = column
+		case "volume":
+			mapping.Volume = column
+		default:
+			return mapping, fmt.Errorf("unknown kline field in column mapping: %s", field)
+		}
+	}
+
+	return mapping, nil
+}
+
+// ParseBinanceFileName extracts symbol and interval from a Binance archive name
+// such as "BTCUSDT-1m-2024-01.zip" or "ETHUSDT-5m-2024-01-15.csv"
+func ParseBinanceFileName(name string) (symbol, interval string, ok bool) {
+	parts := strings.Split(filepath.Base(name), "-")
+	if len(parts) < 3 {
+		return "", "", false
+	}
+	return strings.ToUpper(parts[0]), parts[1], true
+}
+
+// rowParser turns CSV records into klines
+type rowParser interface {
+	parse(record []string) (kline models.Kline, skip bool, err error)
+}
+
+// newRowParser creates the parser for a format
+// Generic CSVs consume their header row here to resolve the column mapping
+func newRowParser(r *csv.Reader, opts Options, symbol, interval string) (rowParser, error) {
+	intervalMs := int64(0)
+	if d, err := models.IntervalDuration(interval); err == nil {
+		intervalMs = d.Milliseconds()
+	}
+
+	switch opts.Format {
+	case FormatBinance, "":
+		return &binanceParser{symbol: symbol, interval: interval}, nil
+	case FormatGeneric:
+		header, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		return newGenericParser(header, opts, symbol, interval, intervalMs)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", opts.Format)
+	}
+}
+
+// binanceParser parses rows of the Binance kline archive layout:
+// open_time, open, high, low, close, volume, close_time, quote_volume, count, ...
+type binanceParser struct {
+	symbol   string
+	interval string
+	seenData bool
+}
+
+func (p *binanceParser) parse(record []string) (models.Kline, bool, error) {
+	if len(record) < 7 {
+		return models.Kline{}, false, fmt.Errorf("expected at least 7 columns, got %d", len(record))
+	}
+
+	openTime, err := parseTime(record[0], TimeUnitAuto)
+	if err != nil {
+		// Newer archives start with a header row
+		if !p.seenData {
+			return models.Kline{}, true, nil
+		}
+		return models.Kline{}, false, fmt.Errorf("invalid open_time: %w", err)
+	}
+	p.seenData = true
+
+	closeTime, err := parseTime(record[6], TimeUnitAuto)
+	if err != nil {
+		return models.Kline{}, false, fmt.Errorf("invalid close_time: %w", err)
+	}
+
+	prices, err := parseFloats(record[1:6])
+	if err != nil {
+		return models.Kline{}, false, err
+	}
+
+	return models.Kline{
+		Symbol:     p.symbol,
+		Interval:   p.interval,
+		OpenTime:   openTime,
+		CloseTime:  closeTime,
+		OpenPrice:  prices[0],
+		HighPrice:  prices[1],
+		LowPrice:   prices[2],
+		ClosePrice: prices[3],
+		Volume:     prices[4],
+	}, false, nil
+}
+
+// genericParser parses rows using column positions resolved from the header
+type genericParser struct {
+	symbol     string
+	interval   string
+	intervalMs int64
+	timeUnit   string
+	openTime   int
+	closeTime  int // -1 when derived from the interval
+	values     [5]int
+}
+
+func newGenericParser(header []string, opts Options, symbol, interval string, intervalMs int64) (*genericParser, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	lookup := func(column string) (int, error) {
+		i, ok := index[column]
+		if !ok {
+			return 0, fmt.Errorf("column %q not found in CSV header", column)
+		}
+		return i, nil
+	}
+
+	mapping := opts.Mapping
+	p := &genericParser{
+		symbol:     symbol,
+		interval:   interval,
+		intervalMs: intervalMs,
+		timeUnit:   opts.TimeUnit,
+		closeTime:  -1,
+	}
+
+	var err error
+	if p.openTime, err = lookup(mapping.OpenTime); err != nil {
+		return nil, err
+	}
+	for i, column := range []string{mapping.Open, mapping.High, mapping.Low, mapping.Close, mapping.Volume} {
+		if p.values[i], err = lookup(column); err != nil {
+			return nil, err
+		}
+	}
+
+	if mapping.CloseTime != "" {
+		if i, ok := index[mapping.CloseTime]; ok {
+			p.closeTime = i
+		}
+	}
+	if p.closeTime < 0 && intervalMs == 0 {
+		return nil, fmt.Errorf("close_time column is required for interval %s", interval)
+	}
+
+	return p, nil
+}
+
+func (p *genericParser) parse(record []string) (models.Kline, bool, error) {
+	field := func(i int) (string, error) {
+		if i >= len(record) {
+			return "", fmt.Errorf("expected at least %d columns, got %d", i+1, len(record))
+		}
+		return record[i], nil
+	}
+
+	raw, err := field(p.openTime)
+	if err != nil {
+		return models.Kline{}, false, err
+	}
+	openTime, err := parseTime(raw, p.timeUnit)
+	if err != nil {
+		return models.Kline{}, false, fmt.Errorf("invalid open_time: %w", err)
+	}
+
+	closeTime := openTime + p.intervalMs - 1
+	if p.closeTime >= 0 {
+		if raw, err = field(p.closeTime); err != nil {
+			return models.Kline{}, false, err
+		}
+		if closeTime, err = parseTime(raw, p.timeUnit); err != nil {
+			return models.Kline{}, false, fmt.Errorf("invalid close_time: %w", err)
+		}
+	}
+
+	var fields [5]string
+	for i, column := range p.values {
+		if fields[i], err = field(column); err != nil {
+			return models.Kline{}, false, err
+		}
+	}
+	prices, err := parseFloats(fields[:])
+	if err != nil {
+		return models.Kline{}, false, err
+	}
+
+	return models.Kline{
+		Symbol:     p.symbol,
+		Interval:   p.interval,
+		OpenTime:   openTime,
+		CloseTime:  closeTime,
+		OpenPrice:  prices[0],
+		HighPrice:  prices[1],
+		LowPrice:   prices[2],
+		ClosePrice: prices[3],
+		Volume:     prices[4],
+	}, false, nil
+}
+
+// parseTime parses a timestamp into milliseconds
+// Integer values are scaled by unit; with TimeUnitAuto the unit is inferred from
+// the magnitude. RFC 3339 strings are accepted regardless of unit.
+func parseTime(s, unit string) (int64, error) {
+	s = strings.TrimSpace(s)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		t, terr := time.Parse(time.RFC3339, s)
+		if terr != nil {
+			return 0, errors.New("not an integer timestamp or RFC 3339 time: " + s)
+		}
+		return t.UnixMilli(), nil
+	}
+
+	switch unit {
+	case TimeUnitSeconds:
+		return v * 1000, nil
+	case TimeUnitMicroseconds:
+		return v / 1000, nil
+	case TimeUnitMilliseconds:
+		return v, nil
+	default:
+		// Seconds stay below 1e11 until the year 5138, and milliseconds stay
+		// below 1e15 until the year 33658
+		switch {
+		case v >= 1e15:
+			return v / 1000, nil
+		case v < 1e11:
+			return v * 1000, nil
+		default:
+			return v, nil
+		}
+	}
+}
+
+// parseFloats parses open, high, low, close and volume values
+func parseFloats(fields []string) ([]float64, error) {
+	names := [...]string{"open", "high", "low", "close", "volume"}
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", names[i], f)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// readRecords reads CSV records and emits parsed klines to fn
+func readRecords(r *csv.Reader, parser rowParser, fn func(models.Kline) error) error {
+	for n := 1; ; n++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		kline, skip, err := parser.parse(record)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		if skip {
+			continue
+		}
+		if err := fn(kline); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package logging provides structured, leveled logging on top of log/slog.
+//
+// Every package logs through a component logger obtained from For, whose level
+// can be set separately in the log configuration. Loggers may be created before
+// Setup runs; they pick up the configured output and levels once it does.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"crypto-monitor/internal/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Components with their own configurable level
+const (
+	App        = "app"
+	Binance    = "binance"
+	Bus        = "bus"
+	Collector  = "collector"
+	Database   = "database"
+	GRPC       = "grpc"
+	HTTP       = "http"
+	Importer   = "importer"
+	Repository = "repository"
+	WebSocket  = "websocket"
+	Writer     = "writer"
+)
+
+var (
+	// output is the handler every component logger writes to
+	output atomic.Pointer[slog.Handler]
+
+	mu           sync.Mutex
+	defaultLevel = slog.LevelInfo
+	overrides    = map[string]slog.Level{}
+	levels       = map[string]*slog.LevelVar{}
+
+	sampleEvery atomic.Int64
+)
+
+func init() {
+	h := slog.Default().Handler()
+	output.Store(&h)
+	sampleEvery.Store(1)
+}
+
+// Setup configures the output format, levels and sampling of all loggers and
+// routes the standard library logger through the app component
+func Setup(cfg config.LogConfig, w io.Writer) error {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	parsed := make(map[string]slog.Level, len(cfg.Components))
+	for component, value := range cfg.Components {
+		l, err := ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("log level of %s: %w", component, err)
+		}
+		parsed[component] = l
+	}
+
+	// Levels are filtered per component, so the output accepts everything
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler
+	switch cfg.Format {
+	case config.LogFormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		h = slog.NewJSONHandler(w, opts)
+	}
+
+	mu.Lock()
+	defaultLevel = level
+	overrides = parsed
+	for component, v := range levels {
+		v.Set(levelOf(component))
+	}
+	mu.Unlock()
+
+	output.Store(&h)
+	sampleEvery.Store(int64(max(cfg.SampleEvery, 1)))
+	slog.SetDefault(For(App))
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q, use debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// levelOf returns the configured level of a component; mu must be held
+func levelOf(component string) slog.Level {
+	if level, ok := overrides[component]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+// For returns the logger of a component
+func For(component string) *slog.Logger {
+	mu.Lock()
+	level, ok := levels[component]
+	if !ok {
+		level = new(slog.LevelVar)
+		level.Set(levelOf(component))
+		levels[component] = level
+	}
+	mu.Unlock()
+
+	h := &handler{level: level}
+	return slog.New(h).With("component", component)
+}
+
+// SampleEvery is the configured sampling rate of per-kline logs
+func SampleEvery() int {
+	return int(sampleEvery.Load())
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose log records carry the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// handler filters records by component level, adds the request ID and trace
+// IDs from the context and forwards them to the current output
+// Attributes and groups are replayed onto the output, which is cached until
+// Setup replaces it.
+type handler struct {
+	level *slog.LevelVar
+	ops   []func(slog.Handler) slog.Handler
+
+	cache atomic.Pointer[cachedHandler]
+}
+
+type cachedHandler struct {
+	output  *slog.Handler
+	handler slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.resolve().Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{level: h.level, ops: append(ops, op)}
+}
+
+// resolve returns the output with this handler's attributes and groups applied
+func (h *handler) resolve() slog.Handler {
+	out := output.Load()
+	if cached := h.cache.Load(); cached != nil && cached.output == out {
+		return cached.handler
+	}
+
+	resolved := *out
+	for _, op := range h.ops {
+		resolved = op(resolved)
+	}
+	h.cache.Store(&cachedHandler{output: out, handler: resolved})
+	return resolved
+}
+
+// Sampler lets through the first of every n events
+type Sampler struct {
+	n     uint64
+	count atomic.Uint64
+}
+
+// NewSampler creates a sampler passing one in every n events
+func NewSampler(n int) *Sampler {
+	return &Sampler{n: uint64(max(n, 1))}
+}
+
+// Allow reports whether the current event should be logged
+func (s *Sampler) Allow() bool {
+	return (s.count.Add(1)-1)%s.n == 0
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"crypto-monitor/internal/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// decodeLines parses JSON log output into records
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid JSON log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestSetup_ComponentLevels tests per-component filtering, including loggers created before Setup
+func TestSetup_ComponentLevels(t *testing.T) {
+	binance := For(Binance).With("stream_id", "btcusdt@kline_1m#1")
+
+	var buf bytes.Buffer
+	err := Setup(config.LogConfig{
+		Level:      "warn",
+		Format:     config.LogFormatJSON,
+		Components: map[string]string{Binance: "debug"},
+	}, &buf)
+	if err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+
+	websocket := For(WebSocket)
+	binance.Debug("binance debug")
+	websocket.Info("websocket info")
+	websocket.Warn("websocket warn")
+
+	records := decodeLines(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d: %s", len(records), buf.String())
+	}
+	if records[0]["msg"] != "binance debug" || records[0]["component"] != Binance || records[0]["stream_id"] != "btcusdt@kline_1m#1" {
+		t.Errorf("Unexpected binance record: %v", records[0])
+	}
+	if records[1]["msg"] != "websocket warn" || records[1]["component"] != WebSocket {
+		t.Errorf("Unexpected websocket record: %v", records[1])
+	}
+}
+
+// TestHandler_RequestID tests that the request ID of the context is added to records
+func TestHandler_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(config.LogConfig{Level: "info", Format: config.LogFormatJSON}, &buf); err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	For(HTTP).InfoContext(ctx, "with id")
+	For(HTTP).Info("without id")
+
+	records := decodeLines(t, &buf)
+	if records[0]["request_id"] != "req-1" {
+		t.Errorf("Expected request_id on record, got %v", records[0])
+	}
+	if _, ok := records[1]["request_id"]; ok {
+		t.Errorf("Expected no request_id without one in the context, got %v", records[1])
+	}
+}
+
+// TestHandler_TraceID tests that the span of the context is added to records
+func TestHandler_TraceID(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(config.LogConfig{Level: "info", Format: config.LogFormatJSON}, &buf); err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	For(HTTP).InfoContext(ctx, "traced")
+
+	records := decodeLines(t, &buf)
+	if records[0]["trace_id"] != sc.TraceID().String() || records[0]["span_id"] != sc.SpanID().String() {
+		t.Errorf("Expected trace_id and span_id on record, got %v", records[0])
+	}
+}
+
+// TestSetup_InvalidLevel tests that an unknown level is rejected
+func TestSetup_InvalidLevel(t *testing.T) {
+	err := Setup(config.LogConfig{Level: "info", Components: map[string]string{HTTP: "loud"}}, &bytes.Buffer{})
+	if err == nil {
+		t.Error("Expected error for invalid component level")
+	}
+}
+
+// TestSampler tests that one in every n events is allowed, starting with the first
+func TestSampler(t *testing.T) {
+	sampler := NewSampler(3)
+	var allowed []int
+	for i := 0; i < 7; i++ {
+		if sampler.Allow() {
+			allowed = append(allowed, i)
+		}
+	}
+	if len(allowed) != 3 || allowed[0] != 0 || allowed[1] != 3 || allowed[2] != 6 {
+		t.Errorf("Expected events 0, 3 and 6 to be allowed, got %v", allowed)
+	}
+}
+
+// TestComponents_MatchConfig tests that every component can be configured
+func TestComponents_MatchConfig(t *testing.T) {
+	components := []string{App, Binance, Bus, Collector, Database, GRPC, HTTP, Importer, Repository, WebSocket, Writer}
+	if !slices.Equal(components, config.LogComponents) {
+		t.Errorf("Components %v do not match config.LogComponents %v", components, config.LogComponents)
+	}
+}
@@ -0,0 +1,255 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "crypto_monitor"
+
+// Registry holds every metric of the process, including Go runtime and
+// process metrics
+var Registry = prometheus.NewRegistry()
+
+// Ingestion
+var (
+	// StreamMessages counts the frames read from each upstream stream,
+	// including updates of klines that are still open
+	StreamMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_messages_total",
+		Help:      "Messages read from the exchange per upstream stream.",
+	}, []string{"symbol", "interval"})
+
+	// StreamClosedKlines counts the closed klines received per upstream stream
+	StreamClosedKlines = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_closed_klines_total",
+		Help:      "Closed klines received from the exchange per upstream stream.",
+	}, []string{"symbol", "interval"})
+
+	// StreamReconnects counts reconnects per upstream stream
+	StreamReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_reconnects_total",
+		Help:      "Reconnects of the upstream stream of a series.",
+	}, []string{"symbol", "interval"})
+
+	// BusPublishDrops counts collected klines dropped because the bus
+	// publish queue was full
+	BusPublishDrops = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bus_publish_drops_total",
+		Help:      "Collected klines dropped because the bus publish queue was full.",
+	})
+)
+
+// Database
+var (
+	// DBWriteDuration observes the latency of kline writes by operation
+	DBWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_write_duration_seconds",
+		Help:      "Latency of kline writes to the database.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation"})
+
+	// DBWriteFailures counts failed kline writes by operation, including
+	// writes skipped because the database was unreachable
+	DBWriteFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_write_failures_total",
+		Help:      "Kline writes that failed or were skipped because the database was unavailable.",
+	}, []string{"operation"})
+)
+
+// Kline writer
+var (
+	// WriterQueueDepth is the number of klines in the in-memory write queue,
+	// sampled every flush interval
+	WriterQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "writer_queue_depth",
+		Help:      "Klines waiting in the in-memory write queue.",
+	})
+
+	// WriterJournalPending is the number of klines in the on-disk journal
+	// waiting to be replayed
+	WriterJournalPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "writer_journal_pending",
+		Help:      "Klines in the on-disk journal waiting to be replayed to the database.",
+	})
+
+	// WriterDatabaseDown is 1 while the writer journals klines because the
+	// database is unreachable
+	WriterDatabaseDown = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "writer_database_down",
+		Help:      "Whether the kline writer is journaling because the database is unreachable.",
+	})
+
+	// WriterSpilled counts klines appended to the journal
+	WriterSpilled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "writer_spilled_klines_total",
+		Help:      "Klines appended to the on-disk journal instead of written to the database.",
+	})
+
+	// WriterDropped counts klines lost by the writer
+	WriterDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "writer_dropped_klines_total",
+		Help:      "Klines lost because neither the write queue nor the journal could take them.",
+	})
+)
+
+// WebSocket
+var (
+	// WebSocketClients is the number of connected websocket clients
+	WebSocketClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_clients",
+		Help:      "Connected websocket clients.",
+	})
+
+	// WebSocketSendDrops counts messages dropped because a client's send
+	// queue was full
+	WebSocketSendDrops = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_send_drops_total",
+		Help:      "Messages dropped because a websocket client's send queue was full.",
+	})
+
+	// WebSocketClientSendDrops observes the messages each client dropped on a
+	// full send queue, recorded when it disconnects
+	WebSocketClientSendDrops = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "websocket_client_send_drops",
+		Help:      "Messages a websocket client dropped on a full send queue over its connection.",
+		Buckets:   []float64{0, 1, 10, 100, 1000, 10000},
+	})
+
+	// WebSocketSlowConsumers counts warnings sent to clients falling behind
+	WebSocketSlowConsumers = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_slow_consumers_total",
+		Help:      "Warnings sent to websocket clients falling behind their send queue.",
+	})
+
+	// WebSocketEvictions counts slow clients disconnected, by "lag" or "drops"
+	WebSocketEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_evictions_total",
+		Help:      "Slow websocket clients disconnected, by the threshold they exceeded.",
+	}, []string{"reason"})
+
+	// WebSocketHubDrops counts updates dropped because a fan-out worker's
+	// queue was full
+	WebSocketHubDrops = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_hub_drops_total",
+		Help:      "Kline updates dropped because a websocket fan-out worker's queue was full.",
+	})
+
+	// WebSocketFanoutDuration observes the time to hand one update to all
+	// subscribed clients
+	WebSocketFanoutDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "websocket_fanout_duration_seconds",
+		Help:      "Time to hand a kline update to every subscribed websocket client.",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	})
+
+	// WebSocketFrames counts the data frames written to clients that
+	// negotiated permessage-deflate, by whether they were compressed
+	WebSocketFrames = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_frames_total",
+		Help:      "Data frames written to websocket clients that negotiated compression, by whether they were compressed.",
+	}, []string{"compressed"})
+
+	// WebSocketCompressionInputBytes and WebSocketCompressionOutputBytes count
+	// the payload and the wire bytes of compressed frames; their ratio is the
+	// compression ratio
+	WebSocketCompressionInputBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_compression_input_bytes_total",
+		Help:      "Payload bytes of compressed websocket frames.",
+	})
+	WebSocketCompressionOutputBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_compression_output_bytes_total",
+		Help:      "Bytes written to the connection for compressed websocket frames.",
+	})
+
+	// WebSocketWriteDuration observes the time to write one data frame to a
+	// client that negotiated permessage-deflate, by whether it was compressed
+	// Both include waiting on the connection, so the difference between them
+	// is the cost of deflating.
+	WebSocketWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "websocket_write_duration_seconds",
+		Help:      "Time to write a data frame to a websocket client that negotiated compression, including compressing it, by whether it was compressed.",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	}, []string{"compressed"})
+)
+
+// HTTP
+var (
+	// HTTPRequestDuration observes request latency by method, route and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RateLimited counts requests, connections and messages refused by a
+	// per-client limit: "rest", "ws_connections", "ws_subscriptions" or "ws_messages"
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests, connections and messages refused by a per-client limit.",
+	}, []string{"limit"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		StreamMessages,
+		StreamClosedKlines,
+		StreamReconnects,
+		BusPublishDrops,
+		DBWriteDuration,
+		DBWriteFailures,
+		WriterQueueDepth,
+		WriterJournalPending,
+		WriterDatabaseDown,
+		WriterSpilled,
+		WriterDropped,
+		WebSocketClients,
+		WebSocketSendDrops,
+		WebSocketClientSendDrops,
+		WebSocketSlowConsumers,
+		WebSocketEvictions,
+		WebSocketHubDrops,
+		WebSocketFanoutDuration,
+		WebSocketFrames,
+		WebSocketCompressionInputBytes,
+		WebSocketCompressionOutputBytes,
+		WebSocketWriteDuration,
+		HTTPRequestDuration,
+		RateLimited,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey grants an API client the listed scopes
+// Only the SHA-256 hash of the key is stored; the key itself is shown once,
+// when it is created. Prefix is the start of the key, kept to tell keys apart.
+type APIKey struct {
+	ID         uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"type:text;not null;serializer:json" json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// TableName specifies the table name for GORM
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// intervalDurations maps Binance kline intervals to their fixed durations
+// "1M" is omitted because calendar months have no fixed length
+var intervalDurations = map[string]time.Duration{
+	"1s":  time.Second,
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// IntervalDuration returns the length of a kline interval such as "1m" or "4h"
+func IntervalDuration(interval string) (time.Duration, error) {
+	d, ok := intervalDurations[interval]
+	if !ok {
+		return 0, fmt.Errorf("unsupported interval: %s", interval)
+	}
+	return d, nil
+}
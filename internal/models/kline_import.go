@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// KlineImport records a kline archive that has been fully imported
+// Re-running an import skips files whose checksum is already recorded for the
+// same symbol and interval; a generic CSV may be loaded into several series.
+type KlineImport struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	FileName  string    `gorm:"type:varchar(255);not null" json:"file_name"`
+	Checksum  string    `gorm:"type:char(64);not null;uniqueIndex:idx_kline_imports_target,priority:1" json:"checksum"`
+	Symbol    string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_kline_imports_target,priority:2" json:"symbol"`
+	Interval  string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_kline_imports_target,priority:3" json:"interval"`
+	Rows      int64     `gorm:"not null" json:"rows"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (KlineImport) TableName() string {
+	return "kline_imports"
+}
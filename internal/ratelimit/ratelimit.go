@@ -0,0 +1,133 @@
+// Package ratelimit keeps a token bucket per client and caps the number of
+// resources, such as websocket connections, a client holds at once.
+//
+// Clients are identified by an opaque string, normally their API key or
+// address. Buckets of idle clients are dropped once they would have refilled,
+// so memory is bounded by the clients active within a refill period.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sweepInterval is how often idle buckets are looked for
+const sweepInterval = time.Minute
+
+// bucket is the token bucket of a client
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter rate limits each client with its own token bucket
+type Limiter struct {
+	limit rate.Limit
+	burst int
+	// idle is how long an unused bucket takes to refill, after which it is
+	// no different from a new one
+	idle time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter creates a Limiter refilling perSecond tokens a second into
+// buckets holding up to burst tokens
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		limit:     rate.Limit(perSecond),
+		burst:     burst,
+		idle:      time.Duration(float64(burst) / perSecond * float64(time.Second)),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of client
+// When the bucket is empty it returns false and how long until a token is
+// available.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	return l.allowAt(client, time.Now())
+}
+
+func (l *Limiter) allowAt(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[client] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep drops buckets that have refilled since they were last used
+func (l *Limiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.idle {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Counter caps how many of a resource each client holds at once
+type Counter struct {
+	max int
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewCounter creates a Counter allowing max concurrent holds per client;
+// 0 allows any number
+func NewCounter(max int) *Counter {
+	return &Counter{max: max, counts: make(map[string]int)}
+}
+
+// Max returns the number of holds allowed per client, 0 for unlimited
+func (c *Counter) Max() int {
+	return c.max
+}
+
+// Acquire takes a hold for client, reporting false if it already holds the
+// maximum; release must be called exactly once for every successful hold
+func (c *Counter) Acquire(client string) (release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.max > 0 && c.counts[client] >= c.max {
+		return nil, false
+	}
+	c.counts[client]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.counts[client]--; c.counts[client] <= 0 {
+				delete(c.counts, client)
+			}
+		})
+	}, true
+}
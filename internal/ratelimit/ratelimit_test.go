@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLimiter_Allow tests that each client gets its own bucket and a retry hint when it is empty
+func TestLimiter_Allow(t *testing.T) {
+	l := NewLimiter(2, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allowAt("a", now); !ok {
+			t.Fatalf("Expected request %d within the burst to be allowed", i+1)
+		}
+	}
+	ok, retry := l.allowAt("a", now)
+	if ok {
+		t.Fatal("Expected the request past the burst to be limited")
+	}
+	if retry <= 0 || retry > 500*time.Millisecond {
+		t.Errorf("Expected a retry hint of at most one token interval, got %v", retry)
+	}
+
+	if ok, _ := l.allowAt("b", now); !ok {
+		t.Error("Expected another client to have its own bucket")
+	}
+
+	if ok, _ := l.allowAt("a", now.Add(retry)); !ok {
+		t.Error("Expected a request after the retry hint to be allowed")
+	}
+}
+
+// TestLimiter_Sweep tests that idle buckets are dropped once they have refilled
+func TestLimiter_Sweep(t *testing.T) {
+	l := NewLimiter(10, 10)
+	now := time.Now()
+	l.allowAt("idle", now)
+	l.allowAt("active", now.Add(sweepInterval-time.Millisecond))
+
+	l.allowAt("active", now.Add(sweepInterval))
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("Expected the idle bucket to be dropped")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("Expected the active bucket to be kept")
+	}
+}
+
+// TestCounter tests capping concurrent holds per client
+func TestCounter(t *testing.T) {
+	c := NewCounter(2)
+	first, ok1 := c.Acquire("a")
+	_, ok2 := c.Acquire("a")
+	if !ok1 || !ok2 {
+		t.Fatal("Expected two holds to be allowed")
+	}
+	if _, ok := c.Acquire("a"); ok {
+		t.Error("Expected the third hold to be refused")
+	}
+	if _, ok := c.Acquire("b"); !ok {
+		t.Error("Expected another client to be counted separately")
+	}
+
+	first()
+	first()
+	if _, ok := c.Acquire("a"); !ok {
+		t.Error("Expected a released hold to be reusable")
+	}
+	if _, ok := c.Acquire("a"); ok {
+		t.Error("Expected a repeated release to count once")
+	}
+
+	unlimited := NewCounter(0)
+	for i := 0; i < 100; i++ {
+		if _, ok := unlimited.Acquire("a"); !ok {
+			t.Fatal("Expected no cap with a maximum of 0")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"crypto-monitor/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository handles database operations for APIKey records
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository instance
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// CreateAPIKey stores a new API key
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	if r.db == nil {
+		return fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+// ListAPIKeys returns every API key, revoked ones included, oldest first
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	if r.db == nil {
+		return nil, fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	var keys []models.APIKey
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// FindAPIKeyByHash returns the active API key with the given hash, or nil if
+// there is none or it has been revoked
+func (r *APIKeyRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if r.db == nil {
+		return nil, fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ? AND revoked_at IS NULL", hash).Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API key: %w", err)
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey marks an API key as revoked
+// It reports false if no active key has the given ID.
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id uint64) (bool, error) {
+	if r.db == nil {
+		return false, fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TouchAPIKey records when an API key was last used
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id uint64, at time.Time) error {
+	if r.db == nil {
+		return fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	if err := r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error; err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"crypto-monitor/internal/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestAPIKeyRepository_Lifecycle tests creating, finding, touching and revoking an API key
+func TestAPIKeyRepository_Lifecycle(t *testing.T) {
+	klineRepo := setupTestDB(t)
+	if klineRepo == nil {
+		return
+	}
+	repo := NewAPIKeyRepository(klineRepo.db)
+
+	hash := fmt.Sprintf("%064x", time.Now().UnixNano())
+	key := &models.APIKey{Name: "test", Prefix: "cm_test", KeyHash: hash, Scopes: []string{"market:read"}}
+	if err := repo.CreateAPIKey(t.Context(), key); err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	found, err := repo.FindAPIKeyByHash(t.Context(), hash)
+	if err != nil {
+		t.Fatalf("Failed to find API key: %v", err)
+	}
+	if found == nil || found.ID != key.ID || len(found.Scopes) != 1 || found.Scopes[0] != "market:read" {
+		t.Fatalf("Expected the created key with its scopes, got %+v", found)
+	}
+
+	if err := repo.TouchAPIKey(t.Context(), key.ID, time.Now()); err != nil {
+		t.Fatalf("Failed to touch API key: %v", err)
+	}
+
+	revoked, err := repo.RevokeAPIKey(t.Context(), key.ID)
+	if err != nil || !revoked {
+		t.Fatalf("Expected the key to be revoked, got %v, %v", revoked, err)
+	}
+	if revoked, _ := repo.RevokeAPIKey(t.Context(), key.ID); revoked {
+		t.Error("Expected revoking twice to report no active key")
+	}
+	if found, err := repo.FindAPIKeyByHash(t.Context(), hash); err != nil || found != nil {
+		t.Errorf("Expected revoked key not to be found, got %+v, %v", found, err)
+	}
+}
@@ -1,19 +1,46 @@
 package repository
 
 import (
+	"context"
 	"crypto-monitor/internal/models"
 	"errors"
 	"fmt"
-	"log"
+	"time"
 
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 const (
 	errDBConnectionUnavailable = "database connection is not available"
+
+	// copyThreshold is the batch size above which COPY outperforms multi-row INSERT
+	copyThreshold = 5000
+)
+
+// Write operations as labelled in the database metrics
+const (
+	opUpsert      = "upsert"
+	opBatchUpsert = "batch_upsert"
+	opCopyUpsert  = "copy_upsert"
 )
 
+// observeWrite records the latency of a kline write and counts it as failed
+// when *err is set; call it deferred with the start time of the write
+func observeWrite(operation string, start time.Time, err *error) {
+	metrics.DBWriteDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		metrics.DBWriteFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+var repositoryLog = logging.For(logging.Repository)
+
 // KlineRepository handles database operations for Kline models
 type KlineRepository struct {
 	db *gorm.DB
@@ -26,12 +53,12 @@ func NewKlineRepository(db *gorm.DB) *KlineRepository {
 
 // CreateKline creates a new kline record in the database
 // Returns error if the kline already exists (based on symbol, interval, open_time)
-func (r *KlineRepository) CreateKline(kline *models.Kline) error {
+func (r *KlineRepository) CreateKline(ctx context.Context, kline *models.Kline) error {
 	if r.db == nil {
 		return fmt.Errorf(errDBConnectionUnavailable)
 	}
 
-	if err := r.db.Create(kline).Error; err != nil {
+	if err := r.db.WithContext(ctx).Create(kline).Error; err != nil {
 		// Check if it's a unique constraint violation
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			return fmt.Errorf("kline already exists: %w", err)
@@ -44,14 +71,15 @@ func (r *KlineRepository) CreateKline(kline *models.Kline) error {
 
 // CreateOrUpdateKline creates a new kline or updates existing one using UPSERT logic
 // Uses ON CONFLICT DO UPDATE for PostgreSQL
-func (r *KlineRepository) CreateOrUpdateKline(kline *models.Kline) error {
+func (r *KlineRepository) CreateOrUpdateKline(ctx context.Context, kline *models.Kline) (err error) {
+	defer observeWrite(opUpsert, time.Now(), &err)
 	if r.db == nil {
 		return fmt.Errorf(errDBConnectionUnavailable)
 	}
 
 	// Use GORM's Clauses with OnConflict for UPSERT
 	// This will insert or update based on unique constraint (symbol, interval, open_time)
-	result := r.db.Clauses(clause.OnConflict{
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "symbol"}, {Name: "interval"}, {Name: "open_time"}},
 		DoUpdates: clause.Assignments(map[string]interface{}{
 			"close_time":  kline.CloseTime,
@@ -78,13 +106,13 @@ func (r *KlineRepository) CreateOrUpdateKline(kline *models.Kline) error {
 //   - startTime: optional start time in milliseconds (nil to ignore)
 //   - endTime: optional end time in milliseconds (nil to ignore)
 //   - limit: maximum number of records to return (0 for no limit)
-func (r *KlineRepository) GetKlines(symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error) {
+func (r *KlineRepository) GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error) {
 	if r.db == nil {
 		return nil, fmt.Errorf(errDBConnectionUnavailable)
 	}
 
 	var klines []models.Kline
-	query := r.db.Model(&models.Kline{})
+	query := r.db.WithContext(ctx).Model(&models.Kline{})
 
 	// Apply filters
 	if symbol != "" {
@@ -116,9 +144,71 @@ func (r *KlineRepository) GetKlines(symbol, interval string, startTime, endTime
 	return klines, nil
 }
 
+// GetLatestOpenTime returns the open_time of the most recent stored kline of a series
+// ok is false when the series has no stored klines
+func (r *KlineRepository) GetLatestOpenTime(ctx context.Context, symbol, interval string) (openTime int64, ok bool, err error) {
+	if r.db == nil {
+		return 0, false, fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	var latest *int64
+	err = r.db.WithContext(ctx).Model(&models.Kline{}).
+		Where("symbol = ? AND interval = ?", symbol, interval).
+		Select("MAX(open_time)").
+		Scan(&latest).Error
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query latest kline: %w", err)
+	}
+	if latest == nil {
+		return 0, false, nil
+	}
+	return *latest, true, nil
+}
+
+// StreamKlines iterates over klines matching the filters in ascending open_time order
+// Rows are read from a database cursor and passed to fn one at a time, so the
+// result set is never held in memory. Iteration stops at the first error returned by fn.
+func (r *KlineRepository) StreamKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, fn func(models.Kline) error) error {
+	if r.db == nil {
+		return fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.Kline{}).
+		Where("symbol = ? AND interval = ?", symbol, interval)
+	if startTime != nil {
+		query = query.Where("open_time >= ?", *startTime)
+	}
+	if endTime != nil {
+		query = query.Where("open_time <= ?", *endTime)
+	}
+
+	rows, err := query.Order("open_time ASC").Rows()
+	if err != nil {
+		return fmt.Errorf("failed to open kline cursor: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kline models.Kline
+		if err := r.db.ScanRows(rows, &kline); err != nil {
+			return fmt.Errorf("failed to scan kline: %w", err)
+		}
+		if err := fn(kline); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate klines: %w", err)
+	}
+
+	return nil
+}
+
 // CreateKlinesBatch performs batch insert with UPSERT logic for multiple klines
 // This is optimized for inserting large numbers of klines efficiently
-func (r *KlineRepository) CreateKlinesBatch(klines []models.Kline) error {
+func (r *KlineRepository) CreateKlinesBatch(ctx context.Context, klines []models.Kline) (err error) {
+	defer observeWrite(opBatchUpsert, time.Now(), &err)
 	if r.db == nil {
 		return fmt.Errorf(errDBConnectionUnavailable)
 	}
@@ -137,7 +227,7 @@ func (r *KlineRepository) CreateKlinesBatch(klines []models.Kline) error {
 		}
 
 		batch := klines[i:end]
-		result := r.db.Clauses(clause.OnConflict{
+		result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
 			Columns: []clause.Column{{Name: "symbol"}, {Name: "interval"}, {Name: "open_time"}},
 			DoUpdates: clause.Assignments(map[string]interface{}{
 				"close_time":  clause.Expr{SQL: "excluded.close_time"},
@@ -158,8 +248,124 @@ func (r *KlineRepository) CreateKlinesBatch(klines []models.Kline) error {
 	return nil
 }
 
+// CopyKlinesBatch performs a high-throughput UPSERT of klines using PostgreSQL COPY
+// Rows are copied into a temporary staging table of the connection, emptied
+// on commit, and merged into klines with a single INSERT ... ON CONFLICT
+// statement, all inside one transaction. If the
+// batch contains the same (symbol, interval, open_time) more than once, the
+// last occurrence wins.
+func (r *KlineRepository) CopyKlinesBatch(ctx context.Context, klines []models.Kline) (err error) {
+	defer observeWrite(opCopyUpsert, time.Now(), &err)
+	if r.db == nil {
+		return fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	if len(klines) == 0 {
+		return nil
+	}
+
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("COPY requires the pgx driver, got %T", driverConn)
+		}
+		return copyKlines(ctx, pgxConn.Conn(), klines)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy klines: %w", err)
+	}
+
+	return nil
+}
+
+// stagingTable is the temporary table klines are copied into before being
+// merged into klines
+const stagingTable = "klines_staging"
+
+// copyKlines runs the staging table COPY and merge on a single pgx connection
+func copyKlines(ctx context.Context, conn *pgx.Conn, klines []models.Kline) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// The staging table is private to the connection and created once per
+	// pooled connection; its rows go with the transaction, so a failed batch
+	// leaves nothing behind and batches add no catalog churn
+	_, err = tx.Exec(ctx, `CREATE TEMP TABLE IF NOT EXISTS `+stagingTable+` (
+		seq BIGINT NOT NULL,
+		symbol VARCHAR(20) NOT NULL,
+		interval VARCHAR(10) NOT NULL,
+		open_time BIGINT NOT NULL,
+		close_time BIGINT NOT NULL,
+		open_price DECIMAL(20, 8) NOT NULL,
+		high_price DECIMAL(20, 8) NOT NULL,
+		low_price DECIMAL(20, 8) NOT NULL,
+		close_price DECIMAL(20, 8) NOT NULL,
+		volume DECIMAL(20, 8) NOT NULL
+	) ON COMMIT DELETE ROWS`)
+	if err != nil {
+		return fmt.Errorf("create staging table: %w", err)
+	}
+
+	columns := []string{"seq", "symbol", "interval", "open_time", "close_time",
+		"open_price", "high_price", "low_price", "close_price", "volume"}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{stagingTable}, columns,
+		pgx.CopyFromSlice(len(klines), func(i int) ([]any, error) {
+			k := klines[i]
+			return []any{int64(i), k.Symbol, k.Interval, k.OpenTime, k.CloseTime,
+				k.OpenPrice, k.HighPrice, k.LowPrice, k.ClosePrice, k.Volume}, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("copy into staging table: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO klines (symbol, interval, open_time, close_time,
+			open_price, high_price, low_price, close_price, volume, created_at, updated_at)
+		SELECT DISTINCT ON (symbol, interval, open_time)
+			symbol, interval, open_time, close_time,
+			open_price, high_price, low_price, close_price, volume,
+			CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM `+stagingTable+`
+		ORDER BY symbol, interval, open_time, seq DESC
+		ON CONFLICT (symbol, interval, open_time) DO UPDATE SET
+			close_time = excluded.close_time,
+			open_price = excluded.open_price,
+			high_price = excluded.high_price,
+			low_price = excluded.low_price,
+			close_price = excluded.close_price,
+			volume = excluded.volume,
+			updated_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		return fmt.Errorf("merge staging table: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// BulkUpsertKlines performs batch UPSERT, switching from CreateKlinesBatch to
+// CopyKlinesBatch once the batch reaches copyThreshold klines
+func (r *KlineRepository) BulkUpsertKlines(ctx context.Context, klines []models.Kline) error {
+	if len(klines) >= copyThreshold {
+		return r.CopyKlinesBatch(ctx, klines)
+	}
+	return r.CreateKlinesBatch(ctx, klines)
+}
+
 // IsConnected checks if the database connection is available
-func (r *KlineRepository) IsConnected() bool {
+func (r *KlineRepository) IsConnected(ctx context.Context) bool {
 	if r.db == nil {
 		return false
 	}
@@ -169,7 +375,7 @@ func (r *KlineRepository) IsConnected() bool {
 		return false
 	}
 
-	if err := sqlDB.Ping(); err != nil {
+	if err := sqlDB.PingContext(ctx); err != nil {
 		return false
 	}
 
@@ -179,15 +385,16 @@ func (r *KlineRepository) IsConnected() bool {
 // SafeCreateOrUpdateKline safely creates or updates a kline with error handling
 // If database connection fails, it logs the error but doesn't return it
 // This allows the application to continue running even if database is unavailable
-func (r *KlineRepository) SafeCreateOrUpdateKline(kline *models.Kline) error {
-	if !r.IsConnected() {
-		log.Printf("Warning: Database connection not available, skipping kline storage: %s %s %d",
-			kline.Symbol, kline.Interval, kline.OpenTime)
+func (r *KlineRepository) SafeCreateOrUpdateKline(ctx context.Context, kline *models.Kline) error {
+	if !r.IsConnected(ctx) {
+		repositoryLog.Warn("Database connection not available, skipping kline storage",
+			"symbol", kline.Symbol, "interval", kline.Interval, "open_time", kline.OpenTime)
+		metrics.DBWriteFailures.WithLabelValues(opUpsert).Inc()
 		return nil // Return nil to allow application to continue
 	}
 
-	if err := r.CreateOrUpdateKline(kline); err != nil {
-		log.Printf("Error storing kline (continuing execution): %v", err)
+	if err := r.CreateOrUpdateKline(ctx, kline); err != nil {
+		repositoryLog.Error("Error storing kline (continuing execution)", "error", err)
 		return nil // Return nil to allow application to continue
 	}
 
@@ -196,15 +403,16 @@ func (r *KlineRepository) SafeCreateOrUpdateKline(kline *models.Kline) error {
 
 // SafeCreateKlinesBatch safely performs batch insert with error handling
 // If database connection fails, it logs the error but doesn't return it
-func (r *KlineRepository) SafeCreateKlinesBatch(klines []models.Kline) error {
-	if !r.IsConnected() {
-		log.Printf("Warning: Database connection not available, skipping batch kline storage (%d klines)",
-			len(klines))
+func (r *KlineRepository) SafeCreateKlinesBatch(ctx context.Context, klines []models.Kline) error {
+	if !r.IsConnected(ctx) {
+		repositoryLog.Warn("Database connection not available, skipping batch kline storage",
+			"klines", len(klines))
+		metrics.DBWriteFailures.WithLabelValues(opBatchUpsert).Inc()
 		return nil // Return nil to allow application to continue
 	}
 
-	if err := r.CreateKlinesBatch(klines); err != nil {
-		log.Printf("Error batch storing klines (continuing execution): %v", err)
+	if err := r.BulkUpsertKlines(ctx, klines); err != nil {
+		repositoryLog.Error("Error batch storing klines (continuing execution)", "error", err)
 		return nil // Return nil to allow application to continue
 	}
 
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"crypto-monitor/internal/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// KlineImportRepository handles database operations for KlineImport records
+type KlineImportRepository struct {
+	db *gorm.DB
+}
+
+// NewKlineImportRepository creates a new KlineImportRepository instance
+func NewKlineImportRepository(db *gorm.DB) *KlineImportRepository {
+	return &KlineImportRepository{db: db}
+}
+
+// IsImported reports whether a file with the given SHA-256 checksum has already been imported
+func (r *KlineImportRepository) IsImported(ctx context.Context, checksum, symbol, interval string) (bool, error) {
+	if r.db == nil {
+		return false, fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	var record models.KlineImport
+	err := r.db.WithContext(ctx).Where("checksum = ? AND symbol = ? AND interval = ?", checksum, symbol, interval).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query kline imports: %w", err)
+	}
+
+	return true, nil
+}
+
+// RecordImport stores a completed import, replacing any previous record of
+// the same file into the same series
+func (r *KlineImportRepository) RecordImport(ctx context.Context, record *models.KlineImport) error {
+	if r.db == nil {
+		return fmt.Errorf(errDBConnectionUnavailable)
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "checksum"}, {Name: "symbol"}, {Name: "interval"}},
+		DoUpdates: clause.AssignmentColumns([]string{"file_name", "rows", "created_at"}),
+	}).Create(record)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to record kline import: %w", result.Error)
+	}
+
+	return nil
+}
@@ -1,15 +1,18 @@
 package repository
 
 import (
+	"context"
+	"crypto-monitor/internal/config"
 	"crypto-monitor/internal/models"
 	"crypto-monitor/pkg/database"
+	"fmt"
 	"os"
 	"testing"
 	"time"
 )
 
 // setupTestDB initializes a test database connection
-func setupTestDB(t *testing.T) *KlineRepository {
+func setupTestDB(t testing.TB) *KlineRepository {
 	// Use test database configuration
 	// Use 127.0.0.1 instead of localhost to avoid IPv6 resolution issues
 	os.Setenv("DB_HOST", "127.0.0.1")
@@ -18,7 +21,12 @@ func setupTestDB(t *testing.T) *KlineRepository {
 	os.Setenv("DB_PASSWORD", "postgres")
 	os.Setenv("DB_NAME", "crypto_monitor")
 
-	db, err := database.InitDB()
+	cfg, err := config.LoadFile("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		t.Skipf("Skipping test: database connection failed: %v", err)
 		return nil
@@ -46,7 +54,7 @@ func TestKlineRepository_CreateKline(t *testing.T) {
 		Volume:     100.5,
 	}
 
-	err := repo.CreateKline(kline)
+	err := repo.CreateKline(t.Context(), kline)
 	if err != nil {
 		t.Fatalf("Failed to create kline: %v", err)
 	}
@@ -77,7 +85,7 @@ func TestKlineRepository_CreateOrUpdateKline(t *testing.T) {
 	}
 
 	// First create
-	err := repo.CreateOrUpdateKline(kline)
+	err := repo.CreateOrUpdateKline(t.Context(), kline)
 	if err != nil {
 		t.Fatalf("Failed to create kline: %v", err)
 	}
@@ -87,7 +95,7 @@ func TestKlineRepository_CreateOrUpdateKline(t *testing.T) {
 	// Update with new values
 	kline.ClosePrice = 50600.0
 	kline.Volume = 150.0
-	err = repo.CreateOrUpdateKline(kline)
+	err = repo.CreateOrUpdateKline(t.Context(), kline)
 	if err != nil {
 		t.Fatalf("Failed to update kline: %v", err)
 	}
@@ -124,11 +132,11 @@ func TestKlineRepository_GetKlines(t *testing.T) {
 			ClosePrice: 50500.0 + float64(i),
 			Volume:     100.5 + float64(i),
 		}
-		repo.CreateOrUpdateKline(kline)
+		repo.CreateOrUpdateKline(t.Context(), kline)
 	}
 
 	// Query klines
-	klines, err := repo.GetKlines("BTCUSDT", "1m", nil, nil, 10)
+	klines, err := repo.GetKlines(t.Context(), "BTCUSDT", "1m", nil, nil, 10)
 	if err != nil {
 		t.Fatalf("Failed to get klines: %v", err)
 	}
@@ -171,13 +179,13 @@ func TestKlineRepository_CreateKlinesBatch(t *testing.T) {
 		}
 	}
 
-	err := repo.CreateKlinesBatch(klines)
+	err := repo.CreateKlinesBatch(t.Context(), klines)
 	if err != nil {
 		t.Fatalf("Failed to batch create klines: %v", err)
 	}
 
 	// Verify klines were created
-	result, err := repo.GetKlines("ETHUSDT", "5m", nil, nil, 20)
+	result, err := repo.GetKlines(t.Context(), "ETHUSDT", "5m", nil, nil, 20)
 	if err != nil {
 		t.Fatalf("Failed to get klines: %v", err)
 	}
@@ -207,8 +215,93 @@ func TestKlineRepository_SafeCreateOrUpdateKline(t *testing.T) {
 	}
 
 	// Should not return error even if database fails (in this case it should work)
-	err := repo.SafeCreateOrUpdateKline(kline)
+	err := repo.SafeCreateOrUpdateKline(t.Context(), kline)
 	if err != nil {
 		t.Errorf("SafeCreateOrUpdateKline should not return error: %v", err)
 	}
 }
+
+// TestKlineRepository_CopyKlinesBatch tests COPY based batch upsert
+func TestKlineRepository_CopyKlinesBatch(t *testing.T) {
+	repo := setupTestDB(t)
+	if repo == nil {
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	klines := benchmarkKlines("BNBUSDT", "1h", now, 10)
+
+	// A repeated open_time in the same batch keeps the last occurrence
+	duplicate := klines[0]
+	duplicate.ClosePrice = 999.0
+	klines = append(klines, duplicate)
+
+	if err := repo.CopyKlinesBatch(t.Context(), klines); err != nil {
+		t.Fatalf("Failed to copy klines: %v", err)
+	}
+
+	// Copying again updates instead of failing on the unique constraint
+	if err := repo.CopyKlinesBatch(t.Context(), klines); err != nil {
+		t.Fatalf("Failed to copy klines a second time: %v", err)
+	}
+
+	result, err := repo.GetKlines(t.Context(), "BNBUSDT", "1h", &duplicate.OpenTime, &duplicate.OpenTime, 1)
+	if err != nil {
+		t.Fatalf("Failed to get klines: %v", err)
+	}
+
+	if len(result) != 1 || result[0].ClosePrice != 999.0 {
+		t.Errorf("Expected last duplicate to win with close 999.0, got %+v", result)
+	}
+}
+
+// benchmarkKlines generates n consecutive klines for a series ending at start
+func benchmarkKlines(symbol, interval string, start int64, n int) []models.Kline {
+	klines := make([]models.Kline, n)
+	for i := range klines {
+		openTime := start - int64(i)*60000
+		klines[i] = models.Kline{
+			Symbol:     symbol,
+			Interval:   interval,
+			OpenTime:   openTime,
+			CloseTime:  openTime + 59999,
+			OpenPrice:  500.0 + float64(i%100),
+			HighPrice:  510.0 + float64(i%100),
+			LowPrice:   490.0 + float64(i%100),
+			ClosePrice: 505.0 + float64(i%100),
+			Volume:     10.0 + float64(i%100),
+		}
+	}
+	return klines
+}
+
+// benchmarkBatchWriter measures a batch writer upserting 10000 klines per op
+func benchmarkBatchWriter(b *testing.B, write func(*KlineRepository, context.Context, []models.Kline) error) {
+	repo := setupTestDB(b)
+	if repo == nil {
+		return
+	}
+
+	const batchSize = 10000
+	symbol := fmt.Sprintf("BENCH%d", time.Now().UnixNano()%1000000)
+	start := time.Now().UnixMilli()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		klines := benchmarkKlines(symbol, "1m", start-int64(i)*batchSize*60000, batchSize)
+		if err := write(repo, b.Context(), klines); err != nil {
+			b.Fatalf("Batch write failed: %v", err)
+		}
+	}
+	b.ReportMetric(float64(b.N*batchSize)/b.Elapsed().Seconds(), "klines/s")
+}
+
+// BenchmarkKlineRepository_CreateKlinesBatch benchmarks the multi-row INSERT path
+func BenchmarkKlineRepository_CreateKlinesBatch(b *testing.B) {
+	benchmarkBatchWriter(b, (*KlineRepository).CreateKlinesBatch)
+}
+
+// BenchmarkKlineRepository_CopyKlinesBatch benchmarks the COPY path
+func BenchmarkKlineRepository_CopyKlinesBatch(b *testing.B) {
+	benchmarkBatchWriter(b, (*KlineRepository).CopyKlinesBatch)
+}
@@ -1,20 +1,35 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
 	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/tracing"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	binanceLog    = logging.For(logging.Binance)
+	binanceTracer = tracing.Tracer(logging.Binance)
+)
+
+// streamSeq numbers upstream connections so the logs of one connection can be
+// told apart from those of its reconnects
+var streamSeq atomic.Uint64
+
 // FlexibleString can unmarshal from both string and number JSON types
 type FlexibleString string
 
@@ -51,48 +66,31 @@ type BinanceService struct {
 type BinanceKlineResponse []interface{}
 
 // NewBinanceService creates a new Binance service instance
-// Defaults to testnet if .env file is not present
-func NewBinanceService() *BinanceService {
-	apiURL := os.Getenv("BINANCE_API_URL")
-	wsURL := os.Getenv("BINANCE_WS_URL")
-
-	// Check if .env file exists
-	_, err := os.Stat(".env")
-	useTestnet := os.IsNotExist(err)
-
-	// If no .env file exists, default to testnet
-	if useTestnet {
-		if apiURL == "" {
-			apiURL = "https://testnet.binance.vision"
-		}
-		if wsURL == "" {
-			wsURL = "wss://stream.testnet.binance.vision/ws"
-		}
-		log.Printf("Using Binance Testnet (no .env file found)")
-	} else {
-		// Production defaults
-		if apiURL == "" {
-			apiURL = "https://api.binance.com"
-		}
-		if wsURL == "" {
-			wsURL = "wss://stream.binance.com:9443/ws"
-		}
-		log.Printf("Using Binance Production")
-	}
+func NewBinanceService(cfg config.ExchangeConfig) *BinanceService {
+	cfg = cfg.WithDefaults()
+	binanceLog.Info("Using Binance", "network", cfg.Network, "api_url", cfg.APIURL)
 
 	return &BinanceService{
-		apiURL: apiURL,
-		wsURL:  wsURL,
+		apiURL: cfg.APIURL,
+		wsURL:  cfg.WSURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: cfg.HTTPTimeout.Std(),
 		},
 	}
 }
 
 // GetKlines fetches historical kline data from Binance REST API
-func (s *BinanceService) GetKlines(symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error) {
+func (s *BinanceService) GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) (klines []models.Kline, err error) {
+	ctx, span := binanceTracer.Start(ctx, "binance.GetKlines", trace.WithSpanKind(trace.SpanKindClient),
+		seriesAttributes(Series{Symbol: symbol, Interval: interval}))
+	defer func() {
+		span.SetAttributes(attribute.Int("klines.count", len(klines)))
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	url := fmt.Sprintf("%s/api/v3/klines", s.apiURL)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -121,9 +119,14 @@ func (s *BinanceService) GetKlines(symbol, interval string, startTime, endTime *
 	for i := 0; i < maxRetries; i++ {
 		resp, err := s.httpClient.Do(req)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			if i < maxRetries-1 {
-				log.Printf("Binance API call failed (attempt %d/%d): %v", i+1, maxRetries, err)
-				time.Sleep(retryDelay * time.Duration(1<<i)) // Exponential backoff
+				binanceLog.WarnContext(ctx, "Binance API call failed", "attempt", i+1, "max_attempts", maxRetries, "error", err)
+				if err := sleepContext(ctx, retryDelay*time.Duration(1<<i)); err != nil { // Exponential backoff
+					return nil, err
+				}
 				continue
 			}
 			return nil, fmt.Errorf("failed to fetch klines after %d attempts: %w", maxRetries, err)
@@ -132,8 +135,10 @@ func (s *BinanceService) GetKlines(symbol, interval string, startTime, endTime *
 		if resp.StatusCode != http.StatusOK {
 			resp.Body.Close()
 			if i < maxRetries-1 {
-				log.Printf("Binance API returned status %d (attempt %d/%d)", resp.StatusCode, i+1, maxRetries)
-				time.Sleep(retryDelay * time.Duration(1<<i))
+				binanceLog.WarnContext(ctx, "Binance API returned an error status", "status", resp.StatusCode, "attempt", i+1, "max_attempts", maxRetries)
+				if err := sleepContext(ctx, retryDelay*time.Duration(1<<i)); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, fmt.Errorf("binance API returned status %d", resp.StatusCode)
@@ -147,17 +152,17 @@ func (s *BinanceService) GetKlines(symbol, interval string, startTime, endTime *
 		resp.Body.Close()
 
 		// Convert Binance format to internal model
-		klines := make([]models.Kline, 0, len(binanceKlines))
+		klines = make([]models.Kline, 0, len(binanceKlines))
 		for _, bk := range binanceKlines {
 			kline, err := s.convertBinanceKlineToModel(bk, symbol, interval)
 			if err != nil {
-				log.Printf("Failed to convert kline: %v", err)
+				binanceLog.Warn("Failed to convert kline", "symbol", symbol, "interval", interval, "error", err)
 				continue
 			}
 			klines = append(klines, kline)
 		}
 
-		log.Printf("Successfully fetched %d klines for %s %s", len(klines), symbol, interval)
+		binanceLog.Debug("Fetched klines", "symbol", symbol, "interval", interval, "count", len(klines))
 		return klines, nil
 	}
 
@@ -229,7 +234,9 @@ func (s *BinanceService) convertBinanceKlineToModel(bk BinanceKlineResponse, sym
 
 // SubscribeKlineStream subscribes to Binance WebSocket kline stream
 // Uses raw stream format: /ws/<streamName> which returns direct data payload
-func (s *BinanceService) SubscribeKlineStream(symbol, interval string, callback func(models.Kline)) error {
+// The stream runs until it fails or ctx is cancelled, in which case ctx.Err() is returned
+// Every closed kline starts a new trace, whose context is passed to callback
+func (s *BinanceService) SubscribeKlineStream(ctx context.Context, symbol, interval string, callback func(context.Context, models.Kline)) error {
 	// Build stream name: <symbol>@kline_<interval>
 	// Binance requires lowercase symbols
 	symbolLower := strings.ToLower(symbol)
@@ -239,15 +246,26 @@ func (s *BinanceService) SubscribeKlineStream(symbol, interval string, callback
 	// This returns direct data payload, not wrapped in {"stream":"...","data":{...}}
 	wsURL := fmt.Sprintf("%s/%s", s.wsURL, streamName)
 
-	log.Printf("Connecting to Binance WebSocket: %s", wsURL)
+	streamID := fmt.Sprintf("%s#%d", streamName, streamSeq.Add(1))
+	logger := binanceLog.With("stream_id", streamID)
+	logger.Info("Connecting to Binance WebSocket", "url", wsURL)
 
-	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Binance WebSocket: %w", err)
 	}
 	defer conn.Close()
 
-	log.Printf("Connected to Binance WebSocket stream: %s", streamName)
+	// Closing the connection unblocks the read loop when ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	logger.Info("Connected to Binance WebSocket stream")
+
+	// Closed klines are logged for one in every SampleEvery, not each of them
+	sampler := logging.NewSampler(logging.SampleEvery())
+	received := 0
+	messages := metrics.StreamMessages.WithLabelValues(symbol, interval)
 
 	// Read messages
 	// Raw stream format: message is directly the kline data payload
@@ -271,9 +289,14 @@ func (s *BinanceService) SubscribeKlineStream(symbol, interval string, callback
 		}
 
 		if err := conn.ReadJSON(&msg); err != nil {
-			log.Printf("Error reading WebSocket message: %v", err)
+			if ctx.Err() != nil {
+				logger.Info("Closed Binance WebSocket stream")
+				return ctx.Err()
+			}
+			logger.Warn("Error reading WebSocket message", "error", err)
 			return fmt.Errorf("failed to read message: %w", err)
 		}
+		messages.Inc()
 
 		// Process kline update (only process closed klines)
 		if msg.EventType == "kline" && msg.Kline.IsClosed {
@@ -289,17 +312,43 @@ func (s *BinanceService) SubscribeKlineStream(symbol, interval string, callback
 				Volume:     parseFloat(msg.Kline.Volume.String()),
 			}
 
-			log.Printf("Received kline update: %s %s at %d", kline.Symbol, kline.Interval, kline.OpenTime)
-			callback(kline)
+			received++
+			if sampler.Allow() {
+				logger.Info("Received kline update", "open_time", kline.OpenTime, "klines_since_last_log", received)
+				received = 0
+			}
+
+			msgCtx, span := binanceTracer.Start(ctx, "binance.kline",
+				trace.WithNewRoot(),
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				seriesAttributes(Series{Symbol: kline.Symbol, Interval: kline.Interval}),
+				trace.WithAttributes(
+					attribute.String("binance.stream_id", streamID),
+					attribute.Int64("kline.open_time", kline.OpenTime),
+				))
+			callback(msgCtx, kline)
+			span.End()
 		}
 	}
 }
 
+// sleepContext waits for d, returning ctx.Err() early if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // parseFloat safely parses a string to float64
 func parseFloat(s string) float64 {
 	val, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		log.Printf("Failed to parse float: %s, error: %v", s, err)
+		binanceLog.Warn("Failed to parse float", "value", s, "error", err)
 		return 0
 	}
 	return val
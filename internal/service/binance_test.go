@@ -1,10 +1,20 @@
 package service
 
 import (
+	"context"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/metrics"
 	"crypto-monitor/internal/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestBinanceService_GetKlines(t *testing.T) {
@@ -13,10 +23,10 @@ func TestBinanceService_GetKlines(t *testing.T) {
 		t.Skip("Skipping network tests")
 	}
 
-	service := NewBinanceService()
+	service := NewBinanceService(config.Default().Exchange)
 
 	// Test fetching BTC/USDT 1m klines
-	klines, err := service.GetKlines("BTCUSDT", "1m", nil, nil, 10)
+	klines, err := service.GetKlines(context.Background(), "BTCUSDT", "1m", nil, nil, 10)
 	if err != nil {
 		t.Fatalf("Failed to fetch klines: %v", err)
 	}
@@ -45,7 +55,7 @@ func TestBinanceService_GetKlines(t *testing.T) {
 }
 
 func TestBinanceService_ConvertBinanceKline(t *testing.T) {
-	service := NewBinanceService()
+	service := NewBinanceService(config.Default().Exchange)
 
 	// Sample Binance kline response
 	binanceKline := BinanceKlineResponse{
@@ -88,14 +98,14 @@ func TestBinanceService_WebSocketConnection(t *testing.T) {
 		t.Skip("Skipping network tests")
 	}
 
-	service := NewBinanceService()
+	service := NewBinanceService(config.Default().Exchange)
 
 	// Test WebSocket connection with timeout
 	done := make(chan bool, 1)
 	timeout := time.After(10 * time.Second)
 
 	go func() {
-		err := service.SubscribeKlineStream("BTCUSDT", "1s", func(kline models.Kline) {
+		err := service.SubscribeKlineStream(context.Background(), "BTCUSDT", "1s", func(ctx context.Context, kline models.Kline) {
 			t.Logf("Received kline: %+v", kline)
 			done <- true
 		})
@@ -111,3 +121,100 @@ func TestBinanceService_WebSocketConnection(t *testing.T) {
 		t.Log("WebSocket connection test completed (timeout after 10s)")
 	}
 }
+
+// TestBinanceService_GetKlinesCancelled tests that cancelling ctx interrupts the retry backoff
+func TestBinanceService_GetKlinesCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	service := NewBinanceService(config.ExchangeConfig{APIURL: server.URL, HTTPTimeout: config.Duration(time.Second)})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := service.GetKlines(ctx, "BTCUSDT", "1m", nil, nil, 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+	// Without cancellation the backoff alone takes three seconds
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected GetKlines to return once ctx expired, took %v", elapsed)
+	}
+}
+
+// TestBinanceService_SubscribeCancelled tests that cancelling ctx closes an idle upstream stream
+func TestBinanceService_SubscribeCancelled(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Never send anything, only wait for the client to go away
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	service := NewBinanceService(config.ExchangeConfig{APIURL: server.URL, WSURL: wsURL, HTTPTimeout: config.Duration(time.Second)})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- service.SubscribeKlineStream(ctx, "BTCUSDT", "1m", func(context.Context, models.Kline) {})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to close within a second of cancellation")
+	}
+}
+
+// TestBinanceService_StreamMessages tests that every frame of a stream is
+// counted, while only closed klines are passed on
+func TestBinanceService_StreamMessages(t *testing.T) {
+	frames := []string{
+		`{"e":"kline","s":"MSGUSDT","k":{"t":0,"T":59999,"s":"MSGUSDT","i":"1m","o":"1","c":"2","h":"2","l":"1","v":"10","x":false}}`,
+		`{"e":"kline","s":"MSGUSDT","k":{"t":0,"T":59999,"s":"MSGUSDT","i":"1m","o":"1","c":"3","h":"3","l":"1","v":"20","x":true}}`,
+	}
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, frame := range frames {
+			conn.WriteMessage(websocket.TextMessage, []byte(frame))
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	service := NewBinanceService(config.ExchangeConfig{APIURL: server.URL, WSURL: wsURL, HTTPTimeout: config.Duration(time.Second)})
+
+	var klines []models.Kline
+	service.SubscribeKlineStream(context.Background(), "MSGUSDT", "1m", func(_ context.Context, kline models.Kline) {
+		klines = append(klines, kline)
+	})
+
+	if len(klines) != 1 || klines[0].ClosePrice != 3 {
+		t.Errorf("Expected only the closed kline, got %+v", klines)
+	}
+	if n := testutil.ToFloat64(metrics.StreamMessages.WithLabelValues("MSGUSDT", "1m")); n != 2 {
+		t.Errorf("Expected 2 stream messages counted, got %v", n)
+	}
+}
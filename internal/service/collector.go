@@ -0,0 +1,417 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	collectorLog    = logging.For(logging.Collector)
+	collectorTracer = tracing.Tracer(logging.Collector)
+)
+
+const (
+	// backfillPageSize is the maximum number of klines requested per REST call
+	backfillPageSize = 1000
+
+	// Delays between reconnect attempts of a failed upstream stream
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// Series identifies one symbol/interval kline stream
+type Series struct {
+	Symbol   string `json:"symbol"`
+	Interval string `json:"interval"`
+}
+
+// Key returns the "symbol:interval" key used for subscriptions
+func (s Series) Key() string {
+	return fmt.Sprintf("%s:%s", s.Symbol, s.Interval)
+}
+
+// TrackedSeries expands a tracking configuration into every symbol/interval pair
+func TrackedSeries(tracking config.TrackingConfig) []Series {
+	series := make([]Series, 0, len(tracking.Symbols)*len(tracking.Intervals))
+	for _, symbol := range tracking.Symbols {
+		for _, interval := range tracking.Intervals {
+			series = append(series, Series{Symbol: symbol, Interval: interval})
+		}
+	}
+	return series
+}
+
+// KlineSource provides historical and live klines from an exchange
+type KlineSource interface {
+	GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error)
+	// SubscribeKlineStream calls callback for every closed kline with a context
+	// carrying the span of the upstream message
+	SubscribeKlineStream(ctx context.Context, symbol, interval string, callback func(context.Context, models.Kline)) error
+}
+
+// BackfillStore persists backfilled klines and reports how far a series is stored
+type BackfillStore interface {
+	GetLatestOpenTime(ctx context.Context, symbol, interval string) (int64, bool, error)
+	BulkUpsertKlines(ctx context.Context, klines []models.Kline) error
+}
+
+// SeriesListener is notified of collected klines and of series that stop being tracked
+type SeriesListener interface {
+	OnKline(ctx context.Context, kline models.Kline)
+	OnSeriesRemoved(series Series)
+}
+
+// TrackingChange describes the effect of applying a new tracking configuration
+type TrackingChange struct {
+	Added   []Series `json:"added"`
+	Removed []Series `json:"removed"`
+}
+
+// seriesStream is a running upstream stream of one tracked series
+type seriesStream struct {
+	series Series
+	health *seriesHealth
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Collector keeps an upstream stream running for every tracked series around the
+// clock, independent of websocket clients. It writes the collected klines through
+// the KlineWriter and passes them on to listeners, which attach to the running
+// streams rather than starting their own.
+// The tracked set can be replaced at runtime with Apply: streams of removed series
+// are stopped, and new series are backfilled over REST before their stream starts.
+type Collector struct {
+	source KlineSource
+	store  BackfillStore
+	writer *KlineWriter
+
+	mu        sync.Mutex
+	tracking  config.TrackingConfig
+	streams   map[string]*seriesStream
+	listeners []SeriesListener
+	stopped   bool
+	wg        sync.WaitGroup
+}
+
+// NewCollector creates a Collector that tracks nothing until Apply is called
+func NewCollector(source KlineSource, store BackfillStore, writer *KlineWriter) *Collector {
+	return &Collector{
+		source:  source,
+		store:   store,
+		writer:  writer,
+		streams: make(map[string]*seriesStream),
+	}
+}
+
+// AddListener registers a listener for collected klines and removed series
+func (c *Collector) AddListener(listener SeriesListener) {
+	c.mu.Lock()
+	c.listeners = append(c.listeners, listener)
+	c.mu.Unlock()
+}
+
+// Apply replaces the tracked symbols and intervals, starting streams for added
+// series and stopping streams of removed ones
+// Listeners are told about removed series once their streams have been stopped
+func (c *Collector) Apply(tracking config.TrackingConfig) (TrackingChange, error) {
+	tracking = tracking.Normalized()
+	if err := tracking.Validate(); err != nil {
+		return TrackingChange{}, err
+	}
+
+	desired := make(map[string]Series)
+	for _, series := range TrackedSeries(tracking) {
+		desired[series.Key()] = series
+	}
+
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return TrackingChange{}, fmt.Errorf("collector is stopped")
+	}
+
+	var change TrackingChange
+	var stopping []*seriesStream
+	for key, stream := range c.streams {
+		if _, ok := desired[key]; !ok {
+			stream.cancel()
+			stopping = append(stopping, stream)
+			delete(c.streams, key)
+			change.Removed = append(change.Removed, stream.series)
+		}
+	}
+	for key, series := range desired {
+		if _, ok := c.streams[key]; !ok {
+			c.streams[key] = c.start(series)
+			change.Added = append(change.Added, series)
+		}
+	}
+	c.tracking = tracking
+	listeners := append([]SeriesListener(nil), c.listeners...)
+	c.mu.Unlock()
+
+	for _, stream := range stopping {
+		<-stream.done
+	}
+	for _, series := range change.Removed {
+		for _, listener := range listeners {
+			listener.OnSeriesRemoved(series)
+		}
+	}
+
+	sortSeries(change.Added)
+	sortSeries(change.Removed)
+	if len(change.Added) > 0 || len(change.Removed) > 0 {
+		collectorLog.Info("Tracking updated", "added", len(change.Added), "removed", len(change.Removed), "tracked", len(desired))
+	}
+	return change, nil
+}
+
+// Tracking returns the currently applied tracking configuration
+func (c *Collector) Tracking() config.TrackingConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tracking
+}
+
+// Series returns the tracked series sorted by symbol and interval
+func (c *Collector) Series() []Series {
+	c.mu.Lock()
+	series := make([]Series, 0, len(c.streams))
+	for _, stream := range c.streams {
+		series = append(series, stream.series)
+	}
+	c.mu.Unlock()
+
+	sortSeries(series)
+	return series
+}
+
+// Health returns the ingestion state of every tracked series sorted by symbol and interval
+func (c *Collector) Health() []SeriesHealth {
+	c.mu.Lock()
+	streams := make([]*seriesStream, 0, len(c.streams))
+	for _, stream := range c.streams {
+		streams = append(streams, stream)
+	}
+	c.mu.Unlock()
+
+	now := time.Now()
+	health := make([]SeriesHealth, len(streams))
+	for i, stream := range streams {
+		health[i] = stream.health.snapshot(now)
+	}
+	sort.Slice(health, func(i, j int) bool {
+		if health[i].Symbol != health[j].Symbol {
+			return health[i].Symbol < health[j].Symbol
+		}
+		return health[i].Interval < health[j].Interval
+	})
+	return health
+}
+
+// IsTracked reports whether a stream is running for the series
+func (c *Collector) IsTracked(symbol, interval string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.streams[Series{Symbol: symbol, Interval: interval}.Key()]
+	return ok
+}
+
+// Stop closes every upstream stream and waits for them to exit, or until ctx
+// is done, in which case ctx.Err() is returned
+func (c *Collector) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	c.stopped = true
+	for key, stream := range c.streams {
+		stream.cancel()
+		delete(c.streams, key)
+	}
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start launches the stream goroutine of a series; c.mu must be held
+func (c *Collector) start(series Series) *seriesStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &seriesStream{
+		series: series,
+		health: newSeriesHealth(series),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer close(stream.done)
+		c.run(ctx, stream)
+	}()
+	return stream
+}
+
+// run backfills a series and keeps its upstream stream connected until ctx is cancelled
+// Every reconnect backfills the gap left by the outage first
+func (c *Collector) run(ctx context.Context, stream *seriesStream) {
+	series, health := stream.series, stream.health
+	onKline := func(ctx context.Context, kline models.Kline) {
+		health.recordKline(kline)
+		c.handleKline(ctx, kline)
+	}
+
+	delay := minReconnectDelay
+	for {
+		health.setState(SeriesStateBackfilling)
+		n, err := c.backfill(ctx, series)
+		health.recordBackfill(n)
+		if err != nil {
+			health.recordError(err)
+			collectorLog.Warn("Backfill failed", "symbol", series.Symbol, "interval", series.Interval, "error", err)
+		}
+
+		health.setState(SeriesStateStreaming)
+		connected := time.Now()
+		err = c.source.SubscribeKlineStream(ctx, series.Symbol, series.Interval, onKline)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A stream that stayed up for a while is a fresh failure, not a retry loop
+		if time.Since(connected) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		if err == nil {
+			err = errors.New("stream closed")
+		}
+		health.recordError(err)
+		health.setState(SeriesStateReconnecting)
+		collectorLog.Warn("Stream closed, reconnecting", "symbol", series.Symbol, "interval", series.Interval, "error", err, "delay", delay.String())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// handleKline stores a collected kline and passes it on to listeners
+func (c *Collector) handleKline(ctx context.Context, kline models.Kline) {
+	c.writer.Enqueue(ctx, kline)
+
+	c.mu.Lock()
+	listeners := c.listeners
+	c.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener.OnKline(ctx, kline)
+	}
+}
+
+// backfill fetches closed klines missing since the latest stored kline, going
+// back at most the configured backfill limit, and stores them directly
+// It returns the number of klines stored
+func (c *Collector) backfill(ctx context.Context, series Series) (total int, err error) {
+	limit := c.Tracking().BackfillLimit
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	ctx, span := collectorTracer.Start(ctx, "collector.backfill", seriesAttributes(series))
+	defer func() {
+		span.SetAttributes(attribute.Int("klines.count", total))
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	duration, err := models.IntervalDuration(series.Interval)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UnixMilli()
+	startTime := now - int64(limit)*duration.Milliseconds()
+	latest, ok, err := c.store.GetLatestOpenTime(ctx, series.Symbol, series.Interval)
+	if err != nil {
+		return 0, err
+	}
+	if ok && latest+1 > startTime {
+		startTime = latest + 1
+	}
+
+	for startTime < now && ctx.Err() == nil {
+		klines, err := c.source.GetKlines(ctx, series.Symbol, series.Interval, &startTime, nil, backfillPageSize)
+		if err != nil {
+			return total, err
+		}
+
+		// The stream only delivers closed klines, so the open candle is left to it
+		closed := klines[:0]
+		for _, kline := range klines {
+			if kline.CloseTime < now {
+				closed = append(closed, kline)
+			}
+		}
+		if len(closed) == 0 {
+			break
+		}
+
+		if err := c.store.BulkUpsertKlines(ctx, closed); err != nil {
+			return total, err
+		}
+		total += len(closed)
+		startTime = closed[len(closed)-1].OpenTime + 1
+
+		if len(klines) < backfillPageSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		collectorLog.Info("Backfilled klines", "symbol", series.Symbol, "interval", series.Interval, "count", total)
+	}
+	return total, nil
+}
+
+// seriesAttributes labels a span with the series it works on
+func seriesAttributes(series Series) trace.SpanStartOption {
+	return trace.WithAttributes(
+		attribute.String("kline.symbol", series.Symbol),
+		attribute.String("kline.interval", series.Interval),
+	)
+}
+
+// sortSeries orders series by symbol, then interval
+func sortSeries(series []Series) {
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Symbol != series[j].Symbol {
+			return series[i].Symbol < series[j].Symbol
+		}
+		return series[i].Interval < series[j].Interval
+	})
+}
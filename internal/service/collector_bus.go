@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"crypto-monitor/internal/bus"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	busLog    = logging.For(logging.Bus)
+	busTracer = tracing.Tracer(logging.Bus)
+)
+
+// Bus topics between the collector and API servers
+const (
+	TopicKlines        = "klines"         // klineMessage collected from a stream
+	TopicSeriesStatus  = "series_status"  // SeriesHealth of one tracked series
+	TopicSeriesRemoved = "series_removed" // Series that stopped being tracked
+	TopicStatusRequest = "status_request" // asks the collector to publish every status now
+)
+
+// publishTimeout bounds a single publish so a slow bus cannot stall the publisher
+const publishTimeout = 2 * time.Second
+
+// publishQueueSize is how many collected klines wait to be published before
+// klines are dropped
+const publishQueueSize = 1024
+
+// klineMessage is a kline on the bus with the trace context of the upstream
+// message it came from
+type klineMessage struct {
+	models.Kline
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// SeriesCatalog knows which series are tracked and notifies listeners about them
+// Both the Collector and a RemoteCollector fed by the bus implement it
+type SeriesCatalog interface {
+	IsTracked(symbol, interval string) bool
+	AddListener(listener SeriesListener)
+}
+
+// CollectorPublisher publishes the events of a Collector to the bus: every
+// collected kline, removed series, and the status of every tracked series
+// at a fixed interval and on request
+// Klines are published off the stream's goroutine, from a bounded queue, so a
+// slow bus drops klines instead of stalling the stream.
+type CollectorPublisher struct {
+	bus       bus.Bus
+	collector *Collector
+	interval  time.Duration
+	failures  atomic.Uint64
+	klines    chan queuedKline
+	dropped   atomic.Uint64
+
+	// ctx bounds kline publishes; it is cancelled by Stop
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewCollectorPublisher creates a publisher and registers it with the collector
+func NewCollectorPublisher(b bus.Bus, collector *Collector, statusInterval time.Duration) (*CollectorPublisher, error) {
+	p := &CollectorPublisher{
+		bus:       b,
+		collector: collector,
+		interval:  statusInterval,
+		klines:    make(chan queuedKline, publishQueueSize),
+		done:      make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	// API servers that start after the collector ask for the tracked series
+	if err := b.Subscribe(TopicStatusRequest, func([]byte) { go p.PublishStatus() }); err != nil {
+		return nil, err
+	}
+	collector.AddListener(p)
+	return p, nil
+}
+
+// Start launches the kline publisher and the periodic status announcements
+func (p *CollectorPublisher) Start() {
+	p.wg.Add(2)
+	go p.publishKlines()
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.PublishStatus()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the kline publisher and the periodic status announcements
+// A kline being published is abandoned and klines still queued are not published.
+func (p *CollectorPublisher) Stop() {
+	p.once.Do(func() {
+		close(p.done)
+		p.cancel()
+		p.wg.Wait()
+	})
+}
+
+// OnKline queues a collected kline for publishing without blocking
+// If the queue is full the kline is dropped and counted
+func (p *CollectorPublisher) OnKline(ctx context.Context, kline models.Kline) {
+	select {
+	case p.klines <- queuedKline{kline: kline, span: trace.SpanContextFromContext(ctx)}:
+	default:
+		metrics.BusPublishDrops.Inc()
+		if p.dropped.Add(1)%1000 == 1 {
+			busLog.WarnContext(ctx, "Bus publish queue full, dropping klines", "capacity", cap(p.klines), "dropped", p.dropped.Load())
+		}
+	}
+}
+
+// publishKlines publishes queued klines until Stop is called, each in the
+// trace of the upstream message it came from
+func (p *CollectorPublisher) publishKlines() {
+	defer p.wg.Done()
+	for {
+		select {
+		case queued := <-p.klines:
+			ctx := trace.ContextWithSpanContext(p.ctx, queued.span)
+			ctx, span := busTracer.Start(ctx, "bus.publish "+TopicKlines, trace.WithSpanKind(trace.SpanKindProducer))
+			tracing.RecordError(span, p.publish(ctx, TopicKlines, klineMessage{Kline: queued.kline, Trace: tracing.Inject(ctx)}))
+			span.End()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// OnSeriesRemoved publishes a series that stopped being tracked
+func (p *CollectorPublisher) OnSeriesRemoved(series Series) {
+	p.publish(context.Background(), TopicSeriesRemoved, series)
+}
+
+// PublishStatus publishes the health of every tracked series
+func (p *CollectorPublisher) PublishStatus() {
+	for _, health := range p.collector.Health() {
+		p.publish(context.Background(), TopicSeriesStatus, health)
+	}
+}
+
+// publish encodes and sends one message, logging the first of a run of failures
+func (p *CollectorPublisher) publish(ctx context.Context, topic string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		busLog.ErrorContext(ctx, "Error encoding bus message", "topic", topic, "error", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if err := p.bus.Publish(ctx, topic, payload); err != nil {
+		if p.failures.Add(1)%100 == 1 {
+			busLog.WarnContext(ctx, "Error publishing bus message", "topic", topic, "error", err)
+		}
+		return err
+	}
+	p.failures.Store(0)
+	return nil
+}
+
+// remoteSeries is the last status received for a series
+type remoteSeries struct {
+	health     SeriesHealth
+	receivedAt time.Time
+}
+
+// RemoteCollector mirrors the tracked series and their health from collector
+// status messages on the bus and passes collected klines on to listeners
+// Series whose status stops arriving are considered removed after expireAfter,
+// so a collector that goes away does not leave subscriptions dangling forever.
+type RemoteCollector struct {
+	bus         bus.Bus
+	interval    time.Duration
+	expireAfter time.Duration
+
+	mu        sync.Mutex
+	series    map[string]remoteSeries
+	listeners []SeriesListener
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewRemoteCollector subscribes to collector events on the bus and asks the
+// collector for the current status
+// statusInterval must match the collector's announcement interval
+func NewRemoteCollector(b bus.Bus, statusInterval time.Duration) (*RemoteCollector, error) {
+	r := &RemoteCollector{
+		bus:         b,
+		interval:    statusInterval,
+		expireAfter: max(12*statusInterval, time.Minute),
+		series:      make(map[string]remoteSeries),
+		done:        make(chan struct{}),
+	}
+
+	subscriptions := map[string]bus.Handler{
+		TopicKlines:        r.handleKline,
+		TopicSeriesStatus:  r.handleStatus,
+		TopicSeriesRemoved: r.handleRemoved,
+	}
+	for topic, handler := range subscriptions {
+		if err := b.Subscribe(topic, handler); err != nil {
+			return nil, err
+		}
+	}
+
+	r.RequestStatus()
+	return r, nil
+}
+
+// RequestStatus asks the collector to publish the status of every tracked series
+func (r *RemoteCollector) RequestStatus() {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := r.bus.Publish(ctx, TopicStatusRequest, []byte("{}")); err != nil {
+		busLog.Warn("Error requesting collector status", "error", err)
+	}
+}
+
+// Start launches the loop that expires series the collector no longer reports
+func (r *RemoteCollector) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.expire(time.Now())
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the expiry loop
+func (r *RemoteCollector) Stop() {
+	r.once.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+	})
+}
+
+// AddListener registers a listener for collected klines and removed series
+func (r *RemoteCollector) AddListener(listener SeriesListener) {
+	r.mu.Lock()
+	r.listeners = append(r.listeners, listener)
+	r.mu.Unlock()
+}
+
+// IsTracked reports whether the collector tracks the series
+func (r *RemoteCollector) IsTracked(symbol, interval string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.series[Series{Symbol: symbol, Interval: interval}.Key()]
+	return ok
+}
+
+// Health returns the last reported health of every tracked series
+// A series whose status is overdue is reported as unhealthy
+func (r *RemoteCollector) Health() []SeriesHealth {
+	now := time.Now()
+	overdue := 2*r.interval + staleGrace
+
+	r.mu.Lock()
+	health := make([]SeriesHealth, 0, len(r.series))
+	for _, series := range r.series {
+		h := series.health
+		if now.Sub(series.receivedAt) > overdue {
+			h.Healthy = false
+		}
+		health = append(health, h)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(health, func(i, j int) bool {
+		if health[i].Symbol != health[j].Symbol {
+			return health[i].Symbol < health[j].Symbol
+		}
+		return health[i].Interval < health[j].Interval
+	})
+	return health
+}
+
+// handleKline passes a collected kline on to listeners in the trace of the
+// upstream message
+func (r *RemoteCollector) handleKline(payload []byte) {
+	var msg klineMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		busLog.Error("Error decoding bus message", "topic", TopicKlines, "error", err)
+		return
+	}
+
+	ctx, span := busTracer.Start(tracing.Extract(context.Background(), msg.Trace), "bus.receive "+TopicKlines,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.Int64("kline.open_time", msg.OpenTime)),
+		seriesAttributes(Series{Symbol: msg.Symbol, Interval: msg.Interval}))
+	defer span.End()
+
+	r.mu.Lock()
+	listeners := r.listeners
+	r.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener.OnKline(ctx, msg.Kline)
+	}
+}
+
+// handleStatus records the health of a tracked series
+func (r *RemoteCollector) handleStatus(payload []byte) {
+	var health SeriesHealth
+	if err := json.Unmarshal(payload, &health); err != nil {
+		busLog.Error("Error decoding bus message", "topic", TopicSeriesStatus, "error", err)
+		return
+	}
+
+	series := Series{Symbol: health.Symbol, Interval: health.Interval}
+	r.mu.Lock()
+	r.series[series.Key()] = remoteSeries{health: health, receivedAt: time.Now()}
+	r.mu.Unlock()
+}
+
+// handleRemoved forgets a series and notifies listeners
+func (r *RemoteCollector) handleRemoved(payload []byte) {
+	var series Series
+	if err := json.Unmarshal(payload, &series); err != nil {
+		busLog.Error("Error decoding bus message", "topic", TopicSeriesRemoved, "error", err)
+		return
+	}
+	r.remove([]Series{series})
+}
+
+// expire removes series whose status has not been refreshed within expireAfter
+func (r *RemoteCollector) expire(now time.Time) {
+	var expired []Series
+	r.mu.Lock()
+	for _, series := range r.series {
+		if now.Sub(series.receivedAt) > r.expireAfter {
+			expired = append(expired, Series{Symbol: series.health.Symbol, Interval: series.health.Interval})
+		}
+	}
+	r.mu.Unlock()
+
+	if len(expired) > 0 {
+		busLog.Warn("No collector status, treating series as removed", "series", len(expired), "expire_after", r.expireAfter.String())
+		r.remove(expired)
+	}
+}
+
+// remove forgets series and tells listeners about those that were known
+func (r *RemoteCollector) remove(series []Series) {
+	var removed []Series
+	r.mu.Lock()
+	for _, s := range series {
+		if _, ok := r.series[s.Key()]; ok {
+			delete(r.series, s.Key())
+			removed = append(removed, s)
+		}
+	}
+	listeners := r.listeners
+	r.mu.Unlock()
+
+	for _, s := range removed {
+		for _, listener := range listeners {
+			listener.OnSeriesRemoved(s)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/bus"
+	"crypto-monitor/internal/config"
+)
+
+// setupTestBridge connects a collector to a RemoteCollector over an in-memory bus
+func setupTestBridge(t *testing.T) (*Collector, *fakeSource, *CollectorPublisher, *RemoteCollector) {
+	t.Helper()
+	collector, source, _, _ := setupTestCollector(t)
+	b := bus.NewMemory()
+	t.Cleanup(func() { b.Close() })
+
+	// The remote comes first, so its status request is not answered in the
+	// background while a test is running
+	remote, err := NewRemoteCollector(b, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create remote collector: %v", err)
+	}
+	remote.Start()
+	t.Cleanup(remote.Stop)
+
+	publisher, err := NewCollectorPublisher(b, collector, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create publisher: %v", err)
+	}
+	publisher.Start()
+	t.Cleanup(publisher.Stop)
+	return collector, source, publisher, remote
+}
+
+// TestRemoteCollector_Status tests that tracked series are mirrored from status messages
+func TestRemoteCollector_Status(t *testing.T) {
+	collector, _, publisher, remote := setupTestBridge(t)
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT", "ETHUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if remote.IsTracked("BTCUSDT", "1m") {
+		t.Fatal("Expected series to be unknown before a status is published")
+	}
+
+	publisher.PublishStatus()
+	if !remote.IsTracked("BTCUSDT", "1m") || !remote.IsTracked("ETHUSDT", "1m") {
+		t.Fatalf("Expected published series to be tracked, got %+v", remote.Health())
+	}
+	if health := remote.Health(); len(health) != 2 || health[0].Symbol != "BTCUSDT" {
+		t.Errorf("Expected sorted health of 2 series, got %+v", health)
+	}
+}
+
+// TestRemoteCollector_Klines tests that collected klines reach remote listeners
+func TestRemoteCollector_Klines(t *testing.T) {
+	collector, source, _, remote := setupTestBridge(t)
+	listener := &recordingListener{}
+	remote.AddListener(listener)
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if !waitFor(t, time.Second, func() bool { return source.emit(context.Background(), testKline(42)) }) {
+		t.Fatal("Expected stream to start")
+	}
+
+	// Klines are published asynchronously
+	waitFor(t, time.Second, func() bool {
+		listener.mu.Lock()
+		defer listener.mu.Unlock()
+		return len(listener.klines) > 0
+	})
+	listener.mu.Lock()
+	defer listener.mu.Unlock()
+	if len(listener.klines) != 1 || listener.klines[0].OpenTime != 42 || listener.klines[0].ClosePrice != 50000 {
+		t.Errorf("Expected remote listener to receive the kline, got %+v", listener.klines)
+	}
+}
+
+// stalledBus is a bus whose publishes block until their context is done
+type stalledBus struct{}
+
+func (stalledBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (stalledBus) Subscribe(topic string, handler bus.Handler) error { return nil }
+
+func (stalledBus) Close() error { return nil }
+
+// TestCollectorPublisher_SlowBus tests that a stalled bus drops klines instead
+// of blocking the stream that collects them
+func TestCollectorPublisher_SlowBus(t *testing.T) {
+	collector, _, _, _ := setupTestCollector(t)
+	publisher, err := NewCollectorPublisher(stalledBus{}, collector, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create publisher: %v", err)
+	}
+	publisher.Start()
+	t.Cleanup(publisher.Stop)
+
+	start := time.Now()
+	for i := int64(0); i < publishQueueSize+10; i++ {
+		publisher.OnKline(context.Background(), testKline(i))
+	}
+	if elapsed := time.Since(start); elapsed > publishTimeout/2 {
+		t.Errorf("Expected OnKline not to block on the bus, took %v", elapsed)
+	}
+
+	// One kline is being published, the queue is full and the rest are dropped
+	if dropped := publisher.dropped.Load(); dropped < 9 || dropped > 10 {
+		t.Errorf("Expected 9 or 10 klines dropped, got %d", dropped)
+	}
+}
+
+// TestRemoteCollector_Removed tests removal notifications and expiry of silent series
+func TestRemoteCollector_Removed(t *testing.T) {
+	collector, _, publisher, remote := setupTestBridge(t)
+	listener := &recordingListener{}
+	remote.AddListener(listener)
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT", "ETHUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	publisher.PublishStatus()
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if remote.IsTracked("ETHUSDT", "1m") {
+		t.Error("Expected removed series to be forgotten")
+	}
+
+	// A collector that stops reporting has its series expire
+	remote.expire(time.Now().Add(remote.expireAfter + time.Second))
+	if remote.IsTracked("BTCUSDT", "1m") {
+		t.Error("Expected series without status to expire")
+	}
+
+	listener.mu.Lock()
+	defer listener.mu.Unlock()
+	if len(listener.removed) != 2 || listener.removed[0].Key() != "ETHUSDT:1m" || listener.removed[1].Key() != "BTCUSDT:1m" {
+		t.Errorf("Expected listeners to be told about both removals, got %+v", listener.removed)
+	}
+}
+
+// TestRemoteCollector_Overdue tests that a series with an overdue status is unhealthy
+func TestRemoteCollector_Overdue(t *testing.T) {
+	_, _, _, remote := setupTestBridge(t)
+
+	remote.series["BTCUSDT:1m"] = remoteSeries{
+		health:     SeriesHealth{Symbol: "BTCUSDT", Interval: "1m", State: SeriesStateStreaming, Healthy: true},
+		receivedAt: time.Now().Add(-3 * remote.interval),
+	}
+	if health := remote.Health(); len(health) != 1 || health[0].Healthy {
+		t.Errorf("Expected overdue series to be unhealthy, got %+v", health)
+	}
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/models"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource serves generated history and keeps streams open until cancelled
+type fakeSource struct {
+	mu      sync.Mutex
+	active  map[string]bool
+	streams map[string]func(context.Context, models.Kline)
+	// hang, when set, keeps cancelled streams open until it is closed
+	hang chan struct{}
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{active: make(map[string]bool), streams: make(map[string]func(context.Context, models.Kline))}
+}
+
+func (f *fakeSource) GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error) {
+	duration, _ := models.IntervalDuration(interval)
+	step := duration.Milliseconds()
+	now := time.Now().UnixMilli()
+
+	var klines []models.Kline
+	for openTime := (*startTime + step - 1) / step * step; openTime < now && len(klines) < limit; openTime += step {
+		klines = append(klines, models.Kline{Symbol: symbol, Interval: interval, OpenTime: openTime, CloseTime: openTime + step - 1})
+	}
+	return klines, nil
+}
+
+func (f *fakeSource) SubscribeKlineStream(ctx context.Context, symbol, interval string, callback func(context.Context, models.Kline)) error {
+	key := Series{Symbol: symbol, Interval: interval}.Key()
+	f.mu.Lock()
+	f.active[key] = true
+	f.streams[key] = callback
+	f.mu.Unlock()
+
+	<-ctx.Done()
+	if f.hang != nil {
+		<-f.hang
+	}
+
+	f.mu.Lock()
+	delete(f.active, key)
+	delete(f.streams, key)
+	f.mu.Unlock()
+	return ctx.Err()
+}
+
+func (f *fakeSource) isActive(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active[key]
+}
+
+// emit delivers a kline on an open stream as part of the trace in ctx
+func (f *fakeSource) emit(ctx context.Context, kline models.Kline) bool {
+	f.mu.Lock()
+	callback := f.streams[Series{Symbol: kline.Symbol, Interval: kline.Interval}.Key()]
+	f.mu.Unlock()
+	if callback == nil {
+		return false
+	}
+	callback(ctx, kline)
+	return true
+}
+
+// fakeBackfillStore counts backfilled klines per series
+type fakeBackfillStore struct {
+	mu     sync.Mutex
+	latest map[string]int64
+	stored map[string]int
+}
+
+func newFakeBackfillStore() *fakeBackfillStore {
+	return &fakeBackfillStore{latest: make(map[string]int64), stored: make(map[string]int)}
+}
+
+func (f *fakeBackfillStore) GetLatestOpenTime(ctx context.Context, symbol, interval string) (int64, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	latest, ok := f.latest[Series{Symbol: symbol, Interval: interval}.Key()]
+	return latest, ok, nil
+}
+
+func (f *fakeBackfillStore) BulkUpsertKlines(ctx context.Context, klines []models.Kline) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range klines {
+		key := Series{Symbol: k.Symbol, Interval: k.Interval}.Key()
+		f.stored[key]++
+		if k.OpenTime > f.latest[key] {
+			f.latest[key] = k.OpenTime
+		}
+	}
+	return nil
+}
+
+func (f *fakeBackfillStore) count(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stored[key]
+}
+
+// recordingListener records collected klines and removed series
+type recordingListener struct {
+	mu      sync.Mutex
+	klines  []models.Kline
+	removed []Series
+}
+
+func (r *recordingListener) OnKline(ctx context.Context, kline models.Kline) {
+	r.mu.Lock()
+	r.klines = append(r.klines, kline)
+	r.mu.Unlock()
+}
+
+func (r *recordingListener) OnSeriesRemoved(series Series) {
+	r.mu.Lock()
+	r.removed = append(r.removed, series)
+	r.mu.Unlock()
+}
+
+func setupTestCollector(t *testing.T) (*Collector, *fakeSource, *fakeBackfillStore, *fakeBatchStore) {
+	t.Helper()
+	batchStore := &fakeBatchStore{}
+	writer, err := NewKlineWriter(batchStore, config.WriterConfig{
+		BatchSize:     1,
+		FlushInterval: config.Duration(20 * time.Millisecond),
+		JournalPath:   filepath.Join(t.TempDir(), "klines.ndjson"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	writer.Start()
+	t.Cleanup(func() { writer.Close(context.Background()) })
+
+	source := newFakeSource()
+	store := newFakeBackfillStore()
+	collector := NewCollector(source, store, writer)
+	t.Cleanup(func() { collector.Stop(context.Background()) })
+	return collector, source, store, batchStore
+}
+
+// TestCollector_Apply tests starting, stopping and keeping streams across reloads
+func TestCollector_Apply(t *testing.T) {
+	collector, source, _, _ := setupTestCollector(t)
+	listener := &recordingListener{}
+	collector.AddListener(listener)
+
+	change, err := collector.Apply(config.TrackingConfig{Symbols: []string{"btcusdt", "ETHUSDT"}, Intervals: []string{"1m"}})
+	if err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if len(change.Added) != 2 || len(change.Removed) != 0 {
+		t.Fatalf("Expected 2 added series, got %+v", change)
+	}
+	if !waitFor(t, time.Second, func() bool { return source.isActive("BTCUSDT:1m") && source.isActive("ETHUSDT:1m") }) {
+		t.Fatal("Expected streams to start for tracked series")
+	}
+
+	change, err = collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m", "5m"}})
+	if err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if len(change.Added) != 1 || change.Added[0].Key() != "BTCUSDT:5m" {
+		t.Errorf("Expected BTCUSDT:5m to be added, got %+v", change.Added)
+	}
+	if len(change.Removed) != 1 || change.Removed[0].Key() != "ETHUSDT:1m" {
+		t.Errorf("Expected ETHUSDT:1m to be removed, got %+v", change.Removed)
+	}
+
+	// Apply waits for removed streams, so ETHUSDT must already be closed
+	if source.isActive("ETHUSDT:1m") {
+		t.Error("Expected stream of removed series to be stopped")
+	}
+	if !source.isActive("BTCUSDT:1m") {
+		t.Error("Expected unchanged series to keep its stream")
+	}
+	if !collector.IsTracked("BTCUSDT", "5m") || collector.IsTracked("ETHUSDT", "1m") {
+		t.Errorf("Unexpected tracked series: %+v", collector.Series())
+	}
+
+	listener.mu.Lock()
+	removed := listener.removed
+	listener.mu.Unlock()
+	if len(removed) != 1 || removed[0].Key() != "ETHUSDT:1m" {
+		t.Errorf("Expected listener to be told ETHUSDT:1m was removed, got %+v", removed)
+	}
+}
+
+// TestCollector_ApplyInvalid tests that an invalid configuration leaves streams untouched
+func TestCollector_ApplyInvalid(t *testing.T) {
+	collector, _, _, _ := setupTestCollector(t)
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"7m"}}); err == nil {
+		t.Fatal("Expected error for unsupported interval")
+	}
+	if !collector.IsTracked("BTCUSDT", "1m") {
+		t.Error("Expected previous tracking to remain after a rejected update")
+	}
+}
+
+// TestCollector_Backfill tests that added series are backfilled from the latest stored kline
+func TestCollector_Backfill(t *testing.T) {
+	collector, source, store, _ := setupTestCollector(t)
+
+	// ETHUSDT already has klines up to ten minutes ago
+	now := time.Now().UnixMilli()
+	store.latest["ETHUSDT:1m"] = now - now%60000 - 10*60000
+
+	_, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT", "ETHUSDT"}, Intervals: []string{"1m"}, BackfillLimit: 30})
+	if err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if !waitFor(t, time.Second, func() bool { return source.isActive("BTCUSDT:1m") && source.isActive("ETHUSDT:1m") }) {
+		t.Fatal("Expected streams to start after backfill")
+	}
+
+	// The open candle is left to the stream, so the counts may be one lower
+	if n := store.count("BTCUSDT:1m"); n < 29 || n > 30 {
+		t.Errorf("Expected about 30 backfilled BTCUSDT klines, got %d", n)
+	}
+	if n := store.count("ETHUSDT:1m"); n < 9 || n > 10 {
+		t.Errorf("Expected about 10 backfilled ETHUSDT klines, got %d", n)
+	}
+}
+
+// TestCollector_StreamedKlines tests that streamed klines are written and passed to listeners
+func TestCollector_StreamedKlines(t *testing.T) {
+	collector, source, _, batchStore := setupTestCollector(t)
+	listener := &recordingListener{}
+	collector.AddListener(listener)
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if !waitFor(t, time.Second, func() bool { return source.emit(context.Background(), testKline(42)) }) {
+		t.Fatal("Expected stream to start")
+	}
+
+	if !waitFor(t, time.Second, func() bool { return len(batchStore.openTimes()) == 1 }) {
+		t.Fatalf("Expected streamed kline to be written, got %v", batchStore.openTimes())
+	}
+	listener.mu.Lock()
+	defer listener.mu.Unlock()
+	if len(listener.klines) != 1 || listener.klines[0].OpenTime != 42 {
+		t.Errorf("Expected listener to receive the streamed kline, got %+v", listener.klines)
+	}
+}
+
+// TestCollector_Health tests per-series state and staleness reporting
+func TestCollector_Health(t *testing.T) {
+	collector, source, _, _ := setupTestCollector(t)
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1s"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if !waitFor(t, time.Second, func() bool { return source.isActive("BTCUSDT:1s") }) {
+		t.Fatal("Expected stream to start")
+	}
+
+	health := collector.Health()
+	if len(health) != 1 {
+		t.Fatalf("Expected health of 1 series, got %+v", health)
+	}
+	if health[0].State != SeriesStateStreaming || !health[0].Healthy {
+		t.Errorf("Expected healthy streaming series, got %+v", health[0])
+	}
+
+	kline := models.Kline{Symbol: "BTCUSDT", Interval: "1s", OpenTime: 1000, CloseTime: 1999}
+	source.emit(context.Background(), kline)
+	health = collector.Health()
+	if health[0].Klines != 1 || health[0].LastOpenTime != 1000 || health[0].LastKlineAt == nil {
+		t.Errorf("Expected received kline to be recorded, got %+v", health[0])
+	}
+
+	// Without klines for longer than two intervals plus grace the series is stale
+	stream := collector.streams["BTCUSDT:1s"]
+	if snapshot := stream.health.snapshot(time.Now().Add(2*time.Second + staleGrace + time.Second)); snapshot.Healthy {
+		t.Errorf("Expected stale series to be unhealthy, got %+v", snapshot)
+	}
+}
+
+// TestCollector_Stop tests that Stop closes every stream and gives up on streams
+// that do not close before ctx expires
+func TestCollector_Stop(t *testing.T) {
+	collector, source, _, _ := setupTestCollector(t)
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT", "ETHUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if !waitFor(t, time.Second, func() bool { return source.isActive("BTCUSDT:1m") && source.isActive("ETHUSDT:1m") }) {
+		t.Fatal("Expected streams to start")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := collector.Stop(ctx); err != nil {
+		t.Fatalf("Expected streams to stop in time, got %v", err)
+	}
+	if source.isActive("BTCUSDT:1m") || source.isActive("ETHUSDT:1m") {
+		t.Error("Expected every stream to be closed")
+	}
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err == nil {
+		t.Error("Expected Apply to fail after Stop")
+	}
+
+	// A stream that ignores cancellation must not hold up shutdown past the deadline
+	stuck, stuckSource, _, _ := setupTestCollector(t)
+	stuckSource.hang = make(chan struct{})
+	defer close(stuckSource.hang)
+	if _, err := stuck.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	if !waitFor(t, time.Second, func() bool { return stuckSource.isActive("BTCUSDT:1m") }) {
+		t.Fatal("Expected stream to start")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := stuck.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected Stop to return at the deadline, took %v", elapsed)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"crypto-monitor/internal/models"
+)
+
+// freshnessGrace is how long after a candle closes it may take to be stored
+// before its series is reported as stale
+const freshnessGrace = 30 * time.Second
+
+// weekOffset is how far weekly candles open before the Unix epoch week
+// boundaries: Binance weeks open on Monday, three days before the Thursday the
+// epoch fell on
+const weekOffset = 3 * 24 * time.Hour
+
+// LatestOpenTimeStore reports the newest stored kline of a series
+type LatestOpenTimeStore interface {
+	GetLatestOpenTime(ctx context.Context, symbol, interval string) (int64, bool, error)
+}
+
+// SeriesFreshness compares the newest stored kline of a series with the most
+// recently closed candle
+type SeriesFreshness struct {
+	Symbol   string `json:"symbol"`
+	Interval string `json:"interval"`
+	// LatestOpenTime is the open time of the newest stored kline, 0 if none
+	LatestOpenTime int64 `json:"latest_open_time"`
+	// ExpectedOpenTime is the open time of the most recently closed candle
+	ExpectedOpenTime int64 `json:"expected_open_time"`
+	// Missing is the number of closed candles after the newest stored one
+	Missing int64 `json:"missing"`
+	// LagSeconds is how long ago the oldest missing candle closed
+	LagSeconds float64 `json:"lag_seconds"`
+	Stale      bool    `json:"stale"`
+}
+
+// CheckFreshness reports how far the stored klines of each series trail the
+// exchange; a series is stale when a closed candle has not been stored within
+// freshnessGrace of closing, or when it has no klines at all
+func CheckFreshness(ctx context.Context, store LatestOpenTimeStore, series []Series, now time.Time) ([]SeriesFreshness, error) {
+	nowMs := now.UnixMilli()
+	result := make([]SeriesFreshness, 0, len(series))
+
+	for _, s := range series {
+		duration, err := models.IntervalDuration(s.Interval)
+		if err != nil {
+			return nil, err
+		}
+		step := duration.Milliseconds()
+
+		latest, ok, err := store.GetLatestOpenTime(ctx, s.Symbol, s.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest kline of %s %s: %w", s.Symbol, s.Interval, err)
+		}
+
+		f := SeriesFreshness{
+			Symbol:           s.Symbol,
+			Interval:         s.Interval,
+			ExpectedOpenTime: candleOpenTime(nowMs, duration) - step,
+		}
+		if !ok {
+			f.Stale = true
+			result = append(result, f)
+			continue
+		}
+
+		f.LatestOpenTime = latest
+		if latest < f.ExpectedOpenTime {
+			f.Missing = (f.ExpectedOpenTime - latest) / step
+			// The oldest missing candle opened one step after the latest stored one
+			oldestMissingClose := latest + 2*step
+			lag := time.Duration(nowMs-oldestMissingClose) * time.Millisecond
+			f.LagSeconds = lag.Seconds()
+			f.Stale = lag > freshnessGrace
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// candleOpenTime returns the open time of the candle of length interval that
+// contains the Unix millisecond time ms
+// Candles open at multiples of their length from the Unix epoch, except weekly
+// candles, which open on Monday.
+func candleOpenTime(ms int64, interval time.Duration) int64 {
+	step := interval.Milliseconds()
+	var offset int64
+	if interval == 7*24*time.Hour {
+		offset = weekOffset.Milliseconds()
+	}
+	return ms - (ms+offset)%step
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestCheckFreshness tests lag and staleness of stored series
+func TestCheckFreshness(t *testing.T) {
+	store := newFakeBackfillStore()
+	now := time.Date(2024, 1, 1, 12, 30, 45, 0, time.UTC)
+	minute := int64(60000)
+	lastClosed := time.Date(2024, 1, 1, 12, 29, 0, 0, time.UTC).UnixMilli()
+
+	store.latest["BTCUSDT:1m"] = lastClosed             // up to date
+	store.latest["ETHUSDT:1m"] = lastClosed - minute    // one candle behind, closed 45s ago
+	store.latest["BNBUSDT:1m"] = lastClosed - 10*minute // ten candles behind
+
+	series := []Series{
+		{Symbol: "BTCUSDT", Interval: "1m"},
+		{Symbol: "ETHUSDT", Interval: "1m"},
+		{Symbol: "BNBUSDT", Interval: "1m"},
+		{Symbol: "SOLUSDT", Interval: "1m"},
+	}
+	freshness, err := CheckFreshness(context.Background(), store, series, now)
+	if err != nil {
+		t.Fatalf("Failed to check freshness: %v", err)
+	}
+
+	tests := []struct {
+		missing int64
+		stale   bool
+	}{
+		{0, false},
+		{1, true},
+		{10, true},
+		{0, true}, // no klines stored
+	}
+	for i, tt := range tests {
+		f := freshness[i]
+		if f.ExpectedOpenTime != lastClosed {
+			t.Errorf("%s: expected open time %d, got %d", f.Symbol, lastClosed, f.ExpectedOpenTime)
+		}
+		if f.Missing != tt.missing || f.Stale != tt.stale {
+			t.Errorf("%s: expected missing=%d stale=%v, got %+v", f.Symbol, tt.missing, tt.stale, f)
+		}
+	}
+
+	// A candle that closed moments ago may still be in the write queue
+	freshness, _ = CheckFreshness(context.Background(), store, series[1:2], time.Date(2024, 1, 1, 12, 30, 5, 0, time.UTC))
+	if freshness[0].Missing != 1 || freshness[0].Stale {
+		t.Errorf("Expected a just-closed candle within grace, got %+v", freshness[0])
+	}
+}
+
+// TestCheckFreshness_Weekly tests that weekly candles are expected to open on
+// Monday, as Binance weeks do
+func TestCheckFreshness_Weekly(t *testing.T) {
+	store := newFakeBackfillStore()
+	// A Wednesday; the last closed week opened on Monday 2023-12-25
+	now := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+	lastClosed := time.Date(2023, 12, 25, 0, 0, 0, 0, time.UTC).UnixMilli()
+	week := (7 * 24 * time.Hour).Milliseconds()
+
+	store.latest["BTCUSDT:1w"] = lastClosed
+	store.latest["ETHUSDT:1w"] = lastClosed - week
+
+	series := []Series{
+		{Symbol: "BTCUSDT", Interval: "1w"},
+		{Symbol: "ETHUSDT", Interval: "1w"},
+	}
+	freshness, err := CheckFreshness(context.Background(), store, series, now)
+	if err != nil {
+		t.Fatalf("Failed to check freshness: %v", err)
+	}
+
+	for _, f := range freshness {
+		if f.ExpectedOpenTime != lastClosed {
+			t.Errorf("%s: expected open time %d, got %d", f.Symbol, lastClosed, f.ExpectedOpenTime)
+		}
+	}
+	if f := freshness[0]; f.Missing != 0 || f.Stale {
+		t.Errorf("Expected the up to date series to be fresh, got %+v", f)
+	}
+	if f := freshness[1]; f.Missing != 1 || !f.Stale {
+		t.Errorf("Expected the series a week behind to be stale, got %+v", f)
+	}
+}
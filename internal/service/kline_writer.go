@@ -0,0 +1,551 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/models"
+	"crypto-monitor/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	writerLog    = logging.For(logging.Writer)
+	writerTracer = tracing.Tracer(logging.Writer)
+)
+
+// KlineBatchStore persists batches of klines with upsert semantics
+type KlineBatchStore interface {
+	BulkUpsertKlines(ctx context.Context, klines []models.Kline) error
+	IsConnected(ctx context.Context) bool
+}
+
+// queuedKline is a kline waiting to be written with the span that enqueued it
+type queuedKline struct {
+	kline models.Kline
+	span  trace.SpanContext
+}
+
+// KlineWriterStats is a snapshot of write pipeline counters
+type KlineWriterStats struct {
+	QueueDepth     int    `json:"queue_depth"`
+	QueueCapacity  int    `json:"queue_capacity"`
+	Enqueued       uint64 `json:"enqueued"`
+	Written        uint64 `json:"written"`
+	Spilled        uint64 `json:"spilled"`
+	Replayed       uint64 `json:"replayed"`
+	JournalPending int64  `json:"journal_pending"`
+	WriteFailures  uint64 `json:"write_failures"`
+	Dropped        uint64 `json:"dropped"`
+	DatabaseDown   bool   `json:"database_down"`
+}
+
+// KlineWriter batches klines in memory and writes them to the database off the
+// caller's goroutine. While the database is unreachable, or when the in-memory
+// queue backs up or overflows, batches are appended to an on-disk journal instead. The journal
+// is replayed in order once the database recovers, and all writes after the first
+// spill go through the journal until it is drained, so rows reach the database in
+// the order they were enqueued.
+// Each database write is traced as a span linked to the spans that enqueued
+// the klines of its batch.
+type KlineWriter struct {
+	store KlineBatchStore
+	cfg   config.WriterConfig
+	queue chan queuedKline
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+
+	// overflow holds klines enqueued while the queue was full, until the run
+	// goroutine journals them; overflowed signals it
+	overflowMu sync.Mutex
+	overflow   []queuedKline
+	overflowed chan struct{}
+
+	// ctx bounds database writes; it is cancelled when Close runs out of time
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Journal state, owned by the run goroutine
+	journal       *os.File
+	journalWriter *bufio.Writer
+	replayReader  *bufio.Reader
+	replayFile    *os.File
+	replayPass    int64
+	dbDown        bool
+	nextRetry     time.Time
+
+	enqueued       atomic.Uint64
+	written        atomic.Uint64
+	spilled        atomic.Uint64
+	replayed       atomic.Uint64
+	journalPending atomic.Int64
+	writeFailures  atomic.Uint64
+	dropped        atomic.Uint64
+	databaseDown   atomic.Bool
+}
+
+// NewKlineWriter creates a KlineWriter and opens its journal
+// Klines left in the journal by a previous run are replayed once Start is called
+func NewKlineWriter(store KlineBatchStore, cfg config.WriterConfig) (*KlineWriter, error) {
+	defaults := config.Default().Writer
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaults.FlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaults.QueueSize
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaults.RetryInterval
+	}
+	if cfg.JournalPath == "" {
+		cfg.JournalPath = defaults.JournalPath
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.JournalPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	journal, err := os.OpenFile(cfg.JournalPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	w := &KlineWriter{
+		store:         store,
+		cfg:           cfg,
+		queue:         make(chan queuedKline, cfg.QueueSize),
+		done:          make(chan struct{}),
+		overflowed:    make(chan struct{}, 1),
+		journal:       journal,
+		journalWriter: bufio.NewWriter(journal),
+	}
+
+	pending, err := countJournalRecords(cfg.JournalPath)
+	if err != nil {
+		journal.Close()
+		return nil, err
+	}
+	w.storePending(pending)
+	if pending > 0 {
+		writerLog.Info("Kline journal has pending klines to replay", "pending", pending)
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+
+	return w, nil
+}
+
+// Enqueue submits a kline for writing without blocking
+// If the in-memory queue is full the kline is handed to the run goroutine to be
+// journaled, along with the klines enqueued after it until the overflow is
+// journaled, so the order of the klines is kept. Only when the overflow is
+// full too, because the run goroutine is stuck on a database write, is the
+// kline dropped and counted in Stats.
+func (w *KlineWriter) Enqueue(ctx context.Context, kline models.Kline) {
+	queued := queuedKline{kline: kline, span: trace.SpanContextFromContext(ctx)}
+
+	w.overflowMu.Lock()
+	defer w.overflowMu.Unlock()
+	if len(w.overflow) == 0 {
+		select {
+		case w.queue <- queued:
+			w.enqueued.Add(1)
+			return
+		default:
+		}
+	}
+
+	if len(w.overflow) >= cap(w.queue) {
+		metrics.WriterDropped.Inc()
+		if w.dropped.Add(1)%1000 == 1 {
+			writerLog.Warn("Kline write queue and overflow full, dropping klines", "capacity", w.cfg.QueueSize, "dropped", w.dropped.Load())
+		}
+		return
+	}
+	if len(w.overflow) == 0 {
+		writerLog.Warn("Kline write queue full, journaling klines", "capacity", w.cfg.QueueSize)
+	}
+	w.overflow = append(w.overflow, queued)
+	w.enqueued.Add(1)
+	select {
+	case w.overflowed <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the goroutine that processes the queue until Close is called
+func (w *KlineWriter) Start() {
+	w.wg.Add(1)
+	go w.run()
+}
+
+// run batches queued klines by size and time
+func (w *KlineWriter) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.cfg.FlushInterval.Std())
+	defer ticker.Stop()
+
+	batch := make([]models.Kline, 0, w.cfg.BatchSize)
+	var links []trace.Link
+	for {
+		select {
+		case queued := <-w.queue:
+			batch, links = appendQueued(batch, links, queued)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch, links)
+				batch, links = batch[:0], nil
+			}
+
+		case <-w.overflowed:
+			// The klines batched and queued so far were enqueued before the
+			// overflow, so they are journaled with it
+			batch, links = w.drainQueue(batch, links)
+			w.spillBatch(batch, links)
+			batch, links = batch[:0], nil
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch, links)
+				batch, links = batch[:0], nil
+			}
+			w.replay()
+			metrics.WriterQueueDepth.Set(float64(len(w.queue)))
+
+		case <-w.done:
+			// Drain whatever is still queued before stopping
+			batch, links = w.drainQueue(batch, links)
+			if len(batch) > 0 {
+				w.flush(batch, links)
+			}
+			return
+		}
+	}
+}
+
+// drainQueue appends the queued klines, then the overflow, to the batch
+// The overflow lock keeps Enqueue from queueing klines meanwhile, which would
+// overtake the overflow.
+func (w *KlineWriter) drainQueue(batch []models.Kline, links []trace.Link) ([]models.Kline, []trace.Link) {
+	w.overflowMu.Lock()
+	defer w.overflowMu.Unlock()
+drain:
+	for {
+		select {
+		case queued := <-w.queue:
+			batch, links = appendQueued(batch, links, queued)
+		default:
+			break drain
+		}
+	}
+	for _, queued := range w.overflow {
+		batch, links = appendQueued(batch, links, queued)
+	}
+	w.overflow = nil
+	return batch, links
+}
+
+// appendQueued adds a queued kline to the batch and its span to the links of
+// the batch write
+func appendQueued(batch []models.Kline, links []trace.Link, queued queuedKline) ([]models.Kline, []trace.Link) {
+	if queued.span.IsValid() {
+		links = append(links, trace.Link{SpanContext: queued.span})
+	}
+	return append(batch, queued.kline), links
+}
+
+// Close stops the writer after flushing queued klines and closes the journal
+// If ctx is done before the final flush reaches the database, the write is
+// aborted and the klines are spilled to the journal, to be replayed on the
+// next start.
+func (w *KlineWriter) Close(ctx context.Context) error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		stopped := make(chan struct{})
+		go func() {
+			w.wg.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			writerLog.Warn("Kline writer did not flush in time, spilling to journal", "error", ctx.Err())
+			w.cancel()
+			<-stopped
+		}
+		w.cancel()
+
+		if ferr := w.journalWriter.Flush(); ferr != nil {
+			err = ferr
+		}
+		if w.replayFile != nil {
+			w.replayFile.Close()
+		}
+		if cerr := w.journal.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+// Stats returns a snapshot of the pipeline counters
+func (w *KlineWriter) Stats() KlineWriterStats {
+	return KlineWriterStats{
+		QueueDepth:     len(w.queue),
+		QueueCapacity:  cap(w.queue),
+		Enqueued:       w.enqueued.Load(),
+		Written:        w.written.Load(),
+		Spilled:        w.spilled.Load(),
+		Replayed:       w.replayed.Load(),
+		JournalPending: w.journalPending.Load(),
+		WriteFailures:  w.writeFailures.Load(),
+		Dropped:        w.dropped.Load(),
+		DatabaseDown:   w.databaseDown.Load(),
+	}
+}
+
+// flush writes a batch to the database, or to the journal if the journal is
+// not empty, the database is down, or the queue is backing up
+func (w *KlineWriter) flush(batch []models.Kline, links []trace.Link) {
+	ctx, span := writerTracer.Start(w.ctx, "writer.flush",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("klines.count", len(batch))))
+	defer span.End()
+
+	backlogged := len(w.queue) > cap(w.queue)/2
+	if w.journalPending.Load() == 0 && !w.dbDown && !backlogged {
+		err := w.store.BulkUpsertKlines(ctx, batch)
+		if err == nil {
+			w.written.Add(uint64(len(batch)))
+			return
+		}
+		tracing.RecordError(span, err)
+		w.markDown(err)
+	}
+
+	span.AddEvent("spilled to journal")
+	w.spill(batch)
+}
+
+// spillBatch appends a batch to the journal without trying the database, for
+// klines the queue could not hold
+func (w *KlineWriter) spillBatch(batch []models.Kline, links []trace.Link) {
+	_, span := writerTracer.Start(w.ctx, "writer.spill",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("klines.count", len(batch))))
+	defer span.End()
+
+	w.spill(batch)
+}
+
+// spill appends a batch to the journal
+func (w *KlineWriter) spill(batch []models.Kline) {
+	enc := json.NewEncoder(w.journalWriter)
+	for i, kline := range batch {
+		if err := enc.Encode(kline); err != nil {
+			lost := uint64(len(batch) - i)
+			w.dropped.Add(lost)
+			metrics.WriterDropped.Add(float64(lost))
+			writerLog.Error("Error writing kline journal, klines lost", "lost", lost, "error", err)
+			return
+		}
+		w.addPending(1)
+	}
+
+	if err := w.journalWriter.Flush(); err != nil {
+		writerLog.Error("Error flushing kline journal", "error", err)
+	}
+	w.spilled.Add(uint64(len(batch)))
+	metrics.WriterSpilled.Add(float64(len(batch)))
+}
+
+// replay writes batches from the head of the journal to the database until the
+// journal is drained or the in-memory queue needs attention
+// The journal is truncated once it has been fully replayed
+func (w *KlineWriter) replay() {
+	if w.journalPending.Load() == 0 {
+		return
+	}
+
+	if w.dbDown {
+		if time.Now().Before(w.nextRetry) {
+			return
+		}
+		if !w.store.IsConnected(w.ctx) {
+			w.nextRetry = time.Now().Add(w.cfg.RetryInterval.Std())
+			return
+		}
+		w.markUp()
+	}
+
+	if w.replayReader == nil {
+		f, err := os.Open(w.cfg.JournalPath)
+		if err != nil {
+			writerLog.Error("Error opening kline journal for replay", "error", err)
+			return
+		}
+		w.replayFile = f
+		w.replayReader = bufio.NewReader(f)
+	}
+
+	for len(w.queue) < cap(w.queue)/2 {
+		batch, consumed, err := readJournalBatch(w.replayReader, w.cfg.BatchSize)
+		if err != nil {
+			writerLog.Error("Error reading kline journal", "error", err)
+			return
+		}
+
+		if len(batch) > 0 {
+			if err := w.replayBatch(batch); err != nil {
+				// Rewind so the same records are retried after the outage
+				w.rewindReplay()
+				w.markDown(err)
+				return
+			}
+			w.replayed.Add(uint64(len(batch)))
+			w.replayPass += int64(len(batch))
+			w.addPending(-int64(len(batch)))
+		}
+
+		// Everything spilled so far has been flushed to the file by this
+		// goroutine, so reaching EOF means the journal is fully replayed
+		if consumed {
+			w.truncateJournal()
+			return
+		}
+	}
+}
+
+// replayBatch writes a batch read from the journal to the database
+// Journaled klines have lost their spans, so each replayed batch starts a new trace
+func (w *KlineWriter) replayBatch(batch []models.Kline) error {
+	ctx, span := writerTracer.Start(w.ctx, "writer.replay",
+		trace.WithAttributes(attribute.Int("klines.count", len(batch))))
+	defer span.End()
+
+	err := w.store.BulkUpsertKlines(ctx, batch)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// truncateJournal empties the journal after a complete replay
+func (w *KlineWriter) truncateJournal() {
+	w.resetReplay()
+	if err := w.journal.Truncate(0); err != nil {
+		writerLog.Error("Error truncating kline journal", "error", err)
+		return
+	}
+	w.storePending(0)
+	writerLog.Info("Kline journal fully replayed", "replayed", w.replayed.Load())
+}
+
+// rewindReplay restarts the replay from the journal head, counting the klines
+// replayed in this pass as pending again since they will be replayed again
+func (w *KlineWriter) rewindReplay() {
+	w.addPending(w.replayPass)
+	w.resetReplay()
+}
+
+// resetReplay closes the replay reader so the next replay starts from the journal head
+func (w *KlineWriter) resetReplay() {
+	if w.replayFile != nil {
+		w.replayFile.Close()
+	}
+	w.replayFile = nil
+	w.replayReader = nil
+	w.replayPass = 0
+}
+
+// addPending adjusts the number of klines in the journal waiting to be replayed
+func (w *KlineWriter) addPending(n int64) {
+	metrics.WriterJournalPending.Set(float64(w.journalPending.Add(n)))
+}
+
+// storePending sets the number of klines in the journal waiting to be replayed
+func (w *KlineWriter) storePending(n int64) {
+	w.journalPending.Store(n)
+	metrics.WriterJournalPending.Set(float64(n))
+}
+
+// markDown records a failed database write and switches to journaling
+func (w *KlineWriter) markDown(err error) {
+	w.writeFailures.Add(1)
+	if !w.dbDown {
+		writerLog.Warn("Database write failed, spilling klines to journal", "error", err)
+	}
+	w.dbDown = true
+	w.databaseDown.Store(true)
+	metrics.WriterDatabaseDown.Set(1)
+	w.nextRetry = time.Now().Add(w.cfg.RetryInterval.Std())
+}
+
+// markUp records that the database is reachable again
+func (w *KlineWriter) markUp() {
+	writerLog.Info("Database reachable again, replaying journaled klines", "pending", w.journalPending.Load())
+	w.dbDown = false
+	w.databaseDown.Store(false)
+	metrics.WriterDatabaseDown.Set(0)
+}
+
+// readJournalBatch reads up to n klines from the journal
+// consumed is true when the reader reached the end of the journal
+func readJournalBatch(r *bufio.Reader, n int) (batch []models.Kline, consumed bool, err error) {
+	for len(batch) < n {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A trailing partial line can only come from an interrupted write
+			return batch, true, nil
+		}
+		if err != nil {
+			return batch, false, err
+		}
+
+		var kline models.Kline
+		if err := json.Unmarshal(bytes.TrimSpace(line), &kline); err != nil {
+			writerLog.Warn("Skipping corrupt kline journal record", "error", err)
+			continue
+		}
+		batch = append(batch, kline)
+	}
+	return batch, false, nil
+}
+
+// countJournalRecords counts complete records in an existing journal
+func countJournalRecords(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	var count int64
+	r := bufio.NewReader(f)
+	for {
+		_, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read journal: %w", err)
+		}
+		count++
+	}
+}
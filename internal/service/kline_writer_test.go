@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/models"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBatchStore records written batches and can simulate an outage
+type fakeBatchStore struct {
+	mu      sync.Mutex
+	down    bool
+	batches [][]models.Kline
+	// hang makes writes block until their context is done
+	hang bool
+	// gate, when set, makes writes block until it is closed
+	gate chan struct{}
+	// failures fail that many writes of batches starting at open time failAt
+	failAt   int64
+	failures int
+}
+
+func (f *fakeBatchStore) BulkUpsertKlines(ctx context.Context, klines []models.Kline) error {
+	if f.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return errors.New("connection refused")
+	}
+	if f.failures > 0 && klines[0].OpenTime == f.failAt {
+		f.failures--
+		return errors.New("connection reset")
+	}
+	f.batches = append(f.batches, append([]models.Kline(nil), klines...))
+	return nil
+}
+
+func (f *fakeBatchStore) IsConnected(ctx context.Context) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.down
+}
+
+func (f *fakeBatchStore) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+}
+
+// failBatch fails the next n writes of batches starting at open time
+func (f *fakeBatchStore) failBatch(openTime int64, n int) {
+	f.mu.Lock()
+	f.failAt, f.failures = openTime, n
+	f.mu.Unlock()
+}
+
+// openTimes returns the open times of all written klines in write order
+func (f *fakeBatchStore) openTimes() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var times []int64
+	for _, batch := range f.batches {
+		for _, k := range batch {
+			times = append(times, k.OpenTime)
+		}
+	}
+	return times
+}
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+func testKline(openTime int64) models.Kline {
+	return models.Kline{Symbol: "BTCUSDT", Interval: "1m", OpenTime: openTime, CloseTime: openTime + 59999, ClosePrice: 50000}
+}
+
+// TestKlineWriter_Batching tests flushing by batch size and by interval
+func TestKlineWriter_Batching(t *testing.T) {
+	store := &fakeBatchStore{}
+	writer, err := NewKlineWriter(store, config.WriterConfig{
+		BatchSize:     3,
+		FlushInterval: config.Duration(100 * time.Millisecond),
+		JournalPath:   filepath.Join(t.TempDir(), "klines.ndjson"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	writer.Start()
+	defer writer.Close(context.Background())
+
+	for i := int64(0); i < 7; i++ {
+		writer.Enqueue(context.Background(), testKline(i))
+	}
+
+	if !waitFor(t, time.Second, func() bool { return len(store.openTimes()) == 7 }) {
+		t.Fatalf("Expected 7 klines written, got %d", len(store.openTimes()))
+	}
+
+	store.mu.Lock()
+	sizes := []int{len(store.batches[0]), len(store.batches[1]), len(store.batches[2])}
+	store.mu.Unlock()
+	if sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Errorf("Expected batches of 3, 3 and 1, got %v", sizes)
+	}
+
+	if stats := writer.Stats(); stats.Written != 7 || stats.Spilled != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestKlineWriter_OutageSpillAndReplay tests journaling during an outage and in-order replay
+func TestKlineWriter_OutageSpillAndReplay(t *testing.T) {
+	store := &fakeBatchStore{}
+	writer, err := NewKlineWriter(store, config.WriterConfig{
+		BatchSize:     2,
+		FlushInterval: config.Duration(20 * time.Millisecond),
+		RetryInterval: config.Duration(20 * time.Millisecond),
+		JournalPath:   filepath.Join(t.TempDir(), "klines.ndjson"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	writer.Start()
+	defer writer.Close(context.Background())
+
+	store.setDown(true)
+	for i := int64(0); i < 5; i++ {
+		writer.Enqueue(context.Background(), testKline(i))
+	}
+
+	if !waitFor(t, time.Second, func() bool { return writer.Stats().JournalPending == 5 }) {
+		t.Fatalf("Expected 5 klines journaled, got %+v", writer.Stats())
+	}
+	if !writer.Stats().DatabaseDown {
+		t.Error("Expected writer to report the database as down")
+	}
+
+	// Klines arriving after recovery must not overtake the journal
+	store.setDown(false)
+	for i := int64(5); i < 8; i++ {
+		writer.Enqueue(context.Background(), testKline(i))
+	}
+
+	if !waitFor(t, 2*time.Second, func() bool { return len(store.openTimes()) == 8 }) {
+		t.Fatalf("Expected 8 klines written after recovery, got %d", len(store.openTimes()))
+	}
+
+	for i, openTime := range store.openTimes() {
+		if openTime != int64(i) {
+			t.Fatalf("Expected klines in enqueue order, got %v", store.openTimes())
+		}
+	}
+
+	if stats := writer.Stats(); stats.JournalPending != 0 || stats.DatabaseDown {
+		t.Errorf("Expected drained journal and healthy database, got %+v", stats)
+	}
+}
+
+// TestKlineWriter_JournalSurvivesRestart tests klines journaled before shutdown are replayed on start
+func TestKlineWriter_JournalSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "klines.ndjson")
+	cfg := config.WriterConfig{BatchSize: 10, FlushInterval: config.Duration(20 * time.Millisecond), RetryInterval: config.Duration(20 * time.Millisecond), JournalPath: path}
+
+	store := &fakeBatchStore{down: true}
+	writer, err := NewKlineWriter(store, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	writer.Start()
+	writer.Enqueue(context.Background(), testKline(1))
+	writer.Enqueue(context.Background(), testKline(2))
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Fatal("Expected journal to contain klines after shutdown")
+	}
+
+	store.setDown(false)
+	writer, err = NewKlineWriter(store, cfg)
+	if err != nil {
+		t.Fatalf("Failed to reopen writer: %v", err)
+	}
+	writer.Start()
+	defer writer.Close(context.Background())
+
+	if !waitFor(t, time.Second, func() bool { return len(store.openTimes()) == 2 }) {
+		t.Fatalf("Expected journaled klines to be replayed, got %v", store.openTimes())
+	}
+}
+
+// TestKlineWriter_CloseTimeout tests that a write stuck past the close deadline
+// is aborted and its klines are kept in the journal
+func TestKlineWriter_CloseTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "klines.ndjson")
+	store := &fakeBatchStore{hang: true}
+	writer, err := NewKlineWriter(store, config.WriterConfig{
+		BatchSize:     10,
+		FlushInterval: config.Duration(time.Hour),
+		JournalPath:   path,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	writer.Start()
+	for i := int64(0); i < 3; i++ {
+		writer.Enqueue(context.Background(), testKline(i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := writer.Close(ctx); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected Close to return at the deadline, took %v", elapsed)
+	}
+
+	if pending, err := countJournalRecords(path); err != nil || pending != 3 {
+		t.Errorf("Expected 3 klines in the journal, got %d (%v)", pending, err)
+	}
+}
+
+// TestKlineWriter_ReplayFailureRewinds tests that a replay failing part way
+// through the journal keeps the klines of the pass pending, so the journal is
+// still replayed in full and truncated once the database recovers
+func TestKlineWriter_ReplayFailureRewinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "klines.ndjson")
+	store := &fakeBatchStore{}
+	writer, err := NewKlineWriter(store, config.WriterConfig{
+		BatchSize:     2,
+		FlushInterval: config.Duration(20 * time.Millisecond),
+		RetryInterval: config.Duration(20 * time.Millisecond),
+		JournalPath:   path,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	writer.Start()
+	defer writer.Close(context.Background())
+
+	store.setDown(true)
+	for i := int64(0); i < 6; i++ {
+		writer.Enqueue(context.Background(), testKline(i))
+	}
+	if !waitFor(t, time.Second, func() bool { return writer.Stats().JournalPending == 6 }) {
+		t.Fatalf("Expected 6 klines journaled, got %+v", writer.Stats())
+	}
+
+	// The second batch of three passes fails, after the first was replayed
+	store.failBatch(2, 3)
+	store.setDown(false)
+
+	if !waitFor(t, 2*time.Second, func() bool {
+		stats := writer.Stats()
+		return stats.JournalPending == 0 && !stats.DatabaseDown && stats.WriteFailures >= 4
+	}) {
+		t.Fatalf("Expected the journal to be replayed after the failures, got %+v", writer.Stats())
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("Expected the journal to be truncated, got %v (%v)", info.Size(), err)
+	}
+
+	// Each failed pass wrote the first batch again before failing
+	times := store.openTimes()
+	want := []int64{0, 1, 0, 1, 0, 1, 0, 1, 2, 3, 4, 5}
+	if len(times) != len(want) {
+		t.Fatalf("Expected writes %v, got %v", want, times)
+	}
+	for i := range want {
+		if times[i] != want[i] {
+			t.Fatalf("Expected writes %v, got %v", want, times)
+		}
+	}
+
+	writer.Enqueue(context.Background(), testKline(6))
+	if !waitFor(t, time.Second, func() bool { return len(store.openTimes()) == len(want)+1 }) {
+		t.Fatalf("Expected klines after the replay to be written, got %v", store.openTimes())
+	}
+}
+
+// TestKlineWriter_QueueOverflowJournals tests that klines enqueued while the
+// queue is full are journaled in order, and dropped only once the overflow is
+// full too
+func TestKlineWriter_QueueOverflowJournals(t *testing.T) {
+	gate := make(chan struct{})
+	store := &fakeBatchStore{gate: gate}
+	writer, err := NewKlineWriter(store, config.WriterConfig{
+		BatchSize:     1,
+		FlushInterval: config.Duration(20 * time.Millisecond),
+		QueueSize:     4,
+		JournalPath:   filepath.Join(t.TempDir(), "klines.ndjson"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	writer.Start()
+	defer writer.Close(context.Background())
+
+	// The first kline holds the run goroutine in a write
+	writer.Enqueue(context.Background(), testKline(0))
+	if !waitFor(t, time.Second, func() bool { return writer.Stats().QueueDepth == 0 }) {
+		t.Fatal("Expected the first kline to be taken from the queue")
+	}
+
+	// Four klines fill the queue, four the overflow and two are dropped
+	for i := int64(1); i <= 10; i++ {
+		writer.Enqueue(context.Background(), testKline(i))
+	}
+	if stats := writer.Stats(); stats.Dropped != 2 {
+		t.Errorf("Expected 2 klines dropped, got %+v", stats)
+	}
+	close(gate)
+
+	if !waitFor(t, 2*time.Second, func() bool { return len(store.openTimes()) == 9 }) {
+		t.Fatalf("Expected 9 klines written, got %v", store.openTimes())
+	}
+	for i, openTime := range store.openTimes() {
+		if openTime != int64(i) {
+			t.Fatalf("Expected klines in enqueue order, got %v", store.openTimes())
+		}
+	}
+	if stats := writer.Stats(); stats.Spilled == 0 {
+		t.Errorf("Expected the overflow to be journaled, got %+v", stats)
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/models"
+)
+
+// Collector series states
+const (
+	SeriesStateBackfilling  = "backfilling"
+	SeriesStateStreaming    = "streaming"
+	SeriesStateReconnecting = "reconnecting"
+)
+
+// staleGrace is added to twice the interval before a streaming series without
+// new klines is reported as unhealthy
+const staleGrace = 10 * time.Second
+
+// SeriesHealth is a snapshot of the ingestion state of one tracked series
+type SeriesHealth struct {
+	Symbol   string `json:"symbol"`
+	Interval string `json:"interval"`
+	State    string `json:"state"`
+	// Healthy is true while the series is streaming and klines arrive on schedule
+	Healthy bool `json:"healthy"`
+	// Since is when the series entered its current state
+	Since        time.Time  `json:"since"`
+	LastKlineAt  *time.Time `json:"last_kline_at,omitempty"`
+	LastOpenTime int64      `json:"last_open_time,omitempty"`
+	Klines       uint64     `json:"klines"`
+	Backfilled   uint64     `json:"backfilled"`
+	Reconnects   uint64     `json:"reconnects"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// seriesHealth tracks the state of a series; it is updated by the series'
+// stream goroutine and read by status requests
+type seriesHealth struct {
+	mu       sync.Mutex
+	series   Series
+	interval time.Duration
+	state    SeriesHealth
+}
+
+func newSeriesHealth(series Series) *seriesHealth {
+	interval, _ := models.IntervalDuration(series.Interval)
+	return &seriesHealth{
+		series:   series,
+		interval: interval,
+		state: SeriesHealth{
+			Symbol:   series.Symbol,
+			Interval: series.Interval,
+			State:    SeriesStateBackfilling,
+			Since:    time.Now(),
+		},
+	}
+}
+
+// setState moves the series to a new state
+func (h *seriesHealth) setState(state string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state.State == state {
+		return
+	}
+	if state == SeriesStateReconnecting {
+		h.state.Reconnects++
+		metrics.StreamReconnects.WithLabelValues(h.series.Symbol, h.series.Interval).Inc()
+	}
+	h.state.State = state
+	h.state.Since = time.Now()
+}
+
+// recordError remembers the most recent stream or backfill failure
+func (h *seriesHealth) recordError(err error) {
+	h.mu.Lock()
+	h.state.LastError = err.Error()
+	h.mu.Unlock()
+}
+
+// recordBackfill counts klines stored by a backfill
+func (h *seriesHealth) recordBackfill(n int) {
+	h.mu.Lock()
+	h.state.Backfilled += uint64(n)
+	h.mu.Unlock()
+}
+
+// recordKline registers a kline received from the stream
+func (h *seriesHealth) recordKline(kline models.Kline) {
+	now := time.Now()
+	h.mu.Lock()
+	h.state.LastKlineAt = &now
+	h.state.LastOpenTime = kline.OpenTime
+	h.state.Klines++
+	h.mu.Unlock()
+
+	metrics.StreamClosedKlines.WithLabelValues(h.series.Symbol, h.series.Interval).Inc()
+}
+
+// snapshot returns the current health, judging staleness against now
+func (h *seriesHealth) snapshot(now time.Time) SeriesHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := h.state
+	if health.LastKlineAt != nil {
+		lastKlineAt := *health.LastKlineAt
+		health.LastKlineAt = &lastKlineAt
+	}
+
+	if health.State == SeriesStateStreaming {
+		// Closed klines arrive once per interval, measured from the later of
+		// connecting and the last kline
+		last := health.Since
+		if health.LastKlineAt != nil && health.LastKlineAt.After(last) {
+			last = *health.LastKlineAt
+		}
+		health.Healthy = now.Sub(last) <= 2*h.interval+staleGrace
+	}
+	return health
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HealthSource reports the ingestion health of the tracked series
+// It is the collector itself, or its mirror on an API server fed by the bus
+type HealthSource interface {
+	Health() []SeriesHealth
+}
+
+var (
+	seriesLastKlineAgeDesc = prometheus.NewDesc(
+		"crypto_monitor_series_last_kline_age_seconds",
+		"Seconds since the last kline of a tracked series was received.",
+		[]string{"symbol", "interval"}, nil,
+	)
+	seriesHealthyDesc = prometheus.NewDesc(
+		"crypto_monitor_series_healthy",
+		"Whether a tracked series is streaming and receiving klines on schedule.",
+		[]string{"symbol", "interval"}, nil,
+	)
+)
+
+// SeriesMetrics exports the per-series health of a collector, computed at scrape time
+type SeriesMetrics struct {
+	source HealthSource
+}
+
+// NewSeriesMetrics creates a Prometheus collector for the series of source
+func NewSeriesMetrics(source HealthSource) *SeriesMetrics {
+	return &SeriesMetrics{source: source}
+}
+
+// Describe implements prometheus.Collector
+func (m *SeriesMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- seriesLastKlineAgeDesc
+	ch <- seriesHealthyDesc
+}
+
+// Collect implements prometheus.Collector
+func (m *SeriesMetrics) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, health := range m.source.Health() {
+		healthy := 0.0
+		if health.Healthy {
+			healthy = 1
+		}
+		ch <- prometheus.MustNewConstMetric(seriesHealthyDesc, prometheus.GaugeValue, healthy, health.Symbol, health.Interval)
+
+		// Series that have not received a kline yet have no age
+		if health.LastKlineAt != nil {
+			age := now.Sub(*health.LastKlineAt).Seconds()
+			ch <- prometheus.MustNewConstMetric(seriesLastKlineAgeDesc, prometheus.GaugeValue, age, health.Symbol, health.Interval)
+		}
+	}
+}
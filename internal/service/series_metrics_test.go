@@ -0,0 +1,41 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type staticHealth []SeriesHealth
+
+func (s staticHealth) Health() []SeriesHealth { return s }
+
+// TestSeriesMetrics tests per-series health and last kline age export
+func TestSeriesMetrics(t *testing.T) {
+	lastKlineAt := time.Now().Add(-90 * time.Second)
+	source := staticHealth{
+		{Symbol: "BTCUSDT", Interval: "1m", State: SeriesStateStreaming, Healthy: true, LastKlineAt: &lastKlineAt},
+		{Symbol: "ETHUSDT", Interval: "1m", State: SeriesStateBackfilling},
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewSeriesMetrics(source))
+
+	expected := `
+# HELP crypto_monitor_series_healthy Whether a tracked series is streaming and receiving klines on schedule.
+# TYPE crypto_monitor_series_healthy gauge
+crypto_monitor_series_healthy{interval="1m",symbol="BTCUSDT"} 1
+crypto_monitor_series_healthy{interval="1m",symbol="ETHUSDT"} 0
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "crypto_monitor_series_healthy"); err != nil {
+		t.Error(err)
+	}
+
+	// Only series that received a kline have an age
+	if n, err := testutil.GatherAndCount(registry, "crypto_monitor_series_last_kline_age_seconds"); err != nil || n != 1 {
+		t.Errorf("Expected 1 age sample, got %d (%v)", n, err)
+	}
+}
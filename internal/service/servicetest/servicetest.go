@@ -0,0 +1,17 @@
+// Package servicetest provides fakes of the services for the tests of the
+// packages serving them
+package servicetest
+
+import "crypto-monitor/internal/service"
+
+// TrackedSeries is a catalog and health source tracking BTCUSDT 1m only
+type TrackedSeries struct{}
+
+func (TrackedSeries) IsTracked(symbol, interval string) bool {
+	return symbol == "BTCUSDT" && interval == "1m"
+}
+func (TrackedSeries) AddListener(listener service.SeriesListener) {}
+
+func (TrackedSeries) Health() []service.SeriesHealth {
+	return []service.SeriesHealth{{Symbol: "BTCUSDT", Interval: "1m"}}
+}
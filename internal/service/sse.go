@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// sseKeepAlive is how often an idle event stream is sent a comment, so
+// proxies do not time it out
+const sseKeepAlive = 15 * time.Second
+
+var (
+	// ErrShuttingDown is returned for streams opened while the service
+	// shuts down
+	ErrShuttingDown = errors.New("service is shutting down")
+	// ErrEvicted is returned by Recv once the stream is evicted
+	ErrEvicted = errors.New("evicted")
+)
+
+// StreamRequest asks for a stream of one series
+type StreamRequest struct {
+	Symbol   string
+	Interval string
+	// Snapshot and ThrottleMs are as in a subscribe message
+	Snapshot   int
+	ThrottleMs int64
+	// Resume continues the stream of a reconnecting client after Seq, the
+	// last update it received in the topic of Epoch, as with a resume
+	// message; when the updates after it cannot be replayed, the stream is
+	// subscribed with Snapshot instead
+	Resume     bool
+	Epoch      uint64
+	Seq        uint64
+	RemoteAddr string
+	// Encoding is the encoding of the messages; a stream served as
+	// server-sent events is JSON
+	Encoding Encoding
+}
+
+// EventStream is a subscription to one series outside a websocket
+// connection, read as server-sent events by Serve or message by message by
+// Recv
+type EventStream struct {
+	ws     *WebSocketService
+	client *Client
+	once   sync.Once
+	// epoch is the epoch of the topic of the stream, the prefix of its event
+	// ids
+	epoch uint64
+}
+
+// OpenEventStream subscribes to a series for a stream
+// The stream is a client of the websocket fan-out without a connection: it is
+// throttled, warned and evicted like the others. An open stream must be
+// served, or received from and closed.
+func (ws *WebSocketService) OpenEventStream(req StreamRequest) (*EventStream, error) {
+	client := &Client{
+		id:         clientSeq.Add(1),
+		remoteAddr: req.RemoteAddr,
+		encoding:   req.Encoding,
+		send:       make(chan []byte, sendQueueSize),
+		subs:       make(map[string]bool),
+		lastSent:   make(map[string]time.Time),
+	}
+	if !ws.addClient(client) {
+		return nil, ErrShuttingDown
+	}
+
+	symbol := strings.ToUpper(req.Symbol)
+	var err error
+	switch {
+	case !req.Resume:
+		err = ws.subscribe(client, symbol, req.Interval, req.Snapshot, req.ThrottleMs)
+	case symbol == "" || req.Interval == "":
+		err = errors.New("Symbol and interval are required")
+	case !ws.catalog.IsTracked(symbol, req.Interval):
+		err = fmt.Errorf("%s %s is %w", symbol, req.Interval, ErrNotTracked)
+	case !ws.resume(client, symbol, req.Interval, req.Epoch, req.Seq, req.ThrottleMs, false):
+		// A stream cannot act on resnapshot, so it is sent the snapshot
+		err = ws.subscribe(client, symbol, req.Interval, req.Snapshot, req.ThrottleMs)
+	}
+	if err != nil {
+		ws.removeClient(client)
+		ws.pumps.Done()
+		return nil, err
+	}
+	return &EventStream{ws: ws, client: client, epoch: client.epochOf(symbol + ":" + req.Interval)}, nil
+}
+
+// Epoch returns the epoch of the topic of the stream, which a reconnecting
+// client resumes with along with the last seq it received
+func (s *EventStream) Epoch() uint64 {
+	return s.epoch
+}
+
+// Close unsubscribes the stream; Serve closes it itself
+func (s *EventStream) Close() {
+	s.once.Do(func() {
+		s.ws.removeClient(s.client)
+		s.ws.pumps.Done()
+	})
+}
+
+// Serve writes the events of the stream to w, flushing each, until ctx is
+// done, the series is no longer tracked, the client is evicted or the service
+// shuts down
+func (s *EventStream) Serve(ctx context.Context, w io.Writer, flush func()) error {
+	defer s.Close()
+	client := s.client
+	if client.encoding != EncodingJSON {
+		return fmt.Errorf("events are JSON, the stream is %s", client.encoding)
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case message, ok := <-client.send:
+			if frame := client.evicted(); frame != nil {
+				// The queue is not drained for a client too slow to read it;
+				// the reason follows the status code of the close frame
+				message, _ = EncodeServerMessage(EncodingJSON, ServerMessage{Type: "error", Message: evictionReason(frame)})
+				writeEvent(w, s.epoch, message)
+				flush()
+				return nil
+			}
+			if !ok {
+				return nil
+			}
+			msgType, err := writeEvent(w, s.epoch, message)
+			if err != nil {
+				return err
+			}
+			flush()
+			if msgType == "series_removed" {
+				return nil
+			}
+
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+			flush()
+		}
+	}
+}
+
+// StreamMessage is a message of a stream with its klines decoded
+type StreamMessage struct {
+	// Type is the type of the websocket message
+	Type       string
+	Seq        uint64
+	ThrottleMs int64
+	Message    string
+	// Klines are the kline of a kline_update and the klines of a snapshot
+	Klines []KlineData
+}
+
+// Recv returns the next message of the stream, waiting until ctx is done
+// It fails with ErrEvicted once the stream is evicted, and with io.EOF once
+// the service shuts down; the stream must still be closed.
+func (s *EventStream) Recv(ctx context.Context) (StreamMessage, error) {
+	select {
+	case <-ctx.Done():
+		return StreamMessage{}, ctx.Err()
+	case message, ok := <-s.client.send:
+		if frame := s.client.evicted(); frame != nil {
+			return StreamMessage{}, fmt.Errorf("%w: %s", ErrEvicted, evictionReason(frame))
+		}
+		if !ok {
+			return StreamMessage{}, io.EOF
+		}
+		return decodeStreamMessage(s.client.encoding, message)
+	}
+}
+
+// evictionReason returns the reason of the close frame of an evicted client,
+// which follows its status code
+func evictionReason(frame []byte) string {
+	return string(frame[2:])
+}
+
+// rawData keeps the data of a message undecoded until its type is known
+type rawData []byte
+
+// UnmarshalJSON keeps the JSON of the data
+func (r *rawData) UnmarshalJSON(data []byte) error {
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+// DecodeMsgpack keeps the MessagePack of the data
+func (r *rawData) DecodeMsgpack(dec *msgpack.Decoder) error {
+	raw, err := dec.DecodeRaw()
+	*r = rawData(raw)
+	return err
+}
+
+// decodeStreamMessage decodes a message sent to a stream, with the klines of
+// updates and snapshots
+func decodeStreamMessage(e Encoding, data []byte) (StreamMessage, error) {
+	decode := json.Unmarshal
+	if e == EncodingMsgpack {
+		decode = unmarshalMsgpack
+	}
+	var msg struct {
+		Type       string  `json:"type"`
+		Data       rawData `json:"data"`
+		Message    string  `json:"message"`
+		Seq        uint64  `json:"seq"`
+		ThrottleMs int64   `json:"throttle_ms"`
+	}
+	if err := decode(data, &msg); err != nil {
+		return StreamMessage{}, fmt.Errorf("invalid message: %w", err)
+	}
+
+	decoded := StreamMessage{Type: msg.Type, Seq: msg.Seq, ThrottleMs: msg.ThrottleMs, Message: msg.Message}
+	switch msg.Type {
+	case "kline_update":
+		var kline KlineData
+		if err := decode(msg.Data, &kline); err != nil {
+			return StreamMessage{}, fmt.Errorf("invalid kline: %w", err)
+		}
+		decoded.Klines = []KlineData{kline}
+	case "snapshot":
+		if err := decode(msg.Data, &decoded.Klines); err != nil {
+			return StreamMessage{}, fmt.Errorf("invalid snapshot: %w", err)
+		}
+	}
+	return decoded, nil
+}
+
+// seriesEvents are the messages carrying the seq of their series, sent with
+// it and the epoch of the topic as their event id, so a reconnecting client
+// resumes from the last one
+var seriesEvents = map[string]bool{
+	"subscribed":   true,
+	"snapshot":     true,
+	"resumed":      true,
+	"kline_update": true,
+}
+
+// ParseEventID returns the epoch and seq of the id of a series event,
+// formatted as <epoch>-<seq>
+func ParseEventID(id string) (epoch, seq uint64, err error) {
+	e, s, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid event id %q", id)
+	}
+	if epoch, err = strconv.ParseUint(e, 10, 64); err != nil || epoch == 0 {
+		return 0, 0, fmt.Errorf("invalid event id %q", id)
+	}
+	if seq, err = strconv.ParseUint(s, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid event id %q", id)
+	}
+	return epoch, seq, nil
+}
+
+// writeEvent writes a JSON message as an event named by its type and returns
+// the type; series events have the id of their seq in the topic of epoch
+func writeEvent(w io.Writer, epoch uint64, message []byte) (string, error) {
+	var header struct {
+		Type string `json:"type"`
+		Seq  uint64 `json:"seq"`
+	}
+	if err := json.Unmarshal(message, &header); err != nil {
+		return "", fmt.Errorf("invalid message: %w", err)
+	}
+
+	b := make([]byte, 0, len(message)+64)
+	if seriesEvents[header.Type] {
+		b = strconv.AppendUint(append(b, "id: "...), epoch, 10)
+		b = strconv.AppendUint(append(b, '-'), header.Seq, 10)
+		b = append(b, '\n')
+	}
+	b = append(append(b, "event: "...), header.Type...)
+	b = append(append(append(b, "\ndata: "...), message...), "\n\n"...)
+	_, err := w.Write(b)
+	return header.Type, err
+}
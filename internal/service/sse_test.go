@@ -0,0 +1,243 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is an event read from a stream
+type sseEvent struct {
+	id, event, data string
+}
+
+// serveEventStream serves stream into a pipe and returns the reader of its
+// events and the channel of Serve's result
+func serveEventStream(t *testing.T, stream *EventStream) (*bufio.Reader, <-chan error) {
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- stream.Serve(t.Context(), w, func() {})
+		w.Close()
+	}()
+	t.Cleanup(func() { r.Close() })
+	return bufio.NewReader(r), done
+}
+
+// readEvent reads the next event of a stream, skipping comments
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	events := make(chan sseEvent, 1)
+	go func() {
+		var e sseEvent
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				close(events)
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && e.event != "":
+				events <- e
+				return
+			case strings.HasPrefix(line, "id: "):
+				e.id = line[len("id: "):]
+			case strings.HasPrefix(line, "event: "):
+				e.event = line[len("event: "):]
+			case strings.HasPrefix(line, "data: "):
+				e.data = line[len("data: "):]
+			}
+		}
+	}()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("Expected an event, the stream ended")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+		return sseEvent{}
+	}
+}
+
+// TestEventStream_Serve tests that a stream is sent the websocket messages of
+// its series as events, with the epoch and seq of updates as event ids
+func TestEventStream_Serve(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	stream, err := wsSvc.OpenEventStream(StreamRequest{Symbol: "btcusdt", Interval: "1m"})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	if epoch := wsSvc.findTopic("BTCUSDT:1m").epoch; stream.Epoch() != epoch {
+		t.Fatalf("Expected the epoch of the topic %d, got %d", epoch, stream.Epoch())
+	}
+	events, done := serveEventStream(t, stream)
+
+	if e := readEvent(t, events); e.event != "subscribed" || e.id != fmt.Sprintf("%d-0", stream.Epoch()) {
+		t.Fatalf("Expected subscribed with seq 0, got %+v", e)
+	}
+	wsSvc.OnKline(t.Context(), testKline(60000))
+	e := readEvent(t, events)
+	if e.event != "kline_update" || e.id != fmt.Sprintf("%d-1", stream.Epoch()) || !strings.Contains(e.data, `"close":"50000.00000000"`) {
+		t.Fatalf("Expected the update with id 1, got %+v", e)
+	}
+	if msg, err := DecodeServerMessage(EncodingJSON, []byte(e.data)); err != nil || msg.Seq != 1 || msg.Symbol != "BTCUSDT" {
+		t.Errorf("Expected the data to be the websocket message, got %+v %v", msg, err)
+	}
+
+	wsSvc.OnSeriesRemoved(Series{Symbol: "BTCUSDT", Interval: "1m"})
+	if e := readEvent(t, events); e.event != "series_removed" || e.id != "" {
+		t.Errorf("Expected series_removed without an id, got %+v", e)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the stream to end cleanly, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to end once the series is removed")
+	}
+	waitFor(t, time.Second, func() bool {
+		wsSvc.mu.RLock()
+		defer wsSvc.mu.RUnlock()
+		return len(wsSvc.clients) == 0
+	})
+}
+
+// TestEventStream_Resume tests that a resumed stream replays the updates
+// after the last one received
+func TestEventStream_Resume(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	for i := int64(1); i <= 3; i++ {
+		wsSvc.broadcastKlineUpdate(t.Context(), testKline(i*60000))
+	}
+
+	epoch := wsSvc.findTopic("BTCUSDT:1m").epoch
+	stream, err := wsSvc.OpenEventStream(StreamRequest{Symbol: "BTCUSDT", Interval: "1m", Resume: true, Epoch: epoch, Seq: 1})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	events, _ := serveEventStream(t, stream)
+	if e := readEvent(t, events); e.event != "resumed" || e.id != fmt.Sprintf("%d-1", epoch) {
+		t.Fatalf("Expected resumed from 1, got %+v", e)
+	}
+	for _, want := range []uint64{2, 3} {
+		if e := readEvent(t, events); e.event != "kline_update" || e.id != fmt.Sprintf("%d-%d", epoch, want) {
+			t.Fatalf("Expected update %d replayed, got %+v", want, e)
+		}
+	}
+
+	if _, err := wsSvc.OpenEventStream(StreamRequest{Symbol: "ETHUSDT", Interval: "1m", Resume: true, Seq: 1}); !errors.Is(err, ErrNotTracked) {
+		t.Errorf("Expected ErrNotTracked resuming an untracked series, got %v", err)
+	}
+	if _, err := wsSvc.OpenEventStream(StreamRequest{Symbol: "ETHUSDT", Interval: "1m"}); !errors.Is(err, ErrNotTracked) {
+		t.Errorf("Expected ErrNotTracked for an untracked series, got %v", err)
+	}
+}
+
+// TestEventStream_ResumeFallback tests that a stream that cannot be resumed,
+// its updates no longer kept or its epoch another topic's, is subscribed with
+// a snapshot instead
+func TestEventStream_ResumeFallback(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	for i := 1; i <= wsSvc.logSize+1; i++ {
+		wsSvc.broadcastKlineUpdate(t.Context(), testKline(int64(i)*60000))
+	}
+	epoch := wsSvc.findTopic("BTCUSDT:1m").epoch
+
+	for _, tt := range []struct {
+		name  string
+		epoch uint64
+	}{
+		{"expired", epoch},
+		{"restarted", epoch + 1},
+	} {
+		stream, err := wsSvc.OpenEventStream(StreamRequest{Symbol: "BTCUSDT", Interval: "1m", Snapshot: 1, Resume: true, Epoch: tt.epoch, Seq: 0})
+		if err != nil {
+			t.Fatalf("%s: failed to open stream: %v", tt.name, err)
+		}
+		events, _ := serveEventStream(t, stream)
+		want := fmt.Sprintf("%d-%d", epoch, wsSvc.logSize+1)
+		if e := readEvent(t, events); e.event != "subscribed" || e.id != want {
+			t.Fatalf("%s: expected subscribed at %s, got %+v", tt.name, want, e)
+		}
+		if e := readEvent(t, events); e.event != "snapshot" || e.id != want {
+			t.Fatalf("%s: expected the snapshot at %s, got %+v", tt.name, want, e)
+		}
+		stream.Close()
+	}
+}
+
+// TestEventStream_Evicted tests that an evicted stream is told why before it ends
+func TestEventStream_Evicted(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	stream, err := wsSvc.OpenEventStream(StreamRequest{Symbol: "BTCUSDT", Interval: "1m"})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	events, done := serveEventStream(t, stream)
+	readEvent(t, events)
+
+	wsSvc.mu.Lock()
+	wsSvc.evict(stream.client, "lag", "slow consumer: send queue not drained for 30s")
+	wsSvc.mu.Unlock()
+	if e := readEvent(t, events); e.event != "error" || !strings.Contains(e.data, "slow consumer") {
+		t.Errorf("Expected an error event with the reason, got %+v", e)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to end once evicted")
+	}
+}
+
+// TestEventStream_Recv tests receiving the messages of a stream with their
+// klines decoded, until it is evicted
+func TestEventStream_Recv(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	stream, err := wsSvc.OpenEventStream(StreamRequest{Symbol: "BTCUSDT", Interval: "1m", ThrottleMs: 100, Encoding: EncodingMsgpack})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer stream.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	if msg, err := stream.Recv(ctx); err != nil || msg.Type != "subscribed" || msg.ThrottleMs != 100 {
+		t.Fatalf("Expected subscribed, got %+v %v", msg, err)
+	}
+	wsSvc.OnKline(t.Context(), testKline(60000))
+	msg, err := stream.Recv(ctx)
+	if err != nil || msg.Type != "kline_update" || msg.Seq != 1 || len(msg.Klines) != 1 || msg.Klines[0].Close != 50000 {
+		t.Fatalf("Expected the decoded update, got %+v %v", msg, err)
+	}
+
+	wsSvc.mu.Lock()
+	wsSvc.evict(stream.client, "lag", "slow consumer: send queue not drained for 30s")
+	wsSvc.mu.Unlock()
+	if _, err := stream.Recv(ctx); !errors.Is(err, ErrEvicted) || !strings.Contains(err.Error(), "slow consumer") {
+		t.Errorf("Expected ErrEvicted with the reason, got %v", err)
+	}
+}
+
+// TestDecodeStreamMessage tests decoding snapshots in both encodings
+func TestDecodeStreamMessage(t *testing.T) {
+	data := []KlineData{{OpenTime: 60000, Close: 50000.5}, {OpenTime: 120000, Close: 50001}}
+	for _, e := range []Encoding{EncodingJSON, EncodingMsgpack} {
+		b, err := EncodeServerMessage(e, ServerMessage{Type: "snapshot", Symbol: "BTCUSDT", Interval: "1m", Data: data, Seq: 7})
+		if err != nil {
+			t.Fatalf("Failed to encode %s: %v", e, err)
+		}
+		msg, err := decodeStreamMessage(e, b)
+		if err != nil || msg.Type != "snapshot" || msg.Seq != 7 || len(msg.Klines) != 2 || msg.Klines[0] != data[0] || msg.Klines[1] != data[1] {
+			t.Errorf("Expected the snapshot decoded from %s, got %+v %v", e, msg, err)
+		}
+	}
+}
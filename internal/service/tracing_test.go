@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/config"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testTracing installs a recording tracer provider once per test binary
+// Package tracers delegate to the first provider installed globally, so it
+// cannot be replaced between tests.
+var testTracing = sync.OnceValues(func() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	return provider, recorder
+})
+
+// endedSpan returns the ended span with the given name that is part of the
+// trace of sc or links to sc, if any
+func endedSpan(recorder *tracetest.SpanRecorder, name string, sc trace.SpanContext) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() != name {
+			continue
+		}
+		if span.SpanContext().TraceID() == sc.TraceID() {
+			return span
+		}
+		for _, link := range span.Links() {
+			if link.SpanContext.SpanID() == sc.SpanID() {
+				return span
+			}
+		}
+	}
+	return nil
+}
+
+// TestTracing_UpstreamToBroadcast tests that the span of an upstream message
+// is carried through the writer, over the bus and into the websocket broadcast
+func TestTracing_UpstreamToBroadcast(t *testing.T) {
+	provider, recorder := testTracing()
+	collector, source, _, remote := setupTestBridge(t)
+	wsSvc := NewWebSocketService(remote, nil, config.WebSocketConfig{})
+	go wsSvc.Run(t.Context())
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+
+	ctx, upstream := provider.Tracer("test").Start(context.Background(), "binance.kline")
+	if !waitFor(t, time.Second, func() bool { return source.emit(ctx, testKline(42)) }) {
+		t.Fatal("Expected stream to start")
+	}
+	upstream.End()
+	upstreamSC := upstream.SpanContext()
+
+	if !waitFor(t, time.Second, func() bool { return endedSpan(recorder, "writer.flush", upstreamSC) != nil }) {
+		t.Fatal("Expected the batch write to be traced")
+	}
+
+	flush := endedSpan(recorder, "writer.flush", upstreamSC)
+	if links := flush.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != upstreamSC.SpanID() {
+		t.Errorf("Expected writer.flush to link the upstream span, got %+v", links)
+	}
+
+	publish := endedSpan(recorder, "bus.publish "+TopicKlines, upstreamSC)
+	if publish == nil || publish.Parent().SpanID() != upstreamSC.SpanID() {
+		t.Fatalf("Expected bus publish to be a child of the upstream span, got %v", publish)
+	}
+	receive := endedSpan(recorder, "bus.receive "+TopicKlines, upstreamSC)
+	if receive == nil || receive.Parent().SpanID() != publish.SpanContext().SpanID() || !receive.Parent().IsRemote() {
+		t.Fatalf("Expected bus receive to continue the published trace, got %v", receive)
+	}
+	// Broadcast by a shard worker after the bus handed the kline over
+	waitFor(t, time.Second, func() bool { return endedSpan(recorder, "websocket.broadcast", upstreamSC) != nil })
+	broadcast := endedSpan(recorder, "websocket.broadcast", upstreamSC)
+	if broadcast == nil || broadcast.Parent().SpanID() != receive.SpanContext().SpanID() {
+		t.Fatalf("Expected websocket broadcast to be a child of the bus receive span, got %v", broadcast)
+	}
+	if broadcast.SpanContext().TraceID() != upstreamSC.TraceID() {
+		t.Errorf("Expected one trace from upstream message to broadcast, got %s and %s", upstreamSC.TraceID(), broadcast.SpanContext().TraceID())
+	}
+}
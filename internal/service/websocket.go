@@ -1,55 +1,278 @@
 package service
 
 import (
+	"context"
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/logging"
+	"crypto-monitor/internal/metrics"
 	"crypto-monitor/internal/models"
-	"crypto-monitor/internal/repository"
-	"encoding/json"
+	"crypto-monitor/internal/tracing"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+)
+
+var (
+	wsLog    = logging.For(logging.WebSocket)
+	wsTracer = tracing.Tracer(logging.WebSocket)
+)
+
+// clientSeq numbers websocket clients for log correlation
+var clientSeq atomic.Uint64
+
+// goingAway is the close frame sent to clients when the server shuts down
+var goingAway = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+
+// ProtocolVersion is the version of the websocket protocol announced in the
+// hello message; it changes whenever a change would break existing clients
+const ProtocolVersion = 1
+
+var (
+	// ErrNotTracked refuses subscriptions to series the collector does not track
+	ErrNotTracked = errors.New("not tracked")
+	// ErrSnapshotUnavailable is returned when the stored klines of a
+	// subscription snapshot cannot be read
+	ErrSnapshotUnavailable = errors.New("snapshot unavailable")
+)
+
+// batchPrefix and batchSuffix enclose the messages of a batch envelope
+var (
+	batchPrefix = []byte(`{"type":"batch","messages":[`)
+	batchSuffix = []byte(`]}`)
 )
 
 const (
-	// Throttle interval: maximum one update per second
-	throttleInterval = 1 * time.Second
+	// recentKlines is the least number of broadcast klines kept per series to
+	// stitch snapshots with klines that are not stored yet
+	recentKlines = 16
+	// snapshotTimeout bounds the query for the stored klines of a snapshot
+	snapshotTimeout = 5 * time.Second
+	// sendQueueSize is the number of messages queued for a client's write pump
+	sendQueueSize = 256
 )
 
+// KlineHistory provides the stored klines sent as subscription snapshots,
+// most recent first
+type KlineHistory interface {
+	GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error)
+}
+
 // Client represents a WebSocket client connection
 type Client struct {
+	id       uint64
 	conn     *websocket.Conn
 	send     chan []byte
 	subs     map[string]bool // Map of "symbol:interval" -> subscribed
 	mu       sync.RWMutex
 	lastSent map[string]time.Time // Track last sent time per subscription for throttling
+	drops    atomic.Uint64        // Messages dropped because send was full
+	// throttles holds the negotiated throttle interval of each subscription
+	throttles map[string]time.Duration
+	// epochs holds the epoch of the topic of each subscription
+	epochs map[string]uint64
+	// pending holds the updates of each subscription waiting for its throttle
+	// interval to pass
+	pending map[string]*pendingUpdates
+	// closed is set, under mu, once send is closed
+	closed bool
+	// closeFrame, set under mu before send is closed, is the close frame of
+	// an evicted client
+	closeFrame []byte
+	// backlogSince is when, in Unix nanoseconds, a message was queued while
+	// the queue was empty; the queue has not drained since if it is not empty
+	backlogSince atomic.Int64
+	// warned is set by Run once the client was told it is a slow consumer
+	warned bool
+	// dropBase is the drop count when the queue last drained, under the
+	// service's mu; only the drops since count towards eviction
+	dropBase uint64
+	// session is the token of the client's resumable session, guarded by
+	// the service's sessionsMu
+	session string
+	// remoteAddr is the address the client connected from
+	remoteAddr string
+	// encoding is the format of the messages sent to the client
+	encoding Encoding
+	// pongs carries the payloads of pings to the write pump, which answers
+	// them, so the pump is the only writer of the connection
+	pongs chan string
+	// notices carries messages the write pump sends ahead of the queued ones,
+	// so they reach a client whose queue is full
+	notices chan []byte
+	// compressThreshold is the size of the smallest frame compressed, if the
+	// client negotiated permessage-deflate
+	compressThreshold int
+	// wire is the client's metered connection if it negotiated
+	// permessage-deflate and was upgraded through MeterUpgrade
+	wire *meteredConn
+	// batch is set once the client negotiated batch envelopes in its hello
+	batch atomic.Bool
+	// limiter bounds the rate of inbound messages; nil is unlimited
+	limiter *rate.Limiter
+	// limited is set by readPump while messages are being dropped, so the
+	// client is told once per burst rather than for every message
+	limited bool
+}
+
+// logger returns the websocket logger annotated with the client ID
+func (c *Client) logger() *slog.Logger {
+	return wsLog.With("client_id", c.id)
+}
+
+// drop counts a message that did not fit in the client's send queue
+func (c *Client) drop() {
+	c.drops.Add(1)
+	metrics.WebSocketSendDrops.Inc()
+}
+
+// disconnected records the messages the client dropped over its connection
+func (c *Client) disconnected() {
+	metrics.WebSocketClients.Dec()
+	metrics.WebSocketClientSendDrops.Observe(float64(c.drops.Load()))
+}
+
+// enqueue queues a message for the write pump, dropping it if the queue is
+// full; messages for a closed client are discarded
+// It reports whether the message was queued.
+func (c *Client) enqueue(message []byte) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return false
+	}
+	if len(c.send) == 0 {
+		c.backlogSince.Store(time.Now().UnixNano())
+	}
+	select {
+	case c.send <- message:
+		return true
+	default:
+		c.logger().Debug("Client send channel full, dropping message")
+		c.drop()
+		return false
+	}
+}
+
+// close closes the send queue, which makes the write pump close the
+// connection once it has written the queued messages, and stops pending
+// flushes
+func (c *Client) close() {
+	c.closeWith(nil)
+}
+
+// closeWith closes the send queue like close; with a close frame the write
+// pump sends it at once, discarding the queued messages
+func (c *Client) closeWith(frame []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeFrame = frame
+	close(c.send)
+	for key, p := range c.pending {
+		p.timer.Stop()
+		delete(c.pending, key)
+	}
 }
 
 // WebSocketService manages WebSocket connections and message broadcasting
+// Updates are fanned out by shard workers, each serving the topics, one per
+// series, that hash to it; the collector only queues them.
 type WebSocketService struct {
-	clients       map[*Client]bool
-	broadcast     chan []byte
-	register      chan *Client
-	unregister    chan *Client
-	binanceSvc    *BinanceService
-	klineRepo     *repository.KlineRepository
-	mu            sync.RWMutex
-	subscriptions map[string]map[*Client]bool // Map of "symbol:interval" -> clients
-	subsMu        sync.RWMutex
+	// clients holds the registered clients, under mu
+	clients map[*Client]bool
+	catalog SeriesCatalog
+	history KlineHistory
+	mu      sync.RWMutex
+	// shards hold the topics and fan out their updates
+	shards []*hubShard
+	// hubDrops counts updates dropped on a full shard queue
+	hubDrops atomic.Uint64
+	// logSize is how many updates each topic's log keeps
+	logSize int
+	// sessions maps session tokens to the subscriptions they can resume
+	sessions   map[string]*session
+	sessionsMu sync.Mutex
+	sessionTTL time.Duration
+	// lastSweep is when expired sessions were last dropped
+	lastSweep time.Time
+	// slowQueueDepth and slowLag are when clients are warned that they fall
+	// behind, evictLag and evictDrops when they are disconnected
+	slowQueueDepth int
+	slowLag        time.Duration
+	evictLag       time.Duration
+	evictDrops     uint64
+	// throttleInterval is the default minimum time between updates of one
+	// series to one client, and minThrottle and maxThrottle bound the interval
+	// clients may ask for
+	throttleInterval time.Duration
+	minThrottle      time.Duration
+	maxThrottle      time.Duration
+	// maxSubscriptions caps the subscriptions of one client; 0 is unlimited
+	maxSubscriptions int
+	// messageRate and messageBurst size the inbound token bucket of each client
+	messageRate  float64
+	messageBurst int
+	// maxSnapshot caps the klines of a subscription snapshot; 0 disables snapshots
+	maxSnapshot int
+	// compressionLevel and compressionThreshold are the flate level of
+	// compressed frames and the size of the smallest one
+	compressionLevel     int
+	compressionThreshold int
+	// done is closed once Run starts shutting down
+	done chan struct{}
+	// pumps counts the write pumps of registered clients
+	pumps sync.WaitGroup
 }
 
 // NewWebSocketService creates a new WebSocket service instance
-func NewWebSocketService(binanceSvc *BinanceService, klineRepo *repository.KlineRepository) *WebSocketService {
-	return &WebSocketService{
-		clients:       make(map[*Client]bool),
-		broadcast:     make(chan []byte, 256),
-		register:      make(chan *Client),
-		unregister:    make(chan *Client),
-		binanceSvc:    binanceSvc,
-		klineRepo:     klineRepo,
-		subscriptions: make(map[string]map[*Client]bool),
+// Clients subscribe to series tracked by the collector, whose klines the catalog
+// delivers for broadcast; the service never opens upstream streams itself.
+// Subscription snapshots are read from history, which may be nil to serve them
+// from recently broadcast klines only.
+func NewWebSocketService(catalog SeriesCatalog, history KlineHistory, cfg config.WebSocketConfig) *WebSocketService {
+	shards := cfg.HubShards
+	if shards == 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	ws := &WebSocketService{
+		clients:              make(map[*Client]bool),
+		catalog:              catalog,
+		history:              history,
+		shards:               newShards(shards, max(cfg.HubQueueSize, 1)),
+		logSize:              max(cfg.ReplayBuffer, recentKlines),
+		sessions:             make(map[string]*session),
+		sessionTTL:           cfg.SessionTTL.Std(),
+		lastSweep:            time.Now(),
+		slowQueueDepth:       cfg.SlowQueueDepth,
+		slowLag:              cfg.SlowLag.Std(),
+		evictLag:             cfg.EvictLag.Std(),
+		evictDrops:           uint64(cfg.EvictDrops),
+		throttleInterval:     cfg.ThrottleInterval.Std(),
+		minThrottle:          cfg.MinThrottleInterval.Std(),
+		maxThrottle:          cfg.MaxThrottleInterval.Std(),
+		maxSubscriptions:     cfg.MaxSubscriptions,
+		messageRate:          cfg.MessageRate,
+		messageBurst:         cfg.MessageBurst,
+		maxSnapshot:          cfg.MaxSnapshot,
+		compressionLevel:     cfg.CompressionLevel,
+		compressionThreshold: cfg.CompressionThreshold,
+		done:                 make(chan struct{}),
 	}
+	catalog.AddListener(ws)
+	return ws
 }
 
 // ClientMessage represents a message from client
@@ -57,152 +280,258 @@ type ClientMessage struct {
 	Action   string `json:"action"`   // "subscribe" or "unsubscribe"
 	Symbol   string `json:"symbol"`   // e.g., "BTCUSDT"
 	Interval string `json:"interval"` // e.g., "1m", "5m", "1h"
+	// Snapshot asks subscribe for the last N klines before the live updates
+	Snapshot int `json:"snapshot,omitempty"`
+	// Session and Seq tell resume which session to take over and the last
+	// update of the series the client received
+	Session string `json:"session,omitempty"`
+	Seq     uint64 `json:"seq,omitempty"`
+	// Protocol and Batch are the version a client speaks and whether it
+	// accepts batch envelopes, sent with hello
+	Protocol int  `json:"protocol,omitempty"`
+	Batch    bool `json:"batch,omitempty"`
+	// ThrottleMs asks subscribe and resume for a minimum interval between
+	// updates of the series; 0 uses the server's default
+	ThrottleMs int64 `json:"throttle_ms,omitempty"`
 }
 
 // ServerMessage represents a message to client
 type ServerMessage struct {
-	Type     string      `json:"type"` // "subscribed", "unsubscribed", "kline_update", "error"
+	Type     string      `json:"type"` // "hello", "subscribed", "snapshot", "resumed", "resnapshot", "unsubscribed", "kline_update", "series_removed", "slow_consumer", "error"
 	Symbol   string      `json:"symbol,omitempty"`
 	Interval string      `json:"interval,omitempty"`
 	Data     interface{} `json:"data,omitempty"`
 	Message  string      `json:"message,omitempty"`
+	// RetryAfterMs tells a rate limited client how long to wait before sending again
+	RetryAfterMs int64 `json:"retry_after_ms,omitempty"`
+	// Seq numbers the updates of a series; on other messages of a series it
+	// is the last update they account for
+	Seq uint64 `json:"seq,omitempty"`
+	// Session is the token a client resumes its subscriptions with
+	Session string `json:"session,omitempty"`
+	// Protocol and Batch are the negotiated protocol version and framing,
+	// sent with hello
+	Protocol int  `json:"protocol,omitempty"`
+	Batch    bool `json:"batch,omitempty"`
+	// ThrottleMs is the throttle interval granted to a subscription
+	ThrottleMs int64 `json:"throttle_ms,omitempty"`
 }
 
-// HandleConnection handles a new WebSocket connection
-func (ws *WebSocketService) HandleConnection(conn *websocket.Conn) {
+// HandleConnection handles a new WebSocket connection whose messages are
+// sent in the negotiated encoding
+func (ws *WebSocketService) HandleConnection(conn *websocket.Conn, encoding Encoding) {
 	client := &Client{
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		subs:     make(map[string]bool),
-		lastSent: make(map[string]time.Time),
+		id:                clientSeq.Add(1),
+		conn:              conn,
+		remoteAddr:        conn.RemoteAddr().String(),
+		encoding:          encoding,
+		send:              make(chan []byte, sendQueueSize),
+		pongs:             make(chan string, 1),
+		notices:           make(chan []byte, 1),
+		subs:              make(map[string]bool),
+		lastSent:          make(map[string]time.Time),
+		compressThreshold: ws.compressionThreshold,
 	}
+	if err := conn.SetCompressionLevel(ws.compressionLevel); err != nil {
+		client.logger().Warn("Invalid compression level", "level", ws.compressionLevel, "error", err)
+	}
+	if wire, ok := conn.NetConn().(*meteredConn); ok && wire.deflate {
+		client.wire = wire
+	}
+	if ws.messageRate > 0 {
+		client.limiter = rate.NewLimiter(rate.Limit(ws.messageRate), ws.messageBurst)
+	}
+	// Greet the client with the protocol version and its session
+	hello := ServerMessage{Type: "hello", Protocol: ProtocolVersion}
+	if ws.sessionTTL > 0 {
+		token, err := ws.openSession(client)
+		if err != nil {
+			client.logger().Error("Failed to open session", "error", err)
+		}
+		hello.Session = token
+	}
+	sendMessage(client, hello)
 
-	ws.register <- client
+	if !ws.addClient(client) {
+		ws.closeSession(client)
+		conn.WriteControl(websocket.CloseMessage, goingAway, time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
 
 	// Start goroutines for reading and writing
 	go client.writePump(ws)
 	client.readPump(ws)
 }
 
-// Run starts the WebSocket service
-func (ws *WebSocketService) Run() {
+// Run starts the shard workers fanning out updates and serves clients until
+// ctx is done, then closes every connection with a going-away frame and
+// returns once the frames have been written
+// Clients falling behind are checked every second, warned and evicted.
+func (ws *WebSocketService) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	for _, s := range ws.shards {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			s.run(ctx, ws)
+		}()
+	}
+	defer workers.Wait()
+
+	ticker := time.NewTicker(consumerCheckInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case client := <-ws.register:
-			ws.mu.Lock()
-			ws.clients[client] = true
-			ws.mu.Unlock()
-			log.Printf("WebSocket client connected. Total clients: %d", len(ws.clients))
-
-		case client := <-ws.unregister:
-			ws.mu.Lock()
-			if _, ok := ws.clients[client]; ok {
-				delete(ws.clients, client)
-				close(client.send)
-				// Remove client from all subscriptions
-				ws.removeClientFromAllSubscriptions(client)
-			}
-			ws.mu.Unlock()
-			log.Printf("WebSocket client disconnected. Total clients: %d", len(ws.clients))
-
-		case message := <-ws.broadcast:
-			ws.mu.RLock()
-			for client := range ws.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(ws.clients, client)
-					ws.removeClientFromAllSubscriptions(client)
-				}
-			}
-			ws.mu.RUnlock()
+		case <-ctx.Done():
+			ws.shutdown()
+			return
+
+		case now := <-ticker.C:
+			ws.checkConsumers(now)
 		}
 	}
 }
 
-// SubscribeToBinanceStream subscribes to Binance WebSocket stream for a symbol and interval
-func (ws *WebSocketService) SubscribeToBinanceStream(symbol, interval string) error {
-	// Start a goroutine to handle Binance stream
-	go func() {
-		err := ws.binanceSvc.SubscribeKlineStream(symbol, interval, func(kline models.Kline) {
-			// Store to database
-			if err := ws.klineRepo.SafeCreateOrUpdateKline(&kline); err != nil {
-				log.Printf("Error storing kline to database: %v", err)
-			}
+// addClient registers a client whose write pump is about to start, unless
+// the service is shutting down
+func (ws *WebSocketService) addClient(client *Client) bool {
+	ws.mu.Lock()
+	select {
+	case <-ws.done:
+		ws.mu.Unlock()
+		return false
+	default:
+	}
+	ws.clients[client] = true
+	ws.pumps.Add(1)
+	total := len(ws.clients)
+	ws.mu.Unlock()
+
+	metrics.WebSocketClients.Inc()
+	client.logger().Info("WebSocket client connected", "remote_addr", client.remoteAddr, "clients", total)
+	return true
+}
 
-			// Broadcast to subscribed clients with throttling
-			ws.broadcastKlineUpdate(kline)
-		})
+// removeClient unregisters a disconnected client, unless it was evicted or
+// the service shut down
+func (ws *WebSocketService) removeClient(client *Client) {
+	ws.mu.Lock()
+	_, ok := ws.clients[client]
+	delete(ws.clients, client)
+	total := len(ws.clients)
+	ws.mu.Unlock()
+	if !ok {
+		return
+	}
 
-		if err != nil {
-			log.Printf("Error subscribing to Binance stream for %s %s: %v", symbol, interval, err)
-		}
-	}()
+	client.close()
+	// Keep its subscriptions for resumption, then remove client from all
+	// subscriptions
+	ws.closeSession(client)
+	ws.removeClientFromAllSubscriptions(client)
+	client.disconnected()
+	if drops := client.drops.Load(); drops > 0 {
+		client.logger().Warn("WebSocket client dropped messages on a full send queue", "dropped", drops)
+	}
+	client.logger().Info("WebSocket client disconnected", "clients", total)
+}
 
-	return nil
+// shutdown stops accepting clients and closes the connected ones
+func (ws *WebSocketService) shutdown() {
+	close(ws.done)
+
+	ws.mu.Lock()
+	total := len(ws.clients)
+	for client := range ws.clients {
+		delete(ws.clients, client)
+		client.close()
+		ws.removeClientFromAllSubscriptions(client)
+		client.disconnected()
+	}
+	ws.mu.Unlock()
+
+	ws.pumps.Wait()
+	wsLog.Info("WebSocket service stopped", "closed_clients", total)
 }
 
-// broadcastKlineUpdate broadcasts kline update to all subscribed clients with throttling
-func (ws *WebSocketService) broadcastKlineUpdate(kline models.Kline) {
-	key := fmt.Sprintf("%s:%s", kline.Symbol, kline.Interval)
+// OnKline queues a kline collected for a tracked series for broadcast; it
+// never waits for clients
+func (ws *WebSocketService) OnKline(ctx context.Context, kline models.Kline) {
+	ws.publish(ctx, kline)
+}
 
-	ws.subsMu.RLock()
-	clients, exists := ws.subscriptions[key]
-	ws.subsMu.RUnlock()
+// OnSeriesRemoved tells subscribed clients that a series is no longer tracked
+// and drops their subscriptions to it
+func (ws *WebSocketService) OnSeriesRemoved(series Series) {
+	key := series.Key()
+	clients := ws.dropTopic(key)
 
-	if !exists || len(clients) == 0 {
-		return
+	msg := ServerMessage{
+		Type:     "series_removed",
+		Symbol:   series.Symbol,
+		Interval: series.Interval,
+		Message:  "series is no longer tracked",
+	}
+	for _, client := range clients {
+		client.forget(key)
+		sendMessage(client, msg)
 	}
 
-	// Create message
-	msg := ServerMessage{
-		Type:     "kline_update",
-		Symbol:   kline.Symbol,
-		Interval: kline.Interval,
-		Data: map[string]interface{}{
-			"open_time":  kline.OpenTime,
-			"close_time": kline.CloseTime,
-			"open":       fmt.Sprintf("%.8f", kline.OpenPrice),
-			"high":       fmt.Sprintf("%.8f", kline.HighPrice),
-			"low":        fmt.Sprintf("%.8f", kline.LowPrice),
-			"close":      fmt.Sprintf("%.8f", kline.ClosePrice),
-			"volume":     fmt.Sprintf("%.8f", kline.Volume),
-		},
-	}
-
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Error marshaling kline update message: %v", err)
+	if len(clients) > 0 {
+		wsLog.Info("Notified clients that series is no longer tracked", "symbol", series.Symbol, "interval", series.Interval, "clients", len(clients))
+	}
+}
+
+// broadcastKlineUpdate broadcasts kline update to all subscribed clients with
+// throttling; the shard worker of the series calls it, so the updates of a
+// series are broadcast in order
+func (ws *WebSocketService) broadcastKlineUpdate(ctx context.Context, kline models.Kline) {
+	start := time.Now()
+	ctx, span := wsTracer.Start(ctx, "websocket.broadcast", seriesAttributes(Series{Symbol: kline.Symbol, Interval: kline.Interval}))
+	defer span.End()
+
+	// Remember the kline and pick its recipients at once: clients subscribing
+	// later find it in their snapshot instead
+	t := ws.lockTopic(kline.Symbol, kline.Interval)
+	seq := t.log.append(kline)
+	clients := t.clients()
+	t.mu.Unlock()
+
+	if len(clients) == 0 {
+		span.SetAttributes(attribute.Int("websocket.clients", 0))
 		return
 	}
 
-	// Send to each subscribed client with throttling check
-	ws.subsMu.RLock()
-	for client := range clients {
-		client.mu.Lock()
-		lastSent, exists := client.lastSent[key]
-		shouldSend := !exists || time.Since(lastSent) >= throttleInterval
-		client.mu.Unlock()
-
-		if shouldSend {
-			select {
-			case client.send <- msgBytes:
-				client.mu.Lock()
-				client.lastSent[key] = time.Now()
-				client.mu.Unlock()
-			default:
-				// Channel full, skip this client
-			}
+	// Send to each subscribed client, or hold the update back until its
+	// throttle interval has passed; the update is encoded once per encoding
+	var encoded [encodingCount][]byte
+	var sent, deferred, dropped int
+	span.SetAttributes(attribute.Int("websocket.clients", len(clients)))
+	for _, client := range clients {
+		msgBytes := encoded[client.encoding]
+		if msgBytes == nil {
+			msgBytes = t.encode(client.encoding, kline, seq)
+			encoded[client.encoding] = msgBytes
+		}
+		switch client.deliver(t.key, kline.OpenTime, msgBytes, ws.throttleInterval) {
+		case deliverySent:
+			sent++
+		case deliveryDeferred:
+			deferred++
+		case deliveryDropped:
+			dropped++
 		}
 	}
-	ws.subsMu.RUnlock()
+	span.SetAttributes(attribute.Int("websocket.sent", sent), attribute.Int("websocket.deferred", deferred), attribute.Int("websocket.dropped", dropped))
+	metrics.WebSocketFanoutDuration.Observe(time.Since(start).Seconds())
 }
 
 // readPump reads messages from the WebSocket connection
 func (c *Client) readPump(ws *WebSocketService) {
 	defer func() {
-		ws.unregister <- c
+		ws.removeClient(c)
 		c.conn.Close()
 	}()
 
@@ -212,67 +541,124 @@ func (c *Client) readPump(ws *WebSocketService) {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 		return nil
 	})
+	c.conn.SetPingHandler(func(data string) error {
+		select {
+		case c.pongs <- data:
+		default:
+			// A pong is already waiting, and answers this ping too
+		}
+		return nil
+	})
 
-	for {
-		_, message, err := c.conn.ReadMessage()
+	for first := true; ; first = false {
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				c.logger().Warn("WebSocket error", "error", err)
 			}
 			break
 		}
 
-		// Parse client message
-		var clientMsg ClientMessage
-		if err := json.Unmarshal(message, &clientMsg); err != nil {
-			log.Printf("Error parsing client message: %v", err)
+		if !c.allowMessage() {
+			continue
+		}
+
+		// Parse client message, in the encoding of its frame
+		clientMsg, err := DecodeClientMessage(frameEncoding(messageType), message)
+		if err != nil {
+			c.logger().Debug("Error parsing client message", "error", err)
 			sendError(c, "Invalid message format")
 			continue
 		}
 
-		// Handle subscribe/unsubscribe
+		// Handle the client's request
 		switch clientMsg.Action {
+		case "hello":
+			ws.handleHello(c, clientMsg, first)
 		case "subscribe":
-			ws.handleSubscribe(c, clientMsg.Symbol, clientMsg.Interval)
+			ws.handleSubscribe(c, clientMsg.Symbol, clientMsg.Interval, clientMsg.Snapshot, clientMsg.ThrottleMs)
 		case "unsubscribe":
 			ws.handleUnsubscribe(c, clientMsg.Symbol, clientMsg.Interval)
+		case "resume":
+			ws.handleResume(c, clientMsg.Session, clientMsg.Symbol, clientMsg.Interval, clientMsg.Seq, clientMsg.ThrottleMs)
 		default:
 			sendError(c, fmt.Sprintf("Unknown action: %s", clientMsg.Action))
 		}
 	}
 }
 
+// allowMessage takes a token for an inbound message; when none is left the
+// message is dropped and, on the first drop, the client is sent an error
+// saying when it may send again
+func (c *Client) allowMessage() bool {
+	if c.limiter == nil {
+		return true
+	}
+	reservation := c.limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		c.limited = false
+		return true
+	}
+	reservation.Cancel()
+	metrics.RateLimited.WithLabelValues("ws_messages").Inc()
+
+	if !c.limited {
+		c.limited = true
+		c.logger().Warn("WebSocket client exceeded the message rate", "retry_after", delay)
+		sendMessage(c, ServerMessage{
+			Type:         "error",
+			Message:      "Message rate exceeded, messages are dropped until the retry time",
+			RetryAfterMs: max(delay.Milliseconds(), 1),
+		})
+	}
+	return false
+}
+
 // writePump writes messages to the WebSocket connection
 func (c *Client) writePump(ws *WebSocketService) {
 	ticker := time.NewTicker(54 * time.Second)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		ws.pumps.Done()
 	}()
 
 	for {
+		// A notice is not held up behind a full queue
 		select {
+		case notice := <-c.notices:
+			if err := c.writeNotice(notice); err != nil {
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case notice := <-c.notices:
+			if err := c.writeNotice(notice); err != nil {
+				return
+			}
+
 		case message, ok := <-c.send:
+			if frame := c.evicted(); frame != nil {
+				// The queue is not drained for a client too slow to read it
+				c.conn.WriteControl(websocket.CloseMessage, frame, time.Now().Add(time.Second))
+				return
+			}
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.WriteMessage(websocket.CloseMessage, ws.closeMessage())
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			if err := c.write(message); err != nil {
 				return
 			}
-			w.Write(message)
 
-			// Add queued messages
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+		case data := <-c.pongs:
+			if err := c.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second)); err != nil {
 				return
 			}
 
@@ -285,39 +671,202 @@ func (c *Client) writePump(ws *WebSocketService) {
 	}
 }
 
+// writeNotice sends a notice in a frame of its own
+func (c *Client) writeNotice(notice []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.writeFrame(notice)
+}
+
+// write sends message in a frame of its own, or together with the messages
+// queued behind it in a batch envelope if the client negotiated batching
+func (c *Client) write(message []byte) error {
+	n := len(c.send)
+	if n == 0 || !c.batch.Load() {
+		return c.writeFrame(message)
+	}
+
+	// The envelope is assembled first, so whether it is worth compressing
+	// is known from its size
+	// A MessagePack envelope counts its messages instead of delimiting them.
+	delimited := c.encoding == EncodingJSON
+	frame := appendBatchHeader(make([]byte, 0, (n+1)*(len(message)+1)+64), c.encoding, n+1)
+	frame = append(frame, message...)
+	for i := 0; i < n; i++ {
+		if delimited {
+			frame = append(frame, ',')
+		}
+		frame = append(frame, <-c.send...)
+	}
+	if delimited {
+		frame = append(frame, batchSuffix...)
+	}
+	return c.writeFrame(frame)
+}
+
+// closeMessage returns the payload of the close frame sent to a client that
+// is being disconnected
+func (ws *WebSocketService) closeMessage() []byte {
+	select {
+	case <-ws.done:
+		return goingAway
+	default:
+		return []byte{}
+	}
+}
+
+// handleHello negotiates the protocol with a client, which may send hello as
+// its first message only
+// Clients asking for a newer version than the server speaks are answered
+// with the server's version and decide whether to go on.
+func (ws *WebSocketService) handleHello(client *Client, msg ClientMessage, first bool) {
+	if !first {
+		sendError(client, "hello must be the first message")
+		return
+	}
+	if msg.Protocol < 1 {
+		sendError(client, fmt.Sprintf("protocol version is required, the server speaks %d", ProtocolVersion))
+		return
+	}
+
+	client.batch.Store(msg.Batch)
+	ws.sessionsMu.Lock()
+	token := client.session
+	ws.sessionsMu.Unlock()
+	sendMessage(client, ServerMessage{
+		Type:     "hello",
+		Protocol: min(msg.Protocol, ProtocolVersion),
+		Batch:    msg.Batch,
+		Session:  token,
+	})
+	client.logger().Debug("Client negotiated protocol", "protocol", msg.Protocol, "batch", msg.Batch)
+}
+
 // handleSubscribe handles client subscription
-func (ws *WebSocketService) handleSubscribe(client *Client, symbol, interval string) {
+// With a positive snapshot the last klines of the series follow the
+// confirmation, stitched with the live updates so none is missed or repeated.
+// Updates are sent at most every throttleMs, as negotiated within the
+// server's bounds.
+func (ws *WebSocketService) handleSubscribe(client *Client, symbol, interval string, snapshot int, throttleMs int64) {
+	if err := ws.subscribe(client, symbol, interval, snapshot, throttleMs); err != nil {
+		sendError(client, err.Error())
+	}
+}
+
+// subscribe subscribes client to a series as handleSubscribe does, returning
+// why it cannot instead of telling the client; the errors are worded for
+// clients
+func (ws *WebSocketService) subscribe(client *Client, symbol, interval string, snapshot int, throttleMs int64) error {
 	if symbol == "" || interval == "" {
-		sendError(client, "Symbol and interval are required")
-		return
+		return errors.New("Symbol and interval are required")
+	}
+	if snapshot < 0 || snapshot > ws.maxSnapshot {
+		return fmt.Errorf("snapshot must be between 0 and %d", ws.maxSnapshot)
 	}
 
+	symbol = strings.ToUpper(symbol)
 	key := fmt.Sprintf("%s:%s", symbol, interval)
 
-	// Add to client's subscriptions
-	client.mu.Lock()
-	client.subs[key] = true
-	client.mu.Unlock()
+	if ws.maxSubscriptions > 0 {
+		client.mu.RLock()
+		full := !client.subs[key] && len(client.subs) >= ws.maxSubscriptions
+		client.mu.RUnlock()
+		if full {
+			metrics.RateLimited.WithLabelValues("ws_subscriptions").Inc()
+			return fmt.Errorf("Subscription limit of %d reached, unsubscribe from a series first", ws.maxSubscriptions)
+		}
+	}
 
-	// Add client to subscription map
-	ws.subsMu.Lock()
-	if ws.subscriptions[key] == nil {
-		ws.subscriptions[key] = make(map[*Client]bool)
-		// First client for this subscription, start Binance stream
-		go ws.SubscribeToBinanceStream(symbol, interval)
+	// Stored klines are read first; klines broadcast meanwhile are among the
+	// recent ones merged into the snapshot below
+	var stored []models.Kline
+	if snapshot > 0 && ws.history != nil {
+		var err error
+		if stored, err = ws.loadSnapshot(symbol, interval, snapshot); err != nil {
+			client.logger().Warn("Failed to load subscription snapshot", "symbol", symbol, "interval", interval, "error", err)
+			return fmt.Errorf("Failed to load snapshot of %s %s: %w", symbol, interval, ErrSnapshotUnavailable)
+		}
 	}
-	ws.subscriptions[key][client] = true
-	ws.subsMu.Unlock()
 
-	// Send confirmation
-	msg := ServerMessage{
-		Type:     "subscribed",
-		Symbol:   symbol,
-		Interval: interval,
+	// Attach to the collector's stream; checking under the topic's lock keeps
+	// the subscription from outliving a concurrent removal of the series
+	t := ws.lockTopic(symbol, interval)
+	defer t.mu.Unlock()
+	if !ws.catalog.IsTracked(symbol, interval) {
+		return fmt.Errorf("%s %s is %w", symbol, interval, ErrNotTracked)
 	}
-	sendMessage(client, msg)
 
-	log.Printf("Client subscribed to %s %s", symbol, interval)
+	// Add to client's subscriptions; held back updates are in the snapshot
+	throttle := ws.negotiateThrottle(throttleMs)
+	if !client.subscribe(key, t.epoch, throttle, snapshot > 0) {
+		return nil
+	}
+	t.add(client)
+
+	// Send confirmation, and the snapshot while no update can be broadcast
+	log := &t.log
+	sendMessage(client, ServerMessage{
+		Type:       "subscribed",
+		Symbol:     symbol,
+		Interval:   interval,
+		Seq:        log.seq,
+		ThrottleMs: throttle.Milliseconds(),
+	})
+	if snapshot > 0 {
+		klines := stitchSnapshot(stored, log.klines(), snapshot)
+		data := make([]KlineData, len(klines))
+		for i, kline := range klines {
+			data[i] = klineData(kline)
+		}
+		sendMessage(client, ServerMessage{
+			Type:     "snapshot",
+			Symbol:   symbol,
+			Interval: interval,
+			Data:     data,
+			Seq:      log.seq,
+		})
+	}
+
+	client.logger().Info("Client subscribed", "symbol", symbol, "interval", interval, "snapshot", snapshot, "throttle", throttle)
+	return nil
+}
+
+// loadSnapshot reads the last n stored klines of a series, most recent first
+func (ws *WebSocketService) loadSnapshot(symbol, interval string, n int) ([]models.Kline, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+	ctx, span := wsTracer.Start(ctx, "websocket.snapshot", seriesAttributes(Series{Symbol: symbol, Interval: interval}))
+	defer span.End()
+
+	klines, err := ws.history.GetKlines(ctx, symbol, interval, nil, nil, n)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("websocket.snapshot.klines", len(klines)))
+	return klines, nil
+}
+
+// stitchSnapshot merges stored klines with recently broadcast ones into the
+// last n klines in ascending open time; a broadcast kline replaces a stored
+// one with the same open time
+func stitchSnapshot(stored, recent []models.Kline, n int) []models.Kline {
+	byOpenTime := make(map[int64]models.Kline, len(stored)+len(recent))
+	for _, kline := range stored {
+		byOpenTime[kline.OpenTime] = kline
+	}
+	for _, kline := range recent {
+		byOpenTime[kline.OpenTime] = kline
+	}
+
+	klines := make([]models.Kline, 0, len(byOpenTime))
+	for _, kline := range byOpenTime {
+		klines = append(klines, kline)
+	}
+	sort.Slice(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+	if len(klines) > n {
+		klines = klines[len(klines)-n:]
+	}
+	return klines
 }
 
 // handleUnsubscribe handles client unsubscription
@@ -327,24 +876,19 @@ func (ws *WebSocketService) handleUnsubscribe(client *Client, symbol, interval s
 		return
 	}
 
+	symbol = strings.ToUpper(symbol)
 	key := fmt.Sprintf("%s:%s", symbol, interval)
 
 	// Remove from client's subscriptions
-	client.mu.Lock()
-	delete(client.subs, key)
-	delete(client.lastSent, key)
-	client.mu.Unlock()
-
-	// Remove client from subscription map
-	ws.subsMu.Lock()
-	if clients, exists := ws.subscriptions[key]; exists {
-		delete(clients, client)
-		if len(clients) == 0 {
-			// No more clients, could stop Binance stream here if needed
-			delete(ws.subscriptions, key)
-		}
+	client.forget(key)
+
+	// Remove client from the topic; the collector keeps streaming the series
+	// for storage
+	if t := ws.findTopic(key); t != nil {
+		t.mu.Lock()
+		t.remove(client)
+		t.mu.Unlock()
 	}
-	ws.subsMu.Unlock()
 
 	// Send confirmation
 	msg := ServerMessage{
@@ -354,34 +898,37 @@ func (ws *WebSocketService) handleUnsubscribe(client *Client, symbol, interval s
 	}
 	sendMessage(client, msg)
 
-	log.Printf("Client unsubscribed from %s %s", symbol, interval)
+	client.logger().Info("Client unsubscribed", "symbol", symbol, "interval", interval)
 }
 
 // removeClientFromAllSubscriptions removes client from all subscriptions
+// The client must be closed first, so it cannot subscribe again meanwhile.
 func (ws *WebSocketService) removeClientFromAllSubscriptions(client *Client) {
-	ws.subsMu.Lock()
-	for key, clients := range ws.subscriptions {
-		delete(clients, client)
-		if len(clients) == 0 {
-			delete(ws.subscriptions, key)
+	client.mu.RLock()
+	keys := make([]string, 0, len(client.subs))
+	for key := range client.subs {
+		keys = append(keys, key)
+	}
+	client.mu.RUnlock()
+
+	for _, key := range keys {
+		if t := ws.findTopic(key); t != nil {
+			t.mu.Lock()
+			t.remove(client)
+			t.mu.Unlock()
 		}
 	}
-	ws.subsMu.Unlock()
 }
 
-// sendMessage sends a message to a client
+// sendMessage sends a message to a client in its encoding
 func sendMessage(client *Client, msg ServerMessage) {
-	msgBytes, err := json.Marshal(msg)
+	msgBytes, err := EncodeServerMessage(client.encoding, msg)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		wsLog.Error("Error marshaling message", "error", err)
 		return
 	}
 
-	select {
-	case client.send <- msgBytes:
-	default:
-		log.Printf("Client send channel full, dropping message")
-	}
+	client.enqueue(msgBytes)
 }
 
 // sendError sends an error message to a client
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"crypto-monitor/internal/metrics"
+)
+
+// meteredConn is a hijacked connection counting the bytes written to it, so
+// the compression of frames is measured on the wire
+type meteredConn struct {
+	net.Conn
+	written atomic.Uint64
+	// deflate is set when the client negotiated permessage-deflate
+	deflate bool
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written.Add(uint64(n))
+	return n, err
+}
+
+// meteredWriter hands out its connection metered when it is hijacked
+type meteredWriter struct {
+	http.ResponseWriter
+	deflate bool
+}
+
+func (w meteredWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &meteredConn{Conn: conn, deflate: w.deflate}, rw, nil
+}
+
+// MeterUpgrade wraps the response a websocket connection is upgraded through,
+// so the compression of the frames it is sent is measured; compression is
+// whether the upgrader enables permessage-deflate
+func MeterUpgrade(w http.ResponseWriter, r *http.Request, compression bool) http.ResponseWriter {
+	return meteredWriter{ResponseWriter: w, deflate: compression && offersDeflate(r.Header)}
+}
+
+// offersDeflate reports whether a handshake offers permessage-deflate, which
+// the upgrader then accepts whatever its parameters
+func offersDeflate(header http.Header) bool {
+	for _, value := range header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(ext, ";")
+			if strings.TrimSpace(name) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeFrame writes a data frame, compressed if the client negotiated
+// permessage-deflate and the frame reaches the compression threshold
+func (c *Client) writeFrame(payload []byte) error {
+	frameType := c.encoding.frameType()
+	compress := len(payload) >= c.compressThreshold
+	c.conn.EnableWriteCompression(compress)
+	if c.wire == nil {
+		return c.conn.WriteMessage(frameType, payload)
+	}
+
+	label := strconv.FormatBool(compress)
+	metrics.WebSocketFrames.WithLabelValues(label).Inc()
+	// Frames and pongs are written by the write pump alone, so what the
+	// connection was written meanwhile is the frame
+	written := c.wire.written.Load()
+	start := time.Now()
+	err := c.conn.WriteMessage(frameType, payload)
+	metrics.WebSocketWriteDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+	if !compress {
+		return err
+	}
+	metrics.WebSocketCompressionInputBytes.Add(float64(len(payload)))
+	metrics.WebSocketCompressionOutputBytes.Add(float64(c.wire.written.Load() - written))
+	return err
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/metrics"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// compressionCounts is a reading of the compression metrics
+type compressionCounts struct {
+	compressed, uncompressed float64
+	input, output            float64
+}
+
+func readCompressionCounts() compressionCounts {
+	return compressionCounts{
+		compressed:   testutil.ToFloat64(metrics.WebSocketFrames.WithLabelValues("true")),
+		uncompressed: testutil.ToFloat64(metrics.WebSocketFrames.WithLabelValues("false")),
+		input:        testutil.ToFloat64(metrics.WebSocketCompressionInputBytes),
+		output:       testutil.ToFloat64(metrics.WebSocketCompressionOutputBytes),
+	}
+}
+
+// setupCompressionServer serves a service with snapshots of 200 stored
+// klines through an upgrader enabling permessage-deflate
+func setupCompressionServer(t *testing.T) *httptest.Server {
+	collector, _, _, _ := setupTestCollector(t)
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	history := &fakeHistory{}
+	for i := int64(1); i <= 200; i++ {
+		history.klines = append(history.klines, testKline(i*60000))
+	}
+	wsSvc := NewWebSocketService(collector, history, config.Default().WebSocket)
+	go wsSvc.Run(t.Context())
+
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(MeterUpgrade(w, r, upgrader.EnableCompression), r, nil)
+		if err != nil {
+			return
+		}
+		wsSvc.HandleConnection(conn, EncodingJSON)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// subscribeSnapshot subscribes to BTCUSDT 1m with a snapshot of n klines and
+// returns the klines received
+func subscribeSnapshot(t *testing.T, conn *websocket.Conn, n int) []interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("Failed to read hello: %v", err)
+	}
+	conn.WriteJSON(ClientMessage{Action: "subscribe", Symbol: "BTCUSDT", Interval: "1m", Snapshot: n})
+	for _, want := range []string{"subscribed", "snapshot"} {
+		var msg ServerMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed to read %s: %v", want, err)
+		}
+		if msg.Type != want {
+			t.Fatalf("Expected %s, got %+v", want, msg)
+		}
+		if want == "snapshot" {
+			klines, _ := msg.Data.([]interface{})
+			return klines
+		}
+	}
+	return nil
+}
+
+// TestWebSocketService_Compression tests that a client offering
+// permessage-deflate gets large frames compressed and small ones not
+func TestWebSocketService_Compression(t *testing.T) {
+	server := setupCompressionServer(t)
+	before := readCompressionCounts()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.HasPrefix(ext, "permessage-deflate") {
+		t.Fatalf("Expected permessage-deflate to be negotiated, got %q", ext)
+	}
+
+	if klines := subscribeSnapshot(t, conn, 200); len(klines) != 200 {
+		t.Fatalf("Expected 200 klines in the snapshot, got %d", len(klines))
+	}
+
+	// The pump counts a frame once it is written, after the client may read it
+	var after compressionCounts
+	waitFor(t, time.Second, func() bool {
+		after = readCompressionCounts()
+		return after.compressed > before.compressed && after.uncompressed >= before.uncompressed+2
+	})
+	input, output := after.input-before.input, after.output-before.output
+	if input < 200*100 {
+		t.Errorf("Expected the snapshot to be counted as compressed input, got %v bytes", input)
+	}
+	if output <= 0 || output > input/4 {
+		t.Errorf("Expected the snapshot to deflate to under a quarter of %v bytes, got %v", input, output)
+	}
+}
+
+// TestWebSocketService_CompressionNotOffered tests that clients not offering
+// permessage-deflate are served uncompressed frames, without metering
+func TestWebSocketService_CompressionNotOffered(t *testing.T) {
+	server := setupCompressionServer(t)
+	before := readCompressionCounts()
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Fatalf("Expected no extension to be negotiated, got %q", ext)
+	}
+
+	if klines := subscribeSnapshot(t, conn, 200); len(klines) != 200 {
+		t.Fatalf("Expected 200 klines in the snapshot, got %d", len(klines))
+	}
+	if after := readCompressionCounts(); after != before {
+		t.Errorf("Expected no compression metrics for the client, got %+v after %+v", after, before)
+	}
+}
+
+// TestWebSocketService_PingPong tests that the write pump answers the pings
+// of a client with pongs carrying their payload
+func TestWebSocketService_PingPong(t *testing.T) {
+	server := setupCompressionServer(t)
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, _, err := dialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	pongs := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pongs <- data
+		return nil
+	})
+	if err := conn.WriteControl(websocket.PingMessage, []byte("ping-1"), time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Failed to send ping: %v", err)
+	}
+
+	// Pongs are handled while reading; hello is the only message sent
+	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	select {
+	case data := <-pongs:
+		if data != "ping-1" {
+			t.Errorf("Expected the pong to carry the ping payload, got %q", data)
+		}
+	default:
+		t.Error("Expected a pong")
+	}
+}
+
+// TestOffersDeflate tests recognizing permessage-deflate among the offered
+// extensions
+func TestOffersDeflate(t *testing.T) {
+	for value, want := range map[string]bool{
+		"permessage-deflate; client_max_window_bits": true,
+		"x-webkit-deflate-frame, permessage-deflate": true,
+		"x-webkit-deflate-frame":                     false,
+		"":                                           false,
+	} {
+		header := http.Header{}
+		if value != "" {
+			header.Set("Sec-WebSocket-Extensions", value)
+		}
+		if got := offersDeflate(header); got != want {
+			t.Errorf("Expected %q to offer deflate %t, got %t", value, want, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"time"
+)
+
+// delivery is the outcome of offering an update to a client
+type delivery int
+
+const (
+	deliverySent delivery = iota
+	// deliveryDeferred means the update waits for the throttle interval
+	deliveryDeferred
+	deliveryDropped
+)
+
+// pendingUpdate is an update held back by the throttle
+type pendingUpdate struct {
+	openTime int64
+	message  []byte
+}
+
+// pendingUpdates are the updates of a subscription waiting for its throttle
+// interval to pass, conflated to the latest update of the topic
+// The last update of the kline before the latest is kept too: a kline
+// superseded while held back has closed, and that update carries its final
+// values, which a client would otherwise never receive. Klines that opened
+// and closed within one interval are dropped altogether.
+type pendingUpdates struct {
+	// closed is the last update of the kline before latest, if any
+	closed *pendingUpdate
+	latest pendingUpdate
+	timer  *time.Timer
+}
+
+// put adds an update, replacing the pending update of the same kline
+func (p *pendingUpdates) put(openTime int64, message []byte) {
+	update := pendingUpdate{openTime: openTime, message: message}
+	switch {
+	case p.latest.message == nil || openTime == p.latest.openTime:
+		p.latest = update
+	case openTime > p.latest.openTime:
+		closed := p.latest
+		p.closed, p.latest = &closed, update
+	case p.closed == nil || openTime >= p.closed.openTime:
+		// A late update of an earlier kline
+		p.closed = &update
+	}
+}
+
+// messages returns the held back messages, oldest kline first
+func (p *pendingUpdates) messages() [][]byte {
+	if p.closed == nil {
+		return [][]byte{p.latest.message}
+	}
+	return [][]byte{p.closed.message, p.latest.message}
+}
+
+// throttleFor returns the throttle interval of a subscription; the caller
+// holds mu
+func (c *Client) throttleFor(key string, fallback time.Duration) time.Duration {
+	if throttle, ok := c.throttles[key]; ok {
+		return throttle
+	}
+	return fallback
+}
+
+// deliver sends an update of the subscription key, unless one was sent less
+// than its throttle interval ago; the update is then held back and sent when
+// the interval has passed
+// Held back updates are conflated to the latest, see pendingUpdates.
+func (c *Client) deliver(key string, openTime int64, message []byte, fallback time.Duration) delivery {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return deliveryDropped
+	}
+	if p, ok := c.pending[key]; ok {
+		p.put(openTime, message)
+		c.mu.Unlock()
+		return deliveryDeferred
+	}
+
+	now := time.Now()
+	if last, ok := c.lastSent[key]; ok {
+		if wait := c.throttleFor(key, fallback) - now.Sub(last); wait > 0 {
+			p := &pendingUpdates{}
+			p.put(openTime, message)
+			p.timer = time.AfterFunc(wait, func() { c.flush(key, p) })
+			if c.pending == nil {
+				c.pending = make(map[string]*pendingUpdates)
+			}
+			c.pending[key] = p
+			c.mu.Unlock()
+			return deliveryDeferred
+		}
+	}
+	if c.lastSent == nil {
+		c.lastSent = make(map[string]time.Time)
+	}
+	c.lastSent[key] = now
+	c.mu.Unlock()
+
+	if !c.enqueue(message) {
+		return deliveryDropped
+	}
+	return deliverySent
+}
+
+// flush sends the held back updates of a subscription once its throttle
+// interval has passed
+func (c *Client) flush(key string, p *pendingUpdates) {
+	c.mu.Lock()
+	if c.pending[key] != p {
+		// Cancelled by unsubscribing, or the client closed
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, key)
+	c.lastSent[key] = time.Now()
+	c.mu.Unlock()
+
+	for _, message := range p.messages() {
+		c.enqueue(message)
+	}
+}
+
+// cancelPending discards the held back updates of a subscription; the caller
+// holds mu
+func (c *Client) cancelPending(key string) {
+	if p, ok := c.pending[key]; ok {
+		p.timer.Stop()
+		delete(c.pending, key)
+	}
+}
+
+// subscribe records a subscription to the topic of epoch with its throttle
+// interval, unless the client is closed; it reports whether it did
+// Held back updates are discarded with dropPending, when the client is sent
+// everything up to now instead.
+func (c *Client) subscribe(key string, epoch uint64, throttle time.Duration, dropPending bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.subs[key] = true
+	if c.throttles == nil {
+		c.throttles = make(map[string]time.Duration)
+	}
+	c.throttles[key] = throttle
+	if c.epochs == nil {
+		c.epochs = make(map[string]uint64)
+	}
+	c.epochs[key] = epoch
+	if dropPending {
+		c.cancelPending(key)
+	}
+	return true
+}
+
+// epochOf returns the epoch of the topic of a subscription, 0 if the client
+// is not subscribed
+func (c *Client) epochOf(key string) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.epochs[key]
+}
+
+// forget drops a subscription and its delivery state
+func (c *Client) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subs, key)
+	delete(c.lastSent, key)
+	delete(c.throttles, key)
+	delete(c.epochs, key)
+	c.cancelPending(key)
+}
+
+// negotiateThrottle returns the throttle interval granted for a request of
+// throttleMs milliseconds, 0 meaning the default, within the server's bounds
+func (ws *WebSocketService) negotiateThrottle(throttleMs int64) time.Duration {
+	if throttleMs <= 0 {
+		return ws.throttleInterval
+	}
+	if throttleMs >= ws.maxThrottle.Milliseconds() {
+		return ws.maxThrottle
+	}
+	return max(time.Duration(throttleMs)*time.Millisecond, ws.minThrottle)
+}
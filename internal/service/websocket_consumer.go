@@ -0,0 +1,94 @@
+package service
+
+import (
+	"time"
+
+	"crypto-monitor/internal/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+// consumerCheckInterval is how often Run looks for clients falling behind
+const consumerCheckInterval = time.Second
+
+// lag returns how long the client's queue has not drained, 0 when it is empty
+func (c *Client) lag(now time.Time) time.Duration {
+	if len(c.send) == 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(0, c.backlogSince.Load()))
+}
+
+// evicted returns the close frame of an evicted client, nil otherwise
+func (c *Client) evicted() []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closeFrame
+}
+
+// notify hands a message to the write pump ahead of the queued ones; it is
+// discarded if a notice is already waiting
+func (c *Client) notify(msg ServerMessage) {
+	message, err := EncodeServerMessage(c.encoding, msg)
+	if err != nil {
+		wsLog.Error("Error marshaling message", "error", err)
+		return
+	}
+	select {
+	case c.notices <- message:
+	default:
+	}
+}
+
+// checkConsumers warns clients whose queue fills up or does not drain, and
+// evicts those that still fall behind past the eviction thresholds
+// Only the messages dropped since the queue last drained count towards
+// eviction, so a client that recovered from a burst is not evicted for it.
+// Evicted clients keep their session, so they can resume and catch up on a
+// new connection.
+func (ws *WebSocketService) checkConsumers(now time.Time) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	for client := range ws.clients {
+		depth := len(client.send)
+		lag := client.lag(now)
+		drops := client.drops.Load() - client.dropBase
+
+		switch {
+		case ws.evictLag > 0 && lag >= ws.evictLag:
+			ws.evict(client, "lag", "slow consumer: send queue not drained for "+lag.Round(time.Second).String())
+		case ws.evictDrops > 0 && drops >= ws.evictDrops:
+			ws.evict(client, "drops", "slow consumer: send queue overflowed")
+		case !client.warned && ((ws.slowQueueDepth > 0 && depth >= ws.slowQueueDepth) || (ws.slowLag > 0 && lag >= ws.slowLag)):
+			client.warned = true
+			metrics.WebSocketSlowConsumers.Inc()
+			client.logger().Warn("WebSocket client is falling behind", "queue_depth", depth, "lag", lag, "dropped", drops)
+			client.notify(ServerMessage{
+				Type:    "slow_consumer",
+				Message: "You are falling behind and will be disconnected unless you read faster",
+				Data: map[string]interface{}{
+					"queue_depth": depth,
+					"queue_size":  cap(client.send),
+					"lag_ms":      lag.Milliseconds(),
+					"dropped":     drops,
+				},
+			})
+		case depth == 0:
+			client.warned = false
+			client.dropBase += drops
+		}
+	}
+}
+
+// evict disconnects a slow client with a policy violation close frame; the
+// caller holds mu
+func (ws *WebSocketService) evict(client *Client, reason, message string) {
+	delete(ws.clients, client)
+	client.closeWith(websocket.FormatCloseMessage(websocket.ClosePolicyViolation, message))
+	ws.closeSession(client)
+	ws.removeClientFromAllSubscriptions(client)
+	client.disconnected()
+	metrics.WebSocketEvictions.WithLabelValues(reason).Inc()
+	client.logger().Warn("Evicted slow WebSocket client", "reason", reason, "queue_depth", len(client.send), "dropped", client.drops.Load())
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// newConsumerTestService creates a service with small slow consumer thresholds
+// that is not running, so checks are driven by the test
+func newConsumerTestService(t *testing.T) *WebSocketService {
+	collector, _, _, _ := setupTestCollector(t)
+	cfg := config.Default().WebSocket
+	cfg.SlowQueueDepth = 4
+	cfg.SlowLag = config.Duration(time.Second)
+	cfg.EvictLag = config.Duration(5 * time.Second)
+	cfg.EvictDrops = 3
+	return NewWebSocketService(collector, nil, cfg)
+}
+
+// addTestClient registers a client without a connection
+func addTestClient(ws *WebSocketService) *Client {
+	client := &Client{
+		send:     make(chan []byte, 8),
+		notices:  make(chan []byte, 1),
+		subs:     make(map[string]bool),
+		lastSent: make(map[string]time.Time),
+	}
+	ws.mu.Lock()
+	ws.clients[client] = true
+	ws.mu.Unlock()
+	return client
+}
+
+// TestWebSocketService_SlowConsumer tests warning a client whose queue fills
+// up and evicting it once it does not drain
+func TestWebSocketService_SlowConsumer(t *testing.T) {
+	ws := newConsumerTestService(t)
+	client := addTestClient(ws)
+	for i := 0; i < 4; i++ {
+		client.enqueue([]byte(`{}`))
+	}
+	start := time.Unix(0, client.backlogSince.Load())
+
+	ws.checkConsumers(start)
+	if !client.warned || len(client.send) != 4 {
+		t.Fatalf("Expected a slow_consumer warning at the queue depth threshold, got %d queued", len(client.send))
+	}
+	var warning ServerMessage
+	if err := json.Unmarshal(<-client.notices, &warning); err != nil || warning.Type != "slow_consumer" {
+		t.Fatalf("Expected a slow_consumer notice, got %+v %v", warning, err)
+	}
+	for i := 0; i < 4; i++ {
+		<-client.send
+	}
+
+	// Draining clears the warning
+	ws.checkConsumers(start)
+	if client.warned {
+		t.Error("Expected the warning to clear once the queue drained")
+	}
+
+	client.enqueue([]byte(`{}`))
+	ws.checkConsumers(time.Unix(0, client.backlogSince.Load()).Add(5 * time.Second))
+	ws.mu.RLock()
+	_, registered := ws.clients[client]
+	ws.mu.RUnlock()
+	if registered {
+		t.Fatal("Expected a client lagging past the eviction threshold to be evicted")
+	}
+	if !client.closed {
+		t.Error("Expected the evicted client's queue to be closed")
+	}
+	if frame := client.evicted(); len(frame) < 2 || int(frame[0])<<8|int(frame[1]) != websocket.ClosePolicyViolation {
+		t.Errorf("Expected a policy violation close frame, got %v", frame)
+	}
+}
+
+// TestWebSocketService_EvictDrops tests evicting a client whose queue overflowed
+func TestWebSocketService_EvictDrops(t *testing.T) {
+	ws := newConsumerTestService(t)
+	client := addTestClient(ws)
+	for i := 0; i < cap(client.send)+3; i++ {
+		client.enqueue([]byte(`{}`))
+	}
+
+	ws.checkConsumers(time.Unix(0, client.backlogSince.Load()))
+	ws.mu.RLock()
+	_, registered := ws.clients[client]
+	ws.mu.RUnlock()
+	if registered {
+		t.Error("Expected a client that dropped messages to be evicted")
+	}
+}
+
+// TestWebSocketService_EvictDropsRecovered tests that messages dropped before
+// the queue drained do not count towards evicting the client
+func TestWebSocketService_EvictDropsRecovered(t *testing.T) {
+	ws := newConsumerTestService(t)
+	client := addTestClient(ws)
+	overflow := func() {
+		for i := 0; i < cap(client.send)+2; i++ {
+			client.enqueue([]byte(`{}`))
+		}
+		ws.checkConsumers(time.Unix(0, client.backlogSince.Load()))
+	}
+
+	overflow()
+	for len(client.send) > 0 {
+		<-client.send
+	}
+	ws.checkConsumers(time.Now())
+	overflow()
+
+	ws.mu.RLock()
+	_, registered := ws.clients[client]
+	ws.mu.RUnlock()
+	if !registered {
+		t.Errorf("Expected a client that recovered to stay connected after %d drops in all", client.drops.Load())
+	}
+}
+
+// TestWebSocketService_EvictionClosesConnection tests that an evicted client
+// receives the close frame without the messages still queued
+func TestWebSocketService_EvictionClosesConnection(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		wsSvc.HandleConnection(conn, EncodingJSON)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	var client *Client
+	if !waitFor(t, time.Second, func() bool {
+		wsSvc.mu.RLock()
+		defer wsSvc.mu.RUnlock()
+		for c := range wsSvc.clients {
+			client = c
+		}
+		return client != nil
+	}) {
+		t.Fatal("Expected the client to be registered")
+	}
+
+	wsSvc.mu.Lock()
+	wsSvc.evict(client, "lag", "slow consumer")
+	wsSvc.mu.Unlock()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("Expected a policy violation close, got %v", err)
+	}
+}
+
+// TestWebSocketService_ConcurrentLoad exercises connecting, subscribing,
+// broadcasting, tracking changes, consumer checks and disconnecting at once;
+// run with -race to check the service's locking
+func TestWebSocketService_ConcurrentLoad(t *testing.T) {
+	wsSvc, collector := setupTestWebSocketService(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		wsSvc.HandleConnection(conn, EncodingJSON)
+	}))
+	defer server.Close()
+
+	both := config.TrackingConfig{Symbols: []string{"BTCUSDT", "ETHUSDT"}, Intervals: []string{"1m"}}
+	if _, err := collector.Apply(both); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	// Broadcast updates of both series
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int64(1); i <= 300; i++ {
+			for _, symbol := range both.Symbols {
+				kline := testKline(i * 60000)
+				kline.Symbol = symbol
+				wsSvc.OnKline(t.Context(), kline)
+			}
+		}
+	}()
+
+	// Stop and restart tracking of one series
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}})
+			collector.Apply(both)
+		}
+	}()
+
+	// Check consumers more often than Run does
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				wsSvc.checkConsumers(time.Now())
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	var clients sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		clients.Add(1)
+		go func(i int) {
+			defer clients.Done()
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+			if err != nil {
+				t.Errorf("Failed to connect to WebSocket: %v", err)
+				return
+			}
+			defer conn.Close()
+
+			var hello ServerMessage
+			if err := conn.ReadJSON(&hello); err != nil {
+				t.Errorf("Failed to read hello: %v", err)
+				return
+			}
+			conn.WriteJSON(ClientMessage{Action: "hello", Protocol: ProtocolVersion, Batch: i%2 == 0})
+
+			go func() {
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						return
+					}
+				}
+			}()
+			for j := 0; j < 10; j++ {
+				symbol := both.Symbols[j%2]
+				conn.WriteJSON(ClientMessage{Action: "subscribe", Symbol: symbol, Interval: "1m", Snapshot: j, ThrottleMs: int64(j)})
+				if j%3 == 0 {
+					conn.WriteJSON(ClientMessage{Action: "unsubscribe", Symbol: symbol, Interval: "1m"})
+				}
+				if j == 5 {
+					conn.WriteJSON(ClientMessage{Action: "resume", Session: hello.Session, Symbol: symbol, Interval: "1m", Seq: 1})
+				}
+			}
+			time.Sleep(time.Duration(i) * 5 * time.Millisecond)
+		}(i)
+	}
+
+	clients.Wait()
+	close(stop)
+	wg.Wait()
+
+	if !waitFor(t, 2*time.Second, func() bool {
+		wsSvc.mu.RLock()
+		defer wsSvc.mu.RUnlock()
+		return len(wsSvc.clients) == 0
+	}) {
+		t.Error("Expected every client to be unregistered")
+	}
+	for _, key := range []string{"BTCUSDT:1m", "ETHUSDT:1m"} {
+		if clients := subscribersOf(wsSvc, key); len(clients) > 0 {
+			t.Errorf("Expected no subscriptions left, got %d on %s", len(clients), key)
+		}
+	}
+}
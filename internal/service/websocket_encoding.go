@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"crypto-monitor/internal/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoding is the format of the messages of a websocket connection
+type Encoding int
+
+const (
+	// EncodingJSON sends JSON in text frames, with prices as decimal strings
+	EncodingJSON Encoding = iota
+	// EncodingMsgpack sends MessagePack in binary frames, with prices as
+	// floats; the messages have the fields of their JSON counterparts
+	EncodingMsgpack
+
+	encodingCount
+)
+
+var encodingNames = [encodingCount]string{"json", "msgpack"}
+
+// subprotocolPrefix prefixes the encoding names to form websocket subprotocols
+const subprotocolPrefix = "crypto-monitor."
+
+// Subprotocols lists the websocket subprotocols negotiating an encoding, in
+// the server's order of preference
+var Subprotocols = []string{subprotocolPrefix + "msgpack", subprotocolPrefix + "json"}
+
+// String returns the name of the encoding
+func (e Encoding) String() string {
+	return encodingNames[e]
+}
+
+// ParseEncoding returns the encoding called name; empty is JSON
+func ParseEncoding(name string) (Encoding, error) {
+	if name == "" {
+		return EncodingJSON, nil
+	}
+	for e, n := range encodingNames {
+		if n == name {
+			return Encoding(e), nil
+		}
+	}
+	return EncodingJSON, fmt.Errorf("unknown encoding %q, expected json or msgpack", name)
+}
+
+// SubprotocolEncoding returns the encoding of a negotiated subprotocol
+func SubprotocolEncoding(subprotocol string) (Encoding, bool) {
+	for e, n := range encodingNames {
+		if subprotocolPrefix+n == subprotocol {
+			return Encoding(e), true
+		}
+	}
+	return EncodingJSON, false
+}
+
+// frameType returns the websocket frame type carrying the encoding
+func (e Encoding) frameType() int {
+	if e == EncodingMsgpack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// KlineData is a kline as sent to clients
+// JSON carries the prices as strings with 8 decimals, MessagePack as floats.
+type KlineData struct {
+	OpenTime  int64   `json:"open_time"`
+	CloseTime int64   `json:"close_time"`
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	Volume    float64 `json:"volume"`
+}
+
+// klineData formats a kline for clients
+func klineData(kline models.Kline) KlineData {
+	return KlineData{
+		OpenTime:  kline.OpenTime,
+		CloseTime: kline.CloseTime,
+		Open:      kline.OpenPrice,
+		High:      kline.HighPrice,
+		Low:       kline.LowPrice,
+		Close:     kline.ClosePrice,
+		Volume:    kline.Volume,
+	}
+}
+
+// MarshalJSON encodes the kline with its keys sorted and its prices as
+// strings, as clients have always been sent
+func (k KlineData) MarshalJSON() ([]byte, error) {
+	return k.appendJSON(make([]byte, 0, 192)), nil
+}
+
+// appendJSON appends the JSON encoding of the kline
+func (k KlineData) appendJSON(b []byte) []byte {
+	b = appendPrice(append(b, `{"close":`...), k.Close)
+	b = strconv.AppendInt(append(b, `,"close_time":`...), k.CloseTime, 10)
+	b = appendPrice(append(b, `,"high":`...), k.High)
+	b = appendPrice(append(b, `,"low":`...), k.Low)
+	b = appendPrice(append(b, `,"open":`...), k.Open)
+	b = strconv.AppendInt(append(b, `,"open_time":`...), k.OpenTime, 10)
+	b = appendPrice(append(b, `,"volume":`...), k.Volume)
+	return append(b, '}')
+}
+
+// appendPrice appends a price as a quoted string with 8 decimals
+func appendPrice(b []byte, price float64) []byte {
+	b = append(b, '"')
+	b = strconv.AppendFloat(b, price, 'f', 8, 64)
+	return append(b, '"')
+}
+
+// UnmarshalJSON decodes a kline sent as JSON
+func (k *KlineData) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		OpenTime  int64       `json:"open_time"`
+		CloseTime int64       `json:"close_time"`
+		Open      json.Number `json:"open"`
+		High      json.Number `json:"high"`
+		Low       json.Number `json:"low"`
+		Close     json.Number `json:"close"`
+		Volume    json.Number `json:"volume"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	k.OpenTime, k.CloseTime = raw.OpenTime, raw.CloseTime
+	for _, price := range []struct {
+		dst *float64
+		src json.Number
+	}{{&k.Open, raw.Open}, {&k.High, raw.High}, {&k.Low, raw.Low}, {&k.Close, raw.Close}, {&k.Volume, raw.Volume}} {
+		v, err := price.src.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid price %q: %w", price.src, err)
+		}
+		*price.dst = v
+	}
+	return nil
+}
+
+// marshalMsgpack encodes v as MessagePack with the field names of its JSON
+// encoding
+func marshalMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.GetEncoder()
+	defer msgpack.PutEncoder(enc)
+	enc.Reset(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalMsgpack decodes MessagePack encoded by marshalMsgpack
+func unmarshalMsgpack(data []byte, v interface{}) error {
+	dec := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(dec)
+	dec.Reset(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// EncodeServerMessage encodes a message sent to clients
+func EncodeServerMessage(e Encoding, msg ServerMessage) ([]byte, error) {
+	if e == EncodingMsgpack {
+		return marshalMsgpack(msg)
+	}
+	return json.Marshal(msg)
+}
+
+// DecodeServerMessage decodes a message sent to clients; kline data is
+// decoded as generic maps and lists
+func DecodeServerMessage(e Encoding, data []byte) (ServerMessage, error) {
+	var msg ServerMessage
+	if e == EncodingMsgpack {
+		return msg, unmarshalMsgpack(data, &msg)
+	}
+	return msg, json.Unmarshal(data, &msg)
+}
+
+// EncodeClientMessage encodes a message sent by clients
+func EncodeClientMessage(e Encoding, msg ClientMessage) ([]byte, error) {
+	if e == EncodingMsgpack {
+		return marshalMsgpack(msg)
+	}
+	return json.Marshal(msg)
+}
+
+// DecodeClientMessage decodes a message sent by clients
+func DecodeClientMessage(e Encoding, data []byte) (ClientMessage, error) {
+	var msg ClientMessage
+	if e == EncodingMsgpack {
+		return msg, unmarshalMsgpack(data, &msg)
+	}
+	return msg, json.Unmarshal(data, &msg)
+}
+
+// frameEncoding returns the encoding of a received frame: clients may send
+// JSON in text frames whatever encoding they negotiated
+func frameEncoding(messageType int) Encoding {
+	if messageType == websocket.BinaryMessage {
+		return EncodingMsgpack
+	}
+	return EncodingJSON
+}
+
+// appendBatchHeader appends the start of a batch envelope of n messages
+func appendBatchHeader(b []byte, e Encoding, n int) []byte {
+	if e != EncodingMsgpack {
+		return append(b, batchPrefix...)
+	}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.EncodeMapLen(2)
+	enc.EncodeString("type")
+	enc.EncodeString("batch")
+	enc.EncodeString("messages")
+	enc.EncodeArrayLen(n)
+	return append(b, buf.Bytes()...)
+}
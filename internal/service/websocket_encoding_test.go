@@ -0,0 +1,218 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestParseEncoding tests choosing an encoding by name and by subprotocol
+func TestParseEncoding(t *testing.T) {
+	for name, want := range map[string]Encoding{"": EncodingJSON, "json": EncodingJSON, "msgpack": EncodingMsgpack} {
+		if got, err := ParseEncoding(name); err != nil || got != want {
+			t.Errorf("Expected %q to be %v, got %v %v", name, want, got, err)
+		}
+	}
+	if _, err := ParseEncoding("protobuf"); err == nil {
+		t.Error("Expected an error for an unknown encoding")
+	}
+
+	if e, ok := SubprotocolEncoding("crypto-monitor.msgpack"); !ok || e != EncodingMsgpack {
+		t.Errorf("Expected the msgpack subprotocol to select MessagePack, got %v %t", e, ok)
+	}
+	if _, ok := SubprotocolEncoding(""); ok {
+		t.Error("Expected no encoding without a subprotocol")
+	}
+}
+
+// TestKlineData_JSON tests that klines keep their JSON format and decode back
+func TestKlineData_JSON(t *testing.T) {
+	kline := testKline(60000)
+	kline.OpenPrice = 0.000012345678
+	data := klineData(kline)
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Failed to marshal kline: %v", err)
+	}
+	want := `{"close":"50000.00000000","close_time":119999,"high":"0.00000000","low":"0.00000000","open":"0.00001235","open_time":60000,"volume":"0.00000000"}`
+	if string(b) != want {
+		t.Errorf("Expected %s, got %s", want, b)
+	}
+
+	var decoded KlineData
+	if err := json.Unmarshal(b, &decoded); err != nil || decoded.Close != 50000 || decoded.Open != 0.00001235 || decoded.CloseTime != 119999 {
+		t.Errorf("Expected %s to decode back, got %+v %v", b, decoded, err)
+	}
+}
+
+// TestEncodeServerMessage tests that messages round-trip in both encodings
+// and that MessagePack carries prices as numbers
+func TestEncodeServerMessage(t *testing.T) {
+	kline := testKline(60000)
+	msg := ServerMessage{Type: "kline_update", Symbol: "BTCUSDT", Interval: "1m", Data: klineData(kline), Seq: 7}
+
+	sizes := make(map[Encoding]int)
+	for _, e := range []Encoding{EncodingJSON, EncodingMsgpack} {
+		b, err := EncodeServerMessage(e, msg)
+		if err != nil {
+			t.Fatalf("Failed to encode %v: %v", e, err)
+		}
+		sizes[e] = len(b)
+		if want := newTopic("BTCUSDT", "1m", 1).encode(e, kline, 7); string(want) != string(b) {
+			t.Errorf("Expected fan-out to encode %v like EncodeServerMessage", e)
+		}
+
+		decoded, err := DecodeServerMessage(e, b)
+		if err != nil {
+			t.Fatalf("Failed to decode %v: %v", e, err)
+		}
+		if decoded.Type != msg.Type || decoded.Symbol != msg.Symbol || decoded.Seq != msg.Seq {
+			t.Errorf("Expected %v to round-trip, got %+v", e, decoded)
+		}
+		data, _ := decoded.Data.(map[string]interface{})
+		switch e {
+		case EncodingJSON:
+			if data["close"] != "50000.00000000" {
+				t.Errorf("Expected JSON prices as strings, got %v", data["close"])
+			}
+		case EncodingMsgpack:
+			if data["close"] != kline.ClosePrice {
+				t.Errorf("Expected MessagePack prices as floats, got %#v", data["close"])
+			}
+		}
+	}
+	if sizes[EncodingMsgpack] >= sizes[EncodingJSON] {
+		t.Errorf("Expected MessagePack to be smaller than JSON, got %v", sizes)
+	}
+
+	client := ClientMessage{Action: "subscribe", Symbol: "BTCUSDT", Interval: "1m", Snapshot: 10, ThrottleMs: 250}
+	for _, e := range []Encoding{EncodingJSON, EncodingMsgpack} {
+		b, err := EncodeClientMessage(e, client)
+		if err != nil {
+			t.Fatalf("Failed to encode %v: %v", e, err)
+		}
+		if decoded, err := DecodeClientMessage(e, b); err != nil || decoded != client {
+			t.Errorf("Expected the client message to round-trip in %v, got %+v %v", e, decoded, err)
+		}
+	}
+}
+
+// TestWebSocketService_Msgpack tests a client negotiating MessagePack next to
+// a JSON client on the same series
+func TestWebSocketService_Msgpack(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{Subprotocols: Subprotocols}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		encoding, _ := SubprotocolEncoding(conn.Subprotocol())
+		wsSvc.HandleConnection(conn, encoding)
+	}))
+	defer server.Close()
+
+	dial := func(subprotocols ...string) *websocket.Conn {
+		dialer := websocket.Dialer{Subprotocols: subprotocols}
+		conn, _, err := dialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+		if err != nil {
+			t.Fatalf("Failed to connect to WebSocket: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	receive := func(conn *websocket.Conn, e Encoding) ServerMessage {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		frameType, b, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		if frameType != e.frameType() {
+			t.Fatalf("Expected %v in frames of type %d, got %d", e, e.frameType(), frameType)
+		}
+		msg, err := DecodeServerMessage(e, b)
+		if err != nil {
+			t.Fatalf("Failed to decode %v: %v", e, err)
+		}
+		return msg
+	}
+	subscribe := func(conn *websocket.Conn, e Encoding) {
+		t.Helper()
+		b, _ := EncodeClientMessage(e, ClientMessage{Action: "subscribe", Symbol: "BTCUSDT", Interval: "1m", ThrottleMs: 100})
+		conn.WriteMessage(e.frameType(), b)
+		if msg := receive(conn, e); msg.Type != "subscribed" {
+			t.Fatalf("Expected subscribed, got %+v", msg)
+		}
+	}
+
+	binary := dial("crypto-monitor.msgpack")
+	if binary.Subprotocol() != "crypto-monitor.msgpack" {
+		t.Fatalf("Expected the msgpack subprotocol to be negotiated, got %q", binary.Subprotocol())
+	}
+	if msg := receive(binary, EncodingMsgpack); msg.Type != "hello" || msg.Protocol != ProtocolVersion {
+		t.Fatalf("Expected hello in MessagePack, got %+v", msg)
+	}
+	text := dial()
+	receive(text, EncodingJSON)
+
+	subscribe(binary, EncodingMsgpack)
+	subscribe(text, EncodingJSON)
+
+	wsSvc.OnKline(t.Context(), testKline(60000))
+	data, _ := receive(binary, EncodingMsgpack).Data.(map[string]interface{})
+	if data["close"] != 50000.0 {
+		t.Errorf("Expected the update with float prices, got %+v", data)
+	}
+	data, _ = receive(text, EncodingJSON).Data.(map[string]interface{})
+	if data["close"] != "50000.00000000" {
+		t.Errorf("Expected the update with string prices, got %+v", data)
+	}
+}
+
+// TestClient_WriteMsgpackBatch tests batch envelopes of MessagePack messages
+func TestClient_WriteMsgpackBatch(t *testing.T) {
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+	serverConn := <-conns
+	defer serverConn.Close()
+
+	client := &Client{conn: serverConn, encoding: EncodingMsgpack, send: make(chan []byte, 8)}
+	client.batch.Store(true)
+	topic := newTopic("BTCUSDT", "1m", 1)
+	client.send <- topic.encode(EncodingMsgpack, testKline(2*60000), 2)
+	if err := client.write(topic.encode(EncodingMsgpack, testKline(60000), 1)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	frameType, b, err := conn.ReadMessage()
+	if err != nil || frameType != websocket.BinaryMessage {
+		t.Fatalf("Expected a binary frame, got %d %v", frameType, err)
+	}
+	var batch struct {
+		Type     string          `json:"type"`
+		Messages []ServerMessage `json:"messages"`
+	}
+	if err := unmarshalMsgpack(b, &batch); err != nil {
+		t.Fatalf("Failed to decode batch: %v", err)
+	}
+	if batch.Type != "batch" || len(batch.Messages) != 2 || batch.Messages[0].Seq != 1 || batch.Messages[1].Seq != 2 {
+		t.Errorf("Expected a batch of updates 1 and 2, got %+v", batch)
+	}
+}
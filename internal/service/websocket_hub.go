@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"crypto-monitor/internal/metrics"
+	"crypto-monitor/internal/models"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// hubUpdate is a kline waiting for a shard worker to fan it out, with the
+// span it was collected in
+type hubUpdate struct {
+	kline models.Kline
+	span  trace.SpanContext
+}
+
+// hubShard is a worker fanning out the updates of its share of the topics,
+// so a busy series holds up only the series sharing its worker
+type hubShard struct {
+	queue  chan hubUpdate
+	mu     sync.RWMutex
+	topics map[string]*topic
+}
+
+// topic is a series clients subscribe to
+type topic struct {
+	key              string
+	symbol, interval string
+	// prefix is the JSON encoding of the topic's kline_update messages up to
+	// the kline data
+	prefix []byte
+	// mu orders the updates of the topic with clients subscribing and
+	// resuming, so what they are sent then seams exactly with the updates
+	// that follow
+	mu  sync.Mutex
+	log seriesLog
+	// epoch tells the topic apart from the earlier and later topics of its
+	// series, whose updates are numbered from 1 again
+	epoch uint64
+	// subscribers is replaced, never modified, under mu, so fan-out reads it
+	// without locking
+	subscribers atomic.Pointer[[]*Client]
+	// removed is set under mu once the series is no longer tracked
+	removed bool
+}
+
+// newTopic creates the topic of a series keeping size updates
+func newTopic(symbol, interval string, size int) *topic {
+	header, _ := json.Marshal(ServerMessage{Type: "kline_update", Symbol: symbol, Interval: interval})
+	// Reopen {"type":"kline_update","symbol":...,"interval":...} for the data
+	prefix := append(header[:len(header)-1:len(header)-1], `,"data":`...)
+	return &topic{
+		key:      symbol + ":" + interval,
+		symbol:   symbol,
+		interval: interval,
+		prefix:   prefix,
+		log:      seriesLog{size: size},
+		epoch:    newTopicEpoch(),
+	}
+}
+
+// newTopicEpoch returns the epoch of a new topic; epochs are random, so the
+// seqs of a topic since dropped, or of another process, are not mistaken
+// for those of a new one
+func newTopicEpoch() uint64 {
+	return max(rand.Uint64(), 1)
+}
+
+// clients returns the subscribers of the topic; the slice must not be modified
+func (t *topic) clients() []*Client {
+	if clients := t.subscribers.Load(); clients != nil {
+		return *clients
+	}
+	return nil
+}
+
+// add subscribes client to the topic; the caller holds mu
+func (t *topic) add(client *Client) {
+	clients := t.clients()
+	if slices.Contains(clients, client) {
+		return
+	}
+	next := make([]*Client, len(clients), len(clients)+1)
+	copy(next, clients)
+	next = append(next, client)
+	t.subscribers.Store(&next)
+}
+
+// remove unsubscribes client from the topic; the caller holds mu
+func (t *topic) remove(client *Client) {
+	clients := t.clients()
+	i := slices.Index(clients, client)
+	if i < 0 {
+		return
+	}
+	next := make([]*Client, 0, len(clients)-1)
+	next = append(next, clients[:i]...)
+	next = append(next, clients[i+1:]...)
+	t.subscribers.Store(&next)
+}
+
+// encode returns the kline_update message of a kline in an encoding
+// JSON is encoded as json.Marshal encodes the ServerMessage but without the
+// reflection, since every update is encoded once for all subscribers.
+func (t *topic) encode(e Encoding, kline models.Kline, seq uint64) []byte {
+	if e == EncodingMsgpack {
+		// A message of plain values always encodes
+		msg, _ := marshalMsgpack(ServerMessage{
+			Type:     "kline_update",
+			Symbol:   t.symbol,
+			Interval: t.interval,
+			Data:     klineData(kline),
+			Seq:      seq,
+		})
+		return msg
+	}
+
+	b := make([]byte, 0, len(t.prefix)+256)
+	b = append(b, t.prefix...)
+	b = klineData(kline).appendJSON(b)
+	b = strconv.AppendUint(append(b, `,"seq":`...), seq, 10)
+	return append(b, '}')
+}
+
+// newShards creates n shard workers, each queueing up to queueSize updates
+func newShards(n, queueSize int) []*hubShard {
+	shards := make([]*hubShard, n)
+	for i := range shards {
+		shards[i] = &hubShard{
+			queue:  make(chan hubUpdate, queueSize),
+			topics: make(map[string]*topic),
+		}
+	}
+	return shards
+}
+
+// shard returns the worker of a topic
+func (ws *WebSocketService) shard(key string) *hubShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return ws.shards[h.Sum32()%uint32(len(ws.shards))]
+}
+
+// findTopic returns the topic of key, nil if nobody subscribed to it and no
+// update of it was broadcast
+func (ws *WebSocketService) findTopic(key string) *topic {
+	s := ws.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.topics[key]
+}
+
+// lockTopic returns the topic of a series, creating it, with its mu held
+func (ws *WebSocketService) lockTopic(symbol, interval string) *topic {
+	key := symbol + ":" + interval
+	s := ws.shard(key)
+	for {
+		s.mu.RLock()
+		t, ok := s.topics[key]
+		s.mu.RUnlock()
+		if !ok {
+			s.mu.Lock()
+			if t, ok = s.topics[key]; !ok {
+				t = newTopic(symbol, interval, ws.logSize)
+				s.topics[key] = t
+			}
+			s.mu.Unlock()
+		}
+
+		t.mu.Lock()
+		if !t.removed {
+			return t
+		}
+		// Removed meanwhile; the next lookup finds or creates its successor
+		t.mu.Unlock()
+	}
+}
+
+// dropTopic forgets the topic of key and returns the clients that were
+// subscribed to it
+func (ws *WebSocketService) dropTopic(key string) []*Client {
+	s := ws.shard(key)
+	s.mu.Lock()
+	t, ok := s.topics[key]
+	delete(s.topics, key)
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removed = true
+	clients := t.clients()
+	t.subscribers.Store(nil)
+	return clients
+}
+
+// publish queues a kline for its shard worker without waiting for clients;
+// if the worker is that far behind the kline is dropped
+func (ws *WebSocketService) publish(ctx context.Context, kline models.Kline) {
+	s := ws.shard(kline.Symbol + ":" + kline.Interval)
+	select {
+	case s.queue <- hubUpdate{kline: kline, span: trace.SpanContextFromContext(ctx)}:
+	default:
+		metrics.WebSocketHubDrops.Inc()
+		if ws.hubDrops.Add(1)%1000 == 1 {
+			wsLog.WarnContext(ctx, "WebSocket fan-out queue full, dropping kline updates", "capacity", cap(s.queue), "dropped", ws.hubDrops.Load())
+		}
+	}
+}
+
+// run fans out the queued updates until ctx is done
+func (s *hubShard) run(ctx context.Context, ws *WebSocketService) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-s.queue:
+			ws.broadcastKlineUpdate(trace.ContextWithSpanContext(ctx, update.span), update.kline)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/models"
+)
+
+// trackAll is a catalog tracking every series
+type trackAll struct{}
+
+func (trackAll) IsTracked(symbol, interval string) bool { return true }
+func (trackAll) AddListener(listener SeriesListener)    {}
+
+// TestTopic_Encode tests that updates are encoded exactly as json.Marshal
+// encodes the ServerMessage
+func TestTopic_Encode(t *testing.T) {
+	kline := testKline(60000)
+	kline.OpenPrice = 0.000012345678
+	kline.HighPrice = 123456789.5
+	kline.Volume = 0
+
+	got := newTopic("BTCUSDT", "1m", 1).encode(EncodingJSON, kline, 42)
+	want, err := json.Marshal(ServerMessage{
+		Type:     "kline_update",
+		Symbol:   "BTCUSDT",
+		Interval: "1m",
+		Data:     klineData(kline),
+		Seq:      42,
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+// TestTopic_Subscribers tests that subscriber lists are copied on write, so
+// a fan-out in progress keeps the list it started with
+func TestTopic_Subscribers(t *testing.T) {
+	topic := newTopic("BTCUSDT", "1m", 1)
+	first, second := &Client{}, &Client{}
+
+	topic.add(first)
+	topic.add(first)
+	before := topic.clients()
+	topic.add(second)
+	if len(before) != 1 || len(topic.clients()) != 2 {
+		t.Fatalf("Expected the earlier list to keep 1 client and the topic to have 2, got %d and %d", len(before), len(topic.clients()))
+	}
+
+	during := topic.clients()
+	topic.remove(first)
+	if !slices.Equal(during, []*Client{first, second}) || !slices.Equal(topic.clients(), []*Client{second}) {
+		t.Errorf("Expected removing to leave the earlier list intact, got %v and %v", during, topic.clients())
+	}
+}
+
+// TestWebSocketService_OnKlineNeverBlocks tests that the collector hands
+// updates over without waiting, dropping them when the worker is behind
+func TestWebSocketService_OnKlineNeverBlocks(t *testing.T) {
+	cfg := config.Default().WebSocket
+	cfg.HubShards = 1
+	cfg.HubQueueSize = 2
+	// Not running, so the worker never takes an update
+	wsSvc := NewWebSocketService(trackAll{}, nil, cfg)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := int64(1); i <= 5; i++ {
+			wsSvc.OnKline(t.Context(), testKline(i*60000))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected OnKline not to block on a full queue")
+	}
+	if n := len(wsSvc.shards[0].queue); n != 2 {
+		t.Errorf("Expected 2 queued updates, got %d", n)
+	}
+}
+
+// TestWebSocketService_Shards tests that each shard worker delivers the
+// updates of its topics in order
+func TestWebSocketService_Shards(t *testing.T) {
+	cfg := config.Default().WebSocket
+	cfg.HubShards = 4
+	wsSvc := NewWebSocketService(trackAll{}, nil, cfg)
+	go wsSvc.Run(t.Context())
+
+	clients := make(map[string]*Client)
+	for i := 0; i < 8; i++ {
+		symbol := fmt.Sprintf("SYM%dUSDT", i)
+		client, _ := newSessionClient(t, wsSvc)
+		wsSvc.handleSubscribe(client, symbol, "1m", 0, 0)
+		receiveMessage(t, client)
+		client.subscribe(symbol+":1m", 0, 0, false)
+		clients[symbol] = client
+	}
+
+	for i := int64(1); i <= 20; i++ {
+		for symbol := range clients {
+			kline := testKline(i * 60000)
+			kline.Symbol = symbol
+			wsSvc.OnKline(t.Context(), kline)
+		}
+	}
+	for symbol, client := range clients {
+		for want := uint64(1); want <= 20; want++ {
+			if msg := receiveMessage(t, client); msg.Symbol != symbol || msg.Seq != want {
+				t.Fatalf("Expected update %d of %s, got %+v", want, symbol, msg)
+			}
+		}
+	}
+}
+
+// hubBench is a running service with clients subscribed to its topics
+type hubBench struct {
+	ws      *WebSocketService
+	symbols []string
+	clients []*Client
+	// received counts the updates sent to clients
+	received      atomic.Int64
+	subscriptions int
+}
+
+// delivered returns the updates sent to or dropped for clients
+func (h *hubBench) delivered() int64 {
+	n := h.received.Load()
+	for _, client := range h.clients {
+		n += int64(client.drops.Load())
+	}
+	return n
+}
+
+// benchmarkHub runs a service with clients subscribed to subsPerClient of
+// topics each
+func benchmarkHub(b *testing.B, clients, topics, subsPerClient int) *hubBench {
+	h := &hubBench{
+		ws:            NewWebSocketService(trackAll{}, nil, config.Default().WebSocket),
+		symbols:       make([]string, topics),
+		clients:       make([]*Client, clients),
+		subscriptions: clients * subsPerClient,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go h.ws.Run(ctx)
+
+	for i := range h.symbols {
+		h.symbols[i] = fmt.Sprintf("SYM%03dUSDT", i)
+	}
+	for i := range h.clients {
+		client := &Client{
+			id:       uint64(i),
+			send:     make(chan []byte, sendQueueSize),
+			subs:     make(map[string]bool),
+			lastSent: make(map[string]time.Time),
+		}
+		for j := 0; j < subsPerClient; j++ {
+			t := h.ws.lockTopic(h.symbols[(i+j)%topics], "1m")
+			client.subscribe(t.key, t.epoch, 0, false)
+			t.add(client)
+			t.mu.Unlock()
+		}
+		go func() {
+			for range client.send {
+				h.received.Add(1)
+			}
+		}()
+		h.clients[i] = client
+	}
+	b.Cleanup(func() {
+		cancel()
+		for _, client := range h.clients {
+			client.close()
+		}
+	})
+	return h
+}
+
+// BenchmarkWebSocketService_Fanout measures delivering one update of each of
+// 100 topics to 10000 clients, each subscribed to 1 or 10 of them
+func BenchmarkWebSocketService_Fanout(b *testing.B) {
+	for _, subs := range []int{1, 10} {
+		b.Run(fmt.Sprintf("clients=10000/topics=100/subs=%d", subs), func(b *testing.B) {
+			h := benchmarkHub(b, 10000, 100, subs)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, symbol := range h.symbols {
+					kline := testKline(int64(i+1) * 60000)
+					kline.Symbol = symbol
+					h.ws.OnKline(b.Context(), kline)
+				}
+				// Wait for every subscription to get its update
+				for want := int64((i + 1) * h.subscriptions); h.delivered() < want; {
+					time.Sleep(50 * time.Microsecond)
+				}
+			}
+			b.ReportMetric(float64(b.N*h.subscriptions)/b.Elapsed().Seconds(), "deliveries/s")
+		})
+	}
+}
+
+// BenchmarkWebSocketService_OnKline measures the collector handing an update
+// over while 10000 clients are subscribed to 100 topics
+func BenchmarkWebSocketService_OnKline(b *testing.B) {
+	h := benchmarkHub(b, 10000, 100, 10)
+	klines := make([]models.Kline, len(h.symbols))
+	for i, symbol := range h.symbols {
+		klines[i] = testKline(60000)
+		klines[i].Symbol = symbol
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ws.OnKline(b.Context(), klines[i%len(klines)])
+	}
+}
+
+// BenchmarkTopic_Encode measures encoding an update once for all subscribers
+func BenchmarkTopic_Encode(b *testing.B) {
+	topic := newTopic("BTCUSDT", "1m", 1)
+	kline := testKline(60000)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		topic.encode(EncodingJSON, kline, uint64(i))
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"crypto-monitor/internal/models"
+)
+
+// seriesEntry is a numbered update of a series
+type seriesEntry struct {
+	seq   uint64
+	kline models.Kline
+}
+
+// seriesLog numbers the updates of a series and keeps the last ones
+type seriesLog struct {
+	// seq is the number of the last update, 0 before the first
+	seq     uint64
+	size    int
+	entries []seriesEntry // oldest first
+}
+
+// append numbers kline as the next update and returns its sequence number
+func (l *seriesLog) append(kline models.Kline) uint64 {
+	l.seq++
+	l.entries = append(l.entries, seriesEntry{seq: l.seq, kline: kline})
+	if len(l.entries) > l.size {
+		l.entries = l.entries[len(l.entries)-l.size:]
+	}
+	return l.seq
+}
+
+// klines returns the kept updates, oldest first
+func (l *seriesLog) klines() []models.Kline {
+	klines := make([]models.Kline, len(l.entries))
+	for i, entry := range l.entries {
+		klines[i] = entry.kline
+	}
+	return klines
+}
+
+// since returns the updates after seq; ok is false when some of them are no
+// longer kept, or seq is ahead of the log, as after a restart of the server
+func (l *seriesLog) since(seq uint64) (entries []seriesEntry, ok bool) {
+	if seq > l.seq {
+		return nil, false
+	}
+	missed := int(l.seq - seq)
+	if missed > len(l.entries) {
+		return nil, false
+	}
+	return l.entries[len(l.entries)-missed:], true
+}
+
+// session holds the subscriptions a client can resume on a new connection
+type session struct {
+	// client is the connection holding the session, nil once it closed
+	client *Client
+	// subs are the subscriptions of the closed connection, with the epochs
+	// of their topics
+	subs map[string]uint64
+	// expires is when a closed session is forgotten
+	expires time.Time
+}
+
+// openSession starts a session for a new client and returns its token
+func (ws *WebSocketService) openSession(client *Client) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+	ws.sessions[token] = &session{client: client}
+	client.session = token
+	return token, nil
+}
+
+// closeSession keeps the subscriptions of a disconnecting client for the
+// session TTL, unless another connection took the session over
+func (ws *WebSocketService) closeSession(client *Client) {
+	ws.sessionsMu.Lock()
+	defer ws.sessionsMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(ws.lastSweep) >= time.Minute {
+		for token, s := range ws.sessions {
+			if s.client == nil && now.After(s.expires) {
+				delete(ws.sessions, token)
+			}
+		}
+		ws.lastSweep = now
+	}
+
+	s, ok := ws.sessions[client.session]
+	if !ok || s.client != client {
+		return
+	}
+	s.subs = client.subscriptions()
+	s.client = nil
+	s.expires = now.Add(ws.sessionTTL)
+}
+
+// subscriptions returns the subscriptions of client with the epochs of their
+// topics
+func (c *Client) subscriptions() map[string]uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	subs := make(map[string]uint64, len(c.subs))
+	for key := range c.subs {
+		subs[key] = c.epochs[key]
+	}
+	return subs
+}
+
+// takeOverSession moves the session of token to client and reports whether
+// it held a subscription to key, and the epoch of its topic
+// A connection still holding the session, whose loss the server has not
+// noticed yet, is closed.
+func (ws *WebSocketService) takeOverSession(client *Client, token, key string) (epoch uint64, subscribed bool, err error) {
+	ws.sessionsMu.Lock()
+	s, ok := ws.sessions[token]
+	if !ok || (s.client == nil && time.Now().After(s.expires)) {
+		ws.sessionsMu.Unlock()
+		return 0, false, fmt.Errorf("unknown or expired session")
+	}
+
+	old := s.client
+	if old != nil && old != client {
+		s.subs = old.subscriptions()
+	}
+	s.client = client
+	if client.session != token {
+		delete(ws.sessions, client.session)
+		client.session = token
+	}
+	epoch, subscribed = s.subs[key]
+	ws.sessionsMu.Unlock()
+
+	if old != nil && old != client {
+		old.logger().Info("WebSocket session taken over by a new connection")
+		if old.conn != nil {
+			old.conn.Close()
+		}
+	}
+
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	if client.subs[key] {
+		return client.epochs[key], true, nil
+	}
+	return epoch, subscribed, nil
+}
+
+// handleResume restores a subscription of a session on a new connection and
+// replays the updates sent after seq, or asks the client for a new snapshot
+// when they are no longer kept
+// Like subscribe, it negotiates the throttle interval of the subscription.
+func (ws *WebSocketService) handleResume(client *Client, token, symbol, interval string, seq uint64, throttleMs int64) {
+	if token == "" || symbol == "" || interval == "" {
+		sendError(client, "Session, symbol and interval are required")
+		return
+	}
+
+	symbol = strings.ToUpper(symbol)
+	key := fmt.Sprintf("%s:%s", symbol, interval)
+
+	epoch, subscribed, err := ws.takeOverSession(client, token, key)
+	if err != nil {
+		sendError(client, fmt.Sprintf("Cannot resume: %v", err))
+		return
+	}
+	if !subscribed {
+		sendError(client, fmt.Sprintf("Cannot resume: the session is not subscribed to %s %s", symbol, interval))
+		return
+	}
+	ws.resume(client, symbol, interval, epoch, seq, throttleMs, true)
+}
+
+// resume subscribes client to a series again and replays the updates sent
+// after seq in the topic of epoch
+// It reports false when the updates are no longer kept, or the topic of the
+// series was dropped and created again since. With resnapshot the client is
+// then subscribed anyway and asked for a new snapshot; otherwise it is left
+// unsubscribed for the caller to subscribe.
+func (ws *WebSocketService) resume(client *Client, symbol, interval string, epoch, seq uint64, throttleMs int64, resnapshot bool) bool {
+	key := symbol + ":" + interval
+
+	// Replay under the topic's lock, so the first live update follows the
+	// last replayed one
+	t := ws.lockTopic(symbol, interval)
+	defer t.mu.Unlock()
+	if !ws.catalog.IsTracked(symbol, interval) {
+		sendMessage(client, ServerMessage{
+			Type:     "series_removed",
+			Symbol:   symbol,
+			Interval: interval,
+			Message:  "series is no longer tracked",
+		})
+		return true
+	}
+
+	log := &t.log
+	// The seqs of another topic of the series number other updates
+	restarted := epoch != t.epoch
+	missed, ok := log.since(seq)
+	// The replay must fit in the send queue next to the confirmation
+	if restarted || (ok && len(missed) >= cap(client.send)-len(client.send)) {
+		ok = false
+	}
+	if !ok && !resnapshot {
+		client.logger().Info("Client cannot resume", "symbol", symbol, "interval", interval, "seq", seq, "current_seq", log.seq, "restarted", restarted)
+		return false
+	}
+
+	// Held back updates are replayed, or covered by the new snapshot
+	throttle := ws.negotiateThrottle(throttleMs)
+	if !client.subscribe(key, t.epoch, throttle, true) {
+		return true
+	}
+	t.add(client)
+
+	if !ok {
+		message := "missed updates are no longer kept, subscribe with a snapshot"
+		if restarted {
+			message = "the series restarted since, subscribe with a snapshot"
+		}
+		sendMessage(client, ServerMessage{
+			Type:       "resnapshot",
+			Symbol:     symbol,
+			Interval:   interval,
+			Seq:        log.seq,
+			ThrottleMs: throttle.Milliseconds(),
+			Message:    message,
+		})
+		client.logger().Info("Client resumed without replay", "symbol", symbol, "interval", interval, "seq", seq, "current_seq", log.seq, "restarted", restarted)
+		return false
+	}
+
+	sendMessage(client, ServerMessage{
+		Type:       "resumed",
+		Symbol:     symbol,
+		Interval:   interval,
+		Seq:        seq,
+		ThrottleMs: throttle.Milliseconds(),
+	})
+	for _, entry := range missed {
+		client.enqueue(t.encode(client.encoding, entry.kline, entry.seq))
+	}
+	client.logger().Info("Client resumed", "symbol", symbol, "interval", interval, "seq", seq, "replayed", len(missed))
+	return true
+}
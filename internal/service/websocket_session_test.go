@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"crypto-monitor/internal/config"
+)
+
+// newSessionClient creates a test client holding a new session
+func newSessionClient(t *testing.T, ws *WebSocketService) (*Client, string) {
+	t.Helper()
+	client := &Client{
+		send:     make(chan []byte, 256),
+		subs:     make(map[string]bool),
+		lastSent: make(map[string]time.Time),
+	}
+	token, err := ws.openSession(client)
+	if err != nil {
+		t.Fatalf("Failed to open session: %v", err)
+	}
+	return client, token
+}
+
+// receiveMessage decodes the next message queued for client
+func receiveMessage(t *testing.T, client *Client) ServerMessage {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		var msg ServerMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Failed to unmarshal message: %v", err)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("Expected a message")
+		return ServerMessage{}
+	}
+}
+
+// TestSeriesLog_Since tests numbering updates and finding the missed ones
+func TestSeriesLog_Since(t *testing.T) {
+	l := &seriesLog{size: 3}
+	for i := int64(1); i <= 5; i++ {
+		if seq := l.append(testKline(i * 60000)); seq != uint64(i) {
+			t.Fatalf("Expected update %d to be numbered %d, got %d", i, i, seq)
+		}
+	}
+
+	if missed, ok := l.since(3); !ok || len(missed) != 2 || missed[0].seq != 4 {
+		t.Errorf("Expected updates 4 and 5 after 3, got %v %t", missed, ok)
+	}
+	if missed, ok := l.since(5); !ok || len(missed) != 0 {
+		t.Errorf("Expected nothing missed when up to date, got %v %t", missed, ok)
+	}
+	if _, ok := l.since(1); ok {
+		t.Error("Expected updates no longer kept to require a snapshot")
+	}
+	if _, ok := l.since(9); ok {
+		t.Error("Expected a sequence ahead of the log to require a snapshot")
+	}
+}
+
+// TestWebSocketService_Resume tests replaying the updates missed while disconnected
+func TestWebSocketService_Resume(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+
+	first, token := newSessionClient(t, wsSvc)
+	wsSvc.handleSubscribe(first, "BTCUSDT", "1m", 0, 0)
+	receiveMessage(t, first)
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(60000))
+	if msg := receiveMessage(t, first); msg.Type != "kline_update" || msg.Seq != 1 {
+		t.Fatalf("Expected update 1, got %+v", msg)
+	}
+
+	// The connection drops and two updates are missed
+	wsSvc.closeSession(first)
+	wsSvc.removeClientFromAllSubscriptions(first)
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(2*60000))
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(3*60000))
+
+	second, _ := newSessionClient(t, wsSvc)
+	wsSvc.handleResume(second, token, "btcusdt", "1m", 1, 0)
+	if msg := receiveMessage(t, second); msg.Type != "resumed" || msg.Seq != 1 {
+		t.Fatalf("Expected resumed from 1, got %+v", msg)
+	}
+	for _, want := range []uint64{2, 3} {
+		if msg := receiveMessage(t, second); msg.Type != "kline_update" || msg.Seq != want {
+			t.Errorf("Expected replayed update %d, got %+v", want, msg)
+		}
+	}
+
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(4*60000))
+	if msg := receiveMessage(t, second); msg.Type != "kline_update" || msg.Seq != 4 {
+		t.Errorf("Expected live updates to continue at 4, got %+v", msg)
+	}
+
+	wsSvc.handleResume(second, token, "ETHUSDT", "1m", 0, 0)
+	if msg := receiveMessage(t, second); msg.Type != "error" {
+		t.Errorf("Expected an error for a series the session was not subscribed to, got %+v", msg)
+	}
+	wsSvc.handleResume(second, "unknown", "BTCUSDT", "1m", 0, 0)
+	if msg := receiveMessage(t, second); msg.Type != "error" {
+		t.Errorf("Expected an error for an unknown session, got %+v", msg)
+	}
+}
+
+// TestWebSocketService_ResumeResnapshot tests that clients missing more than
+// the replay buffer are asked for a new snapshot
+func TestWebSocketService_ResumeResnapshot(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+
+	first, token := newSessionClient(t, wsSvc)
+	wsSvc.handleSubscribe(first, "BTCUSDT", "1m", 0, 0)
+	wsSvc.closeSession(first)
+	wsSvc.removeClientFromAllSubscriptions(first)
+	for i := 1; i <= wsSvc.logSize+1; i++ {
+		wsSvc.broadcastKlineUpdate(t.Context(), testKline(int64(i)*60000))
+	}
+
+	second, _ := newSessionClient(t, wsSvc)
+	wsSvc.handleResume(second, token, "BTCUSDT", "1m", 0, 0)
+	msg := receiveMessage(t, second)
+	if msg.Type != "resnapshot" || msg.Seq != uint64(wsSvc.logSize+1) {
+		t.Fatalf("Expected resnapshot at the current sequence, got %+v", msg)
+	}
+	if !second.subs["BTCUSDT:1m"] {
+		t.Error("Expected the subscription to be restored for live updates")
+	}
+}
+
+// TestWebSocketService_ResumeRestartedSeries tests that a session subscribed to
+// a series that was removed and tracked again is asked for a new snapshot, as
+// the seqs of the new topic number other updates
+func TestWebSocketService_ResumeRestartedSeries(t *testing.T) {
+	wsSvc, collector := setupTestWebSocketService(t)
+
+	first, token := newSessionClient(t, wsSvc)
+	wsSvc.handleSubscribe(first, "BTCUSDT", "1m", 0, 0)
+	receiveMessage(t, first)
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(60000))
+	receiveMessage(t, first)
+	wsSvc.closeSession(first)
+	wsSvc.removeClientFromAllSubscriptions(first)
+
+	for _, symbols := range [][]string{{"ETHUSDT"}, {"BTCUSDT"}} {
+		if _, err := collector.Apply(config.TrackingConfig{Symbols: symbols, Intervals: []string{"1m"}}); err != nil {
+			t.Fatalf("Failed to apply tracking: %v", err)
+		}
+	}
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(2*60000))
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(3*60000))
+
+	second, _ := newSessionClient(t, wsSvc)
+	wsSvc.handleResume(second, token, "BTCUSDT", "1m", 1, 0)
+	if msg := receiveMessage(t, second); msg.Type != "resnapshot" || msg.Seq != 2 {
+		t.Fatalf("Expected resnapshot at the new topic's sequence, got %+v", msg)
+	}
+}
+
+// TestWebSocketService_ResumeTakeOver tests resuming a session whose old
+// connection has not been noticed to be gone
+func TestWebSocketService_ResumeTakeOver(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+
+	first, token := newSessionClient(t, wsSvc)
+	wsSvc.handleSubscribe(first, "BTCUSDT", "1m", 0, 0)
+
+	second, _ := newSessionClient(t, wsSvc)
+	wsSvc.handleResume(second, token, "BTCUSDT", "1m", 0, 0)
+	if msg := receiveMessage(t, second); msg.Type != "resumed" {
+		t.Fatalf("Expected the live session to be taken over, got %+v", msg)
+	}
+
+	// The old connection closing later keeps the session with the new one
+	wsSvc.closeSession(first)
+	wsSvc.sessionsMu.Lock()
+	holder := wsSvc.sessions[token].client
+	wsSvc.sessionsMu.Unlock()
+	if holder != second {
+		t.Error("Expected the session to stay with the new connection")
+	}
+}
@@ -1,50 +1,48 @@
 package service
 
 import (
+	"context"
+	"crypto-monitor/internal/config"
 	"crypto-monitor/internal/models"
-	"crypto-monitor/internal/repository"
-	"crypto-monitor/pkg/database"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
 )
 
-// setupTestWebSocketService creates a test WebSocket service
-func setupTestWebSocketService(t *testing.T) (*WebSocketService, *BinanceService, *repository.KlineRepository) {
-	// Setup database
-	os.Setenv("DB_HOST", "127.0.0.1")
-	os.Setenv("DB_PORT", "5432")
-	os.Setenv("DB_USER", "postgres")
-	os.Setenv("DB_PASSWORD", "postgres")
-	os.Setenv("DB_NAME", "crypto_monitor")
-
-	db, err := database.InitDB()
-	if err != nil {
-		t.Skipf("Skipping test: database connection failed: %v", err)
-		return nil, nil, nil
+// setupTestWebSocketService creates a test WebSocket service attached to a
+// collector that tracks BTCUSDT 1m from a fake source
+func setupTestWebSocketService(t *testing.T) (*WebSocketService, *Collector) {
+	collector, _, _, _ := setupTestCollector(t)
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
 	}
 
-	binanceSvc := NewBinanceService()
-	klineRepo := repository.NewKlineRepository(db)
-	wsSvc := NewWebSocketService(binanceSvc, klineRepo)
+	wsSvc := NewWebSocketService(collector, nil, config.Default().WebSocket)
 
 	// Start WebSocket service
-	go wsSvc.Run()
+	go wsSvc.Run(t.Context())
 
-	return wsSvc, binanceSvc, klineRepo
+	return wsSvc, collector
+}
+
+// subscribersOf returns the clients subscribed to a topic
+func subscribersOf(ws *WebSocketService, key string) []*Client {
+	if t := ws.findTopic(key); t != nil {
+		return t.clients()
+	}
+	return nil
 }
 
 // TestWebSocketService_HandleConnection tests WebSocket connection handling
 func TestWebSocketService_HandleConnection(t *testing.T) {
-	wsSvc, _, _ := setupTestWebSocketService(t)
-	if wsSvc == nil {
-		return
-	}
+	wsSvc, _ := setupTestWebSocketService(t)
 
 	// Create a test HTTP server with WebSocket upgrade
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -57,7 +55,7 @@ func TestWebSocketService_HandleConnection(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to upgrade connection: %v", err)
 		}
-		wsSvc.HandleConnection(conn)
+		wsSvc.HandleConnection(conn, EncodingJSON)
 	}))
 	defer server.Close()
 
@@ -84,10 +82,7 @@ func TestWebSocketService_HandleConnection(t *testing.T) {
 
 // TestWebSocketService_Subscribe tests subscription functionality
 func TestWebSocketService_Subscribe(t *testing.T) {
-	wsSvc, _, _ := setupTestWebSocketService(t)
-	if wsSvc == nil {
-		return
-	}
+	wsSvc, _ := setupTestWebSocketService(t)
 
 	// Create a test client
 	client := &Client{
@@ -98,7 +93,7 @@ func TestWebSocketService_Subscribe(t *testing.T) {
 	}
 
 	// Test subscribe
-	wsSvc.handleSubscribe(client, "BTCUSDT", "1m")
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", 0, 0)
 
 	// Verify subscription
 	client.mu.RLock()
@@ -109,26 +104,15 @@ func TestWebSocketService_Subscribe(t *testing.T) {
 		t.Error("Expected client to be subscribed to BTCUSDT:1m")
 	}
 
-	// Verify subscription map
-	wsSvc.subsMu.RLock()
-	clients, exists := wsSvc.subscriptions["BTCUSDT:1m"]
-	wsSvc.subsMu.RUnlock()
-
-	if !exists {
-		t.Error("Expected subscription to exist in subscription map")
-	}
-
-	if clients == nil || !clients[client] {
-		t.Error("Expected client to be in subscription map")
+	// Verify the topic's subscribers
+	if !slices.Contains(subscribersOf(wsSvc, "BTCUSDT:1m"), client) {
+		t.Error("Expected client to be subscribed to the topic")
 	}
 }
 
 // TestWebSocketService_Unsubscribe tests unsubscription functionality
 func TestWebSocketService_Unsubscribe(t *testing.T) {
-	wsSvc, _, _ := setupTestWebSocketService(t)
-	if wsSvc == nil {
-		return
-	}
+	wsSvc, _ := setupTestWebSocketService(t)
 
 	// Create a test client
 	client := &Client{
@@ -139,7 +123,7 @@ func TestWebSocketService_Unsubscribe(t *testing.T) {
 	}
 
 	// Subscribe first
-	wsSvc.handleSubscribe(client, "BTCUSDT", "1m")
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", 0, 0)
 
 	// Then unsubscribe
 	wsSvc.handleUnsubscribe(client, "BTCUSDT", "1m")
@@ -156,10 +140,7 @@ func TestWebSocketService_Unsubscribe(t *testing.T) {
 
 // TestWebSocketService_BroadcastKlineUpdate tests kline update broadcasting
 func TestWebSocketService_BroadcastKlineUpdate(t *testing.T) {
-	wsSvc, _, _ := setupTestWebSocketService(t)
-	if wsSvc == nil {
-		return
-	}
+	wsSvc, _ := setupTestWebSocketService(t)
 
 	// Create a test client
 	client := &Client{
@@ -170,7 +151,7 @@ func TestWebSocketService_BroadcastKlineUpdate(t *testing.T) {
 	}
 
 	// Subscribe to BTCUSDT:1m
-	wsSvc.handleSubscribe(client, "BTCUSDT", "1m")
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", 0, 0)
 
 	// Create a test kline
 	kline := models.Kline{
@@ -194,7 +175,7 @@ func TestWebSocketService_BroadcastKlineUpdate(t *testing.T) {
 	}
 
 	// Broadcast update
-	wsSvc.broadcastKlineUpdate(kline)
+	wsSvc.broadcastKlineUpdate(context.Background(), kline)
 
 	// Wait a bit for message to be sent
 	time.Sleep(100 * time.Millisecond)
@@ -225,12 +206,11 @@ func TestWebSocketService_BroadcastKlineUpdate(t *testing.T) {
 	}
 }
 
-// TestWebSocketService_Throttle tests throttling functionality
+// TestWebSocketService_Throttle tests that updates within the throttle
+// interval are held back and conflated to the latest, with the final update
+// of the kline it superseded
 func TestWebSocketService_Throttle(t *testing.T) {
-	wsSvc, _, _ := setupTestWebSocketService(t)
-	if wsSvc == nil {
-		return
-	}
+	wsSvc, _ := setupTestWebSocketService(t)
 
 	// Create a test client
 	client := &Client{
@@ -240,44 +220,469 @@ func TestWebSocketService_Throttle(t *testing.T) {
 		lastSent: make(map[string]time.Time),
 	}
 
-	// Subscribe
-	wsSvc.handleSubscribe(client, "BTCUSDT", "1m")
+	// Subscribe with the shortest throttle the server allows
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", 0, 1)
+	if msg := receiveMessage(t, client); msg.ThrottleMs != wsSvc.minThrottle.Milliseconds() {
+		t.Fatalf("Expected the throttle to be raised to %v, got %+v", wsSvc.minThrottle, msg)
+	}
 
-	// Create test kline
-	kline := models.Kline{
-		Symbol:     "BTCUSDT",
-		Interval:   "1m",
-		OpenTime:   time.Now().UnixMilli(),
-		CloseTime:  time.Now().UnixMilli() + 60000,
-		OpenPrice:  50000.0,
-		HighPrice:  51000.0,
-		LowPrice:   49000.0,
-		ClosePrice: 50500.0,
-		Volume:     100.5,
+	// The first update is sent at once, a burst within the interval is held back
+	update := func(openTime int64, closePrice float64) {
+		kline := testKline(openTime)
+		kline.ClosePrice = closePrice
+		wsSvc.broadcastKlineUpdate(context.Background(), kline)
+	}
+	update(60000, 1)
+	update(60000, 2)
+	update(60000, 3)
+	update(120000, 4)
+	update(180000, 5)
+	update(180000, 6)
+
+	if msg := receiveMessage(t, client); msg.Seq != 1 {
+		t.Fatalf("Expected the first update at once, got %+v", msg)
+	}
+	if len(client.send) != 0 {
+		t.Fatalf("Expected the burst to be held back, got %d messages", len(client.send))
 	}
 
-	// Send first update
-	wsSvc.broadcastKlineUpdate(kline)
-	time.Sleep(50 * time.Millisecond)
+	// Once the interval passes, the closed kline and the latest update are
+	// flushed; the klines before are not
+	for _, want := range []struct {
+		seq   uint64
+		close string
+	}{{4, "4.00000000"}, {6, "6.00000000"}} {
+		msg := receiveMessage(t, client)
+		data, _ := msg.Data.(map[string]interface{})
+		if msg.Type != "kline_update" || msg.Seq != want.seq || data["close"] != want.close {
+			t.Errorf("Expected update %d closing at %s, got %+v", want.seq, want.close, msg)
+		}
+	}
+	select {
+	case extra := <-client.send:
+		t.Errorf("Expected superseded updates to be dropped, got %s", extra)
+	case <-time.After(2 * wsSvc.minThrottle):
+	}
 
-	// Send second update immediately (should be throttled)
-	wsSvc.broadcastKlineUpdate(kline)
-	time.Sleep(50 * time.Millisecond)
+	// Unsubscribing discards held back updates
+	update(240000, 7)
+	update(240000, 8)
+	receiveMessage(t, client)
+	wsSvc.handleUnsubscribe(client, "BTCUSDT", "1m")
+	if msg := receiveMessage(t, client); msg.Type != "unsubscribed" {
+		t.Fatalf("Expected unsubscribed, got %+v", msg)
+	}
+	select {
+	case extra := <-client.send:
+		t.Errorf("Expected no update after unsubscribing, got %s", extra)
+	case <-time.After(2 * wsSvc.minThrottle):
+	}
+}
 
-	// Count messages received
-	messageCount := 0
-	for {
-		select {
-		case <-client.send:
-			messageCount++
-		case <-time.After(200 * time.Millisecond):
-			goto done
+// TestWebSocketService_NegotiateThrottle tests that requested throttle intervals are kept within the server's bounds
+func TestWebSocketService_NegotiateThrottle(t *testing.T) {
+	collector, _, _, _ := setupTestCollector(t)
+	wsSvc := NewWebSocketService(collector, nil, config.WebSocketConfig{
+		ThrottleInterval:    config.Duration(time.Second),
+		MinThrottleInterval: config.Duration(100 * time.Millisecond),
+		MaxThrottleInterval: config.Duration(time.Minute),
+	})
+
+	for _, tc := range []struct {
+		requested int64
+		want      time.Duration
+	}{
+		{0, time.Second},
+		{10, 100 * time.Millisecond},
+		{250, 250 * time.Millisecond},
+		{1 << 62, time.Minute},
+	} {
+		if got := wsSvc.negotiateThrottle(tc.requested); got != tc.want {
+			t.Errorf("Requested %dms: expected %v, got %v", tc.requested, tc.want, got)
+		}
+	}
+}
+
+// TestWebSocketService_SubscribeUntracked tests that clients cannot subscribe to series the collector does not track
+func TestWebSocketService_SubscribeUntracked(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+
+	client := &Client{
+		send:     make(chan []byte, 256),
+		subs:     make(map[string]bool),
+		lastSent: make(map[string]time.Time),
+	}
+
+	wsSvc.handleSubscribe(client, "DOGEUSDT", "1m", 0, 0)
+
+	var serverMsg ServerMessage
+	if err := json.Unmarshal(<-client.send, &serverMsg); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if serverMsg.Type != "error" {
+		t.Errorf("Expected error for untracked series, got %+v", serverMsg)
+	}
+
+	if len(subscribersOf(wsSvc, "DOGEUSDT:1m")) != 0 {
+		t.Error("Expected no subscription for untracked series")
+	}
+}
+
+// TestWebSocketService_SeriesRemoved tests that subscribers are notified when a series stops being tracked
+func TestWebSocketService_SeriesRemoved(t *testing.T) {
+	wsSvc, collector := setupTestWebSocketService(t)
+
+	client := &Client{
+		send:     make(chan []byte, 256),
+		subs:     make(map[string]bool),
+		lastSent: make(map[string]time.Time),
+	}
+	wsSvc.handleSubscribe(client, "btcusdt", "1m", 0, 0)
+	<-client.send // subscription confirmation
+
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"ETHUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+
+	var serverMsg ServerMessage
+	if err := json.Unmarshal(<-client.send, &serverMsg); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if serverMsg.Type != "series_removed" || serverMsg.Symbol != "BTCUSDT" || serverMsg.Interval != "1m" {
+		t.Errorf("Expected series_removed for BTCUSDT 1m, got %+v", serverMsg)
+	}
+
+	client.mu.RLock()
+	subscribed := client.subs["BTCUSDT:1m"]
+	client.mu.RUnlock()
+	if subscribed {
+		t.Error("Expected subscription to be dropped with the series")
+	}
+}
+
+// TestWebSocketService_Shutdown tests that cancelling Run closes connected
+// clients with a going-away frame and returns within the timeout
+func TestWebSocketService_Shutdown(t *testing.T) {
+	collector, _, _, _ := setupTestCollector(t)
+	wsSvc := NewWebSocketService(collector, nil, config.Default().WebSocket)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		wsSvc.Run(ctx)
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		wsSvc.HandleConnection(conn, EncodingJSON)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+	if !waitFor(t, time.Second, func() bool {
+		wsSvc.mu.RLock()
+		defer wsSvc.mu.RUnlock()
+		return len(wsSvc.clients) == 1
+	}) {
+		t.Fatal("Expected the client to be registered")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return after cancellation")
+	}
+
+	// The close frame follows the hello message
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a going-away close frame, got %v", err)
+	}
+
+	// Connections arriving after shutdown are turned away
+	late, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer late.Close()
+	late.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := late.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a late client to be closed as going away, got %v", err)
+	}
+}
+
+// TestWebSocketService_MaxSubscriptions tests that a client cannot subscribe past the limit
+func TestWebSocketService_MaxSubscriptions(t *testing.T) {
+	wsSvc, collector := setupTestWebSocketService(t)
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT", "ETHUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	wsSvc.maxSubscriptions = 1
+
+	client := &Client{
+		send:     make(chan []byte, 256),
+		subs:     make(map[string]bool),
+		lastSent: make(map[string]time.Time),
+	}
+	receive := func() ServerMessage {
+		var msg ServerMessage
+		if err := json.Unmarshal(<-client.send, &msg); err != nil {
+			t.Fatalf("Failed to unmarshal message: %v", err)
+		}
+		return msg
+	}
+
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", 0, 0)
+	if msg := receive(); msg.Type != "subscribed" {
+		t.Fatalf("Expected the first subscription to succeed, got %+v", msg)
+	}
+	// Subscribing again to the same series does not count twice
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", 0, 0)
+	if msg := receive(); msg.Type != "subscribed" {
+		t.Errorf("Expected a repeated subscription to succeed, got %+v", msg)
+	}
+
+	wsSvc.handleSubscribe(client, "ETHUSDT", "1m", 0, 0)
+	if msg := receive(); msg.Type != "error" {
+		t.Errorf("Expected an error past the subscription limit, got %+v", msg)
+	}
+	if client.subs["ETHUSDT:1m"] {
+		t.Error("Expected no subscription past the limit")
+	}
+
+	wsSvc.handleUnsubscribe(client, "BTCUSDT", "1m")
+	receive()
+	wsSvc.handleSubscribe(client, "ETHUSDT", "1m", 0, 0)
+	if msg := receive(); msg.Type != "subscribed" {
+		t.Errorf("Expected a subscription after unsubscribing, got %+v", msg)
+	}
+}
+
+// TestClient_MessageRate tests that messages past the rate are dropped with one error carrying a retry hint
+func TestClient_MessageRate(t *testing.T) {
+	client := &Client{
+		send:    make(chan []byte, 256),
+		limiter: rate.NewLimiter(1, 2),
+	}
+
+	for i := 0; i < 2; i++ {
+		if !client.allowMessage() {
+			t.Fatalf("Expected message %d within the burst to be allowed", i+1)
 		}
 	}
-done:
+	if client.allowMessage() || client.allowMessage() {
+		t.Fatal("Expected messages past the burst to be dropped")
+	}
 
-	// Should receive at least one message, but not necessarily two due to throttling
-	if messageCount == 0 {
-		t.Error("Expected to receive at least one message")
+	if len(client.send) != 1 {
+		t.Fatalf("Expected one error for the dropped messages, got %d messages", len(client.send))
+	}
+	var msg ServerMessage
+	if err := json.Unmarshal(<-client.send, &msg); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if msg.Type != "error" || msg.RetryAfterMs <= 0 || msg.RetryAfterMs > 1000 {
+		t.Errorf("Expected an error with a retry hint of at most a second, got %+v", msg)
+	}
+}
+
+// fakeHistory serves stored klines most recent first, as the repository does
+type fakeHistory struct {
+	klines []models.Kline // ascending
+	err    error
+}
+
+func (f *fakeHistory) GetKlines(ctx context.Context, symbol, interval string, startTime, endTime *int64, limit int) ([]models.Kline, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var klines []models.Kline
+	for i := len(f.klines) - 1; i >= 0 && len(klines) < limit; i-- {
+		klines = append(klines, f.klines[i])
+	}
+	return klines, nil
+}
+
+// TestWebSocketService_Snapshot tests that a subscription snapshot is stitched
+// with klines not stored yet and followed by live updates only
+func TestWebSocketService_Snapshot(t *testing.T) {
+	collector, _, _, _ := setupTestCollector(t)
+	if _, err := collector.Apply(config.TrackingConfig{Symbols: []string{"BTCUSDT"}, Intervals: []string{"1m"}}); err != nil {
+		t.Fatalf("Failed to apply tracking: %v", err)
+	}
+	history := &fakeHistory{}
+	for i := int64(1); i <= 5; i++ {
+		history.klines = append(history.klines, testKline(i*60000))
+	}
+	wsSvc := NewWebSocketService(collector, history, config.Default().WebSocket)
+
+	// Kline 5 was broadcast with a later close than stored, kline 6 is not stored yet
+	updated := testKline(5 * 60000)
+	updated.ClosePrice = 51000
+	wsSvc.broadcastKlineUpdate(t.Context(), updated)
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(6*60000))
+
+	client := &Client{
+		send:     make(chan []byte, 256),
+		subs:     make(map[string]bool),
+		lastSent: make(map[string]time.Time),
+	}
+	receive := func() ServerMessage {
+		var msg ServerMessage
+		if err := json.Unmarshal(<-client.send, &msg); err != nil {
+			t.Fatalf("Failed to unmarshal message: %v", err)
+		}
+		return msg
+	}
+
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", 4, 0)
+	if msg := receive(); msg.Type != "subscribed" {
+		t.Fatalf("Expected subscribed first, got %+v", msg)
+	}
+	msg := receive()
+	if msg.Type != "snapshot" {
+		t.Fatalf("Expected a snapshot, got %+v", msg)
+	}
+	klines, _ := msg.Data.([]interface{})
+	if len(klines) != 4 {
+		t.Fatalf("Expected 4 klines in the snapshot, got %d", len(klines))
+	}
+	for i, want := range []int64{3, 4, 5, 6} {
+		kline := klines[i].(map[string]interface{})
+		if int64(kline["open_time"].(float64)) != want*60000 {
+			t.Errorf("Expected kline %d to open at %d, got %v", i, want*60000, kline["open_time"])
+		}
+	}
+	if closePrice := klines[2].(map[string]interface{})["close"]; closePrice != "51000.00000000" {
+		t.Errorf("Expected the broadcast kline to replace the stored one, got close %v", closePrice)
+	}
+
+	wsSvc.broadcastKlineUpdate(t.Context(), testKline(7*60000))
+	if msg := receive(); msg.Type != "kline_update" {
+		t.Errorf("Expected live updates after the snapshot, got %+v", msg)
+	}
+	if len(client.send) != 0 {
+		t.Errorf("Expected no further messages, got %d", len(client.send))
+	}
+
+	wsSvc.handleSubscribe(client, "BTCUSDT", "1m", config.Default().WebSocket.MaxSnapshot+1, 0)
+	if msg := receive(); msg.Type != "error" {
+		t.Errorf("Expected an error for a snapshot over the maximum, got %+v", msg)
+	}
+
+	history.err = errors.New("database unavailable")
+	other := &Client{send: make(chan []byte, 1), subs: make(map[string]bool), lastSent: make(map[string]time.Time)}
+	wsSvc.handleSubscribe(other, "BTCUSDT", "1m", 10, 0)
+	if other.subs["BTCUSDT:1m"] {
+		t.Error("Expected no subscription when the snapshot cannot be loaded")
+	}
+}
+
+// TestWebSocketService_Hello tests the hello handshake over a connection
+func TestWebSocketService_Hello(t *testing.T) {
+	wsSvc, _ := setupTestWebSocketService(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		wsSvc.HandleConnection(conn, EncodingJSON)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	var hello ServerMessage
+	if err := conn.ReadJSON(&hello); err != nil {
+		t.Fatalf("Failed to read hello: %v", err)
+	}
+	if hello.Type != "hello" || hello.Protocol != ProtocolVersion || hello.Session == "" || hello.Batch {
+		t.Fatalf("Expected hello with the protocol version and a session, got %+v", hello)
+	}
+
+	conn.WriteJSON(ClientMessage{Action: "hello", Protocol: ProtocolVersion + 1, Batch: true})
+	var reply ServerMessage
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatalf("Failed to read hello reply: %v", err)
+	}
+	if reply.Type != "hello" || reply.Protocol != ProtocolVersion || !reply.Batch || reply.Session != hello.Session {
+		t.Errorf("Expected the server's version with batching, got %+v", reply)
+	}
+
+	conn.WriteJSON(ClientMessage{Action: "hello", Protocol: ProtocolVersion})
+	var late ServerMessage
+	if err := conn.ReadJSON(&late); err != nil {
+		t.Fatalf("Failed to read reply: %v", err)
+	}
+	if late.Type != "error" {
+		t.Errorf("Expected an error for a hello after the first message, got %+v", late)
+	}
+}
+
+// TestClient_WriteFraming tests that queued messages are sent one per frame
+// unless the client negotiated batch envelopes
+func TestClient_WriteFraming(t *testing.T) {
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close()
+	serverConn := <-conns
+	defer serverConn.Close()
+
+	client := &Client{conn: serverConn, send: make(chan []byte, 8)}
+	client.send <- []byte(`{"type":"kline_update","seq":2}`)
+	client.send <- []byte(`{"type":"kline_update","seq":3}`)
+	if err := client.write([]byte(`{"type":"kline_update","seq":1}`)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	var single ServerMessage
+	if err := conn.ReadJSON(&single); err != nil || single.Seq != 1 {
+		t.Fatalf("Expected a frame with one message, got %+v %v", single, err)
+	}
+	if len(client.send) != 2 {
+		t.Fatalf("Expected queued messages to wait for their own frames, got %d queued", len(client.send))
+	}
+
+	client.batch.Store(true)
+	if err := client.write(<-client.send); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	var batch struct {
+		Type     string          `json:"type"`
+		Messages []ServerMessage `json:"messages"`
+	}
+	if err := conn.ReadJSON(&batch); err != nil {
+		t.Fatalf("Failed to decode batch: %v", err)
+	}
+	if batch.Type != "batch" || len(batch.Messages) != 2 || batch.Messages[0].Seq != 2 || batch.Messages[1].Seq != 3 {
+		t.Errorf("Expected a batch of updates 2 and 3, got %+v", batch)
 	}
 }
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"crypto-monitor/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpReceiver stands in for an OTLP/HTTP collector and records exported span names
+type otlpReceiver struct {
+	mu      sync.Mutex
+	spans   []string
+	service string
+}
+
+func (o *otlpReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, attr := range rs.GetResource().GetAttributes() {
+			if attr.Key == "service.name" {
+				o.service = attr.GetValue().GetStringValue()
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				o.spans = append(o.spans, span.Name)
+			}
+		}
+	}
+	o.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	resp, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	w.Write(resp)
+}
+
+// TestSetup_ExportsToCollector tests that spans reach the configured OTLP endpoint on shutdown
+func TestSetup_ExportsToCollector(t *testing.T) {
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Enabled:     true,
+		Endpoint:    strings.TrimPrefix(server.URL, "http://"),
+		Insecure:    true,
+		SampleRatio: 1,
+	}, "crypto-monitor-test")
+	if err != nil {
+		t.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	_, span := Tracer("test").Start(context.Background(), "test.operation")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down tracing: %v", err)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.spans) != 1 || receiver.spans[0] != "test.operation" {
+		t.Errorf("Expected the exported test.operation span, got %v", receiver.spans)
+	}
+	if receiver.service != "crypto-monitor-test" {
+		t.Errorf("Expected service.name crypto-monitor-test, got %q", receiver.service)
+	}
+}
+
+// TestSetup_Disabled tests that a disabled configuration leaves the no-op provider in place
+func TestSetup_Disabled(t *testing.T) {
+	previous := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{}, "crypto-monitor-test")
+	if err != nil {
+		t.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	if otel.GetTracerProvider() != previous {
+		t.Error("Expected the tracer provider to be left alone when tracing is disabled")
+	}
+}
+
+// TestInjectExtract tests carrying a span context through propagation headers
+func TestInjectExtract(t *testing.T) {
+	if headers := Inject(context.Background()); headers != nil {
+		t.Errorf("Expected no headers without a span, got %v", headers)
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	headers := Inject(trace.ContextWithSpanContext(context.Background(), sc))
+	if headers["traceparent"] == "" {
+		t.Fatalf("Expected a traceparent header, got %v", headers)
+	}
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), headers))
+	if extracted.TraceID() != sc.TraceID() || extracted.SpanID() != sc.SpanID() || !extracted.IsRemote() {
+		t.Errorf("Expected remote span %v, got %v", sc, extracted)
+	}
+}
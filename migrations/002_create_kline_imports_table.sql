@@ -0,0 +1,19 @@
+-- Migration: Create kline_imports table
+-- Created: 2026-10-19
+-- Description: Records imported kline archives so that re-runs can skip them
+
+CREATE TABLE IF NOT EXISTS kline_imports (
+    id BIGSERIAL PRIMARY KEY,
+    file_name VARCHAR(255) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    symbol VARCHAR(20) NOT NULL,
+    interval VARCHAR(10) NOT NULL,
+    rows BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A file may be imported into several series
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kline_imports_target ON kline_imports (checksum, symbol, interval);
+
+COMMENT ON TABLE kline_imports IS 'Kline archive files that have been fully imported';
+COMMENT ON COLUMN kline_imports.checksum IS 'SHA-256 of the imported file, unique with symbol and interval';
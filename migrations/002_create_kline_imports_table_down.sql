@@ -0,0 +1,5 @@
+-- Rollback migration: Drop kline_imports table
+-- Created: 2026-10-19
+-- Description: Drops the kline_imports table
+
+DROP TABLE IF EXISTS kline_imports;
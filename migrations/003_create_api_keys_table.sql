@@ -0,0 +1,18 @@
+-- Migration: Create api_keys table
+-- Created: 2026-10-19
+-- Description: Stores hashed API keys and the scopes they grant
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+COMMENT ON TABLE api_keys IS 'API keys of REST and websocket clients';
+COMMENT ON COLUMN api_keys.key_hash IS 'SHA-256 of the key; the key itself is never stored';
+COMMENT ON COLUMN api_keys.scopes IS 'JSON array of granted scopes';
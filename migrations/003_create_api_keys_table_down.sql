@@ -0,0 +1,5 @@
+-- Rollback migration: Drop api_keys table
+-- Created: 2026-10-19
+-- Description: Drops the api_keys table
+
+DROP TABLE IF EXISTS api_keys;
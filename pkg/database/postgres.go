@@ -2,54 +2,45 @@ package database
 
 import (
 	"fmt"
-	"log"
-	"os"
 
+	"crypto-monitor/internal/config"
+	"crypto-monitor/internal/logging"
 	"crypto-monitor/internal/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
 )
 
-var DB *gorm.DB
-
-// InitDB initializes the PostgreSQL database connection and runs migrations
-func InitDB() (*gorm.DB, error) {
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-
-	port := os.Getenv("DB_PORT")
-	if port == "" {
-		port = "5432"
-	}
+var databaseLog = logging.For(logging.Database)
 
-	user := os.Getenv("DB_USER")
-	if user == "" {
-		user = "postgres"
-	}
+var DB *gorm.DB
 
-	password := os.Getenv("DB_PASSWORD")
-	if password == "" {
-		password = "postgres"
+// InitDB initializes the PostgreSQL database connection, configures the
+// connection pool and runs migrations
+func InitDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	dbname := os.Getenv("DB_NAME")
-	if dbname == "" {
-		dbname = "crypto_monitor"
+	// Queries made with a traced context become child spans; bind values are
+	// left out of the recorded statements
+	if err := db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics(), gormtracing.WithoutQueryVariables())); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
 	}
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
-		host, port, user, password, dbname)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime.Std())
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime.Std())
 
 	DB = db
-	log.Println("Database connection established successfully")
+	databaseLog.Info("Database connection established")
 
 	// Run migrations
 	if err := RunMigrations(db); err != nil {
@@ -61,14 +52,14 @@ func InitDB() (*gorm.DB, error) {
 
 // RunMigrations runs database migrations
 func RunMigrations(db *gorm.DB) error {
-	log.Println("Running database migrations...")
+	databaseLog.Info("Running database migrations")
 
 	// Auto migrate models
-	if err := db.AutoMigrate(&models.Kline{}); err != nil {
+	if err := db.AutoMigrate(&models.Kline{}, &models.KlineImport{}, &models.APIKey{}); err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
-	log.Println("Database migrations completed successfully")
+	databaseLog.Info("Database migrations completed")
 	return nil
 }
 
@@ -83,3 +74,13 @@ func CloseDB() error {
 	}
 	return nil
 }
+
+// CheckMigrations returns an error if a table created by RunMigrations is missing
+func CheckMigrations(db *gorm.DB) error {
+	for _, model := range []any{&models.Kline{}, &models.KlineImport{}, &models.APIKey{}} {
+		if !db.Migrator().HasTable(model) {
+			return fmt.Errorf("table for %T is missing", model)
+		}
+	}
+	return nil
+}
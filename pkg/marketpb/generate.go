@@ -0,0 +1,5 @@
+// Package marketpb is the gRPC contract of the market API, for Go clients of
+// the server
+package marketpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative market.proto
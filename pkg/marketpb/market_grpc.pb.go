@@ -0,0 +1,215 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: market.proto
+
+package marketpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MarketService_GetKlines_FullMethodName    = "/cryptomonitor.market.v1.MarketService/GetKlines"
+	MarketService_StreamKlines_FullMethodName = "/cryptomonitor.market.v1.MarketService/StreamKlines"
+	MarketService_ListSymbols_FullMethodName  = "/cryptomonitor.market.v1.MarketService/ListSymbols"
+)
+
+// MarketServiceClient is the client API for MarketService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MarketService serves the stored and live klines of the tracked series
+type MarketServiceClient interface {
+	// GetKlines returns stored klines of a series, most recent first, a page
+	// at a time
+	GetKlines(ctx context.Context, in *GetKlinesRequest, opts ...grpc.CallOption) (*GetKlinesResponse, error)
+	// StreamKlines streams the live updates of a tracked series, as the
+	// websocket feed does
+	StreamKlines(ctx context.Context, in *StreamKlinesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamKlinesResponse], error)
+	// ListSymbols returns the tracked symbols with their intervals
+	ListSymbols(ctx context.Context, in *ListSymbolsRequest, opts ...grpc.CallOption) (*ListSymbolsResponse, error)
+}
+
+type marketServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMarketServiceClient(cc grpc.ClientConnInterface) MarketServiceClient {
+	return &marketServiceClient{cc}
+}
+
+func (c *marketServiceClient) GetKlines(ctx context.Context, in *GetKlinesRequest, opts ...grpc.CallOption) (*GetKlinesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetKlinesResponse)
+	err := c.cc.Invoke(ctx, MarketService_GetKlines_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketServiceClient) StreamKlines(ctx context.Context, in *StreamKlinesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamKlinesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MarketService_ServiceDesc.Streams[0], MarketService_StreamKlines_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamKlinesRequest, StreamKlinesResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarketService_StreamKlinesClient = grpc.ServerStreamingClient[StreamKlinesResponse]
+
+func (c *marketServiceClient) ListSymbols(ctx context.Context, in *ListSymbolsRequest, opts ...grpc.CallOption) (*ListSymbolsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSymbolsResponse)
+	err := c.cc.Invoke(ctx, MarketService_ListSymbols_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarketServiceServer is the server API for MarketService service.
+// All implementations must embed UnimplementedMarketServiceServer
+// for forward compatibility.
+//
+// MarketService serves the stored and live klines of the tracked series
+type MarketServiceServer interface {
+	// GetKlines returns stored klines of a series, most recent first, a page
+	// at a time
+	GetKlines(context.Context, *GetKlinesRequest) (*GetKlinesResponse, error)
+	// StreamKlines streams the live updates of a tracked series, as the
+	// websocket feed does
+	StreamKlines(*StreamKlinesRequest, grpc.ServerStreamingServer[StreamKlinesResponse]) error
+	// ListSymbols returns the tracked symbols with their intervals
+	ListSymbols(context.Context, *ListSymbolsRequest) (*ListSymbolsResponse, error)
+	mustEmbedUnimplementedMarketServiceServer()
+}
+
+// UnimplementedMarketServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMarketServiceServer struct{}
+
+func (UnimplementedMarketServiceServer) GetKlines(context.Context, *GetKlinesRequest) (*GetKlinesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKlines not implemented")
+}
+func (UnimplementedMarketServiceServer) StreamKlines(*StreamKlinesRequest, grpc.ServerStreamingServer[StreamKlinesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamKlines not implemented")
+}
+func (UnimplementedMarketServiceServer) ListSymbols(context.Context, *ListSymbolsRequest) (*ListSymbolsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSymbols not implemented")
+}
+func (UnimplementedMarketServiceServer) mustEmbedUnimplementedMarketServiceServer() {}
+func (UnimplementedMarketServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeMarketServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MarketServiceServer will
+// result in compilation errors.
+type UnsafeMarketServiceServer interface {
+	mustEmbedUnimplementedMarketServiceServer()
+}
+
+func RegisterMarketServiceServer(s grpc.ServiceRegistrar, srv MarketServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMarketServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MarketService_ServiceDesc, srv)
+}
+
+func _MarketService_GetKlines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetKlinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServiceServer).GetKlines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketService_GetKlines_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServiceServer).GetKlines(ctx, req.(*GetKlinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MarketService_StreamKlines_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamKlinesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MarketServiceServer).StreamKlines(m, &grpc.GenericServerStream[StreamKlinesRequest, StreamKlinesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MarketService_StreamKlinesServer = grpc.ServerStreamingServer[StreamKlinesResponse]
+
+func _MarketService_ListSymbols_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSymbolsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServiceServer).ListSymbols(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MarketService_ListSymbols_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServiceServer).ListSymbols(ctx, req.(*ListSymbolsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MarketService_ServiceDesc is the grpc.ServiceDesc for MarketService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MarketService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cryptomonitor.market.v1.MarketService",
+	HandlerType: (*MarketServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetKlines",
+			Handler:    _MarketService_GetKlines_Handler,
+		},
+		{
+			MethodName: "ListSymbols",
+			Handler:    _MarketService_ListSymbols_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamKlines",
+			Handler:       _MarketService_StreamKlines_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "market.proto",
+}
@@ -5,17 +5,22 @@ import (
 	"log"
 	"os"
 
+	"crypto-monitor/internal/config"
 	"crypto-monitor/pkg/database"
 )
 
 func main() {
-	// Load environment variables from .env if exists
 	// This is a simple test script, so we rely on environment variables
+	// or the file named by CONFIG_FILE
+	cfg, err := config.LoadFile("")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
 	fmt.Println("Testing database connection...")
 
 	// Initialize database
-	db, err := database.InitDB()
+	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}